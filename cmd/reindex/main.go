@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/rejdeboer/multiplayer-server/internal/application"
+	"github.com/rejdeboer/multiplayer-server/internal/configuration"
+	"github.com/rejdeboer/multiplayer-server/internal/indexing"
+	"github.com/rejdeboer/multiplayer-server/internal/logger"
+)
+
+func main() {
+	log := logger.Get()
+
+	index := flag.String("index", indexing.USERS_INDEX, "alias of the index to rebuild")
+	batchSize := flag.Int("batch-size", 500, "number of documents per bulk request")
+	checkpointPath := flag.String("checkpoint", ".reindex-checkpoint.json", "file used to resume an interrupted reindex")
+	flag.Parse()
+
+	settings := configuration.ReadConfiguration("./configuration")
+
+	pool := application.GetDbConnectionPool(settings.Database)
+	defer pool.Close()
+
+	reindexer := indexing.Reindexer{
+		Pool:           pool,
+		SearchClient:   application.GetSearchClient(settings.Application.ElasticsearchEndpoint),
+		BatchSize:      int32(*batchSize),
+		CheckpointPath: *checkpointPath,
+	}
+
+	err := reindexer.Run(context.Background(), *index)
+	if err != nil {
+		log.Fatal().Err(err).Str("index", *index).Msg("reindex failed")
+	}
+	log.Info().Str("index", *index).Msg("reindex finished")
+}
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id bigserial PRIMARY KEY,
+    topic text NOT NULL,
+    key bytea NOT NULL,
+    value bytea NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    delivered_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS "idx_outbox_pending" ON "outbox" ("id") WHERE delivered_at IS NULL;
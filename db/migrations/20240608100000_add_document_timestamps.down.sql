@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS document_updates_touch_document ON document_updates;
+DROP FUNCTION IF EXISTS touch_document_on_update();
+
+DROP INDEX IF EXISTS "idx_document_contributors_user_id";
+DROP INDEX IF EXISTS "idx_documents_owner_id";
+
+ALTER TABLE document_contributors
+    DROP COLUMN IF EXISTS last_opened_at;
+
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,22 @@
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT NOW();
+
+ALTER TABLE document_contributors
+    ADD COLUMN IF NOT EXISTS last_opened_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS "idx_documents_owner_id" ON "documents" ("owner_id");
+CREATE INDEX IF NOT EXISTS "idx_document_contributors_user_id" ON "document_contributors" ("user_id");
+
+CREATE OR REPLACE FUNCTION touch_document_on_update() RETURNS trigger AS $$
+BEGIN
+    UPDATE documents
+    SET updated_at = NOW()
+    WHERE id = NEW.document_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER document_updates_touch_document
+    AFTER INSERT ON document_updates
+    FOR EACH ROW EXECUTE FUNCTION touch_document_on_update();
@@ -0,0 +1,14 @@
+CREATE OR REPLACE FUNCTION touch_document_on_update() RETURNS trigger AS $$
+BEGIN
+    UPDATE documents
+    SET updated_at = NOW()
+    WHERE id = NEW.document_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE document_updates
+    DROP COLUMN IF EXISTS author_id;
+
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS last_edited_by;
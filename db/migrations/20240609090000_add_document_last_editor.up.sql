@@ -0,0 +1,17 @@
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS last_edited_by uuid REFERENCES users(id)
+        ON DELETE SET NULL;
+
+ALTER TABLE document_updates
+    ADD COLUMN IF NOT EXISTS author_id uuid REFERENCES users(id)
+        ON DELETE SET NULL;
+
+CREATE OR REPLACE FUNCTION touch_document_on_update() RETURNS trigger AS $$
+BEGIN
+    UPDATE documents
+    SET updated_at = NOW(),
+        last_edited_by = COALESCE(NEW.author_id, last_edited_by)
+    WHERE id = NEW.document_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
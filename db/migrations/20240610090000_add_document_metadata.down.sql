@@ -0,0 +1,3 @@
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS icon,
+    DROP COLUMN IF EXISTS description;
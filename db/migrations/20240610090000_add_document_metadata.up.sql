@@ -0,0 +1,3 @@
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS description text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS icon text;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "idx_documents_deleted_at";
+
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
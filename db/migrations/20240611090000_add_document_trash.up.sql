@@ -0,0 +1,7 @@
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_by uuid REFERENCES users(id)
+        ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS "idx_documents_deleted_at" ON "documents" ("deleted_at")
+    WHERE deleted_at IS NOT NULL;
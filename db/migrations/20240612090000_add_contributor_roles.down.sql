@@ -0,0 +1,2 @@
+ALTER TABLE document_contributors
+    DROP COLUMN IF EXISTS role;
@@ -0,0 +1,8 @@
+ALTER TABLE document_contributors
+    ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'editor'
+        CHECK (role IN ('owner', 'editor', 'commenter', 'viewer'));
+
+UPDATE document_contributors c
+SET role = 'owner'
+FROM documents d
+WHERE d.id = c.document_id AND d.owner_id = c.user_id;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id bigserial PRIMARY KEY,
+    document_id uuid REFERENCES documents(id)
+        ON DELETE SET NULL,
+    actor_id uuid REFERENCES users(id)
+        ON DELETE SET NULL,
+    action text NOT NULL,
+    details jsonb NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS "idx_audit_log_document_id" ON "audit_log" ("document_id", "created_at");
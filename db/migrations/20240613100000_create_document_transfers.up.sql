@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS document_transfers (
+    document_id uuid PRIMARY KEY REFERENCES documents(id)
+        ON DELETE CASCADE,
+    from_user_id uuid NOT NULL REFERENCES users(id)
+        ON DELETE CASCADE,
+    to_user_id uuid NOT NULL REFERENCES users(id)
+        ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS document_invites (
+    id uuid PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+    document_id uuid NOT NULL REFERENCES documents(id)
+        ON DELETE CASCADE,
+    token text NOT NULL UNIQUE,
+    role text NOT NULL CHECK (role IN ('editor', 'commenter', 'viewer')),
+    created_by uuid NOT NULL REFERENCES users(id)
+        ON DELETE CASCADE,
+    expires_at timestamptz,
+    max_uses integer CHECK (max_uses > 0),
+    uses integer NOT NULL DEFAULT 0,
+    revoked_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS "idx_document_invites_document_id" ON "document_invites" ("document_id");
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS email_invitations (
+    id uuid PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+    document_id uuid NOT NULL REFERENCES documents(id)
+        ON DELETE CASCADE,
+    email text NOT NULL,
+    role text NOT NULL CHECK (role IN ('editor', 'commenter', 'viewer')),
+    invited_by uuid NOT NULL REFERENCES users(id)
+        ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT NOW(),
+    UNIQUE(document_id, email)
+);
+
+CREATE INDEX IF NOT EXISTS "idx_email_invitations_email" ON "email_invitations" ("email");
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS access_requests (
+    id uuid PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+    document_id uuid NOT NULL REFERENCES documents(id)
+        ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users(id)
+        ON DELETE CASCADE,
+    role text NOT NULL CHECK (role IN ('editor', 'commenter', 'viewer')),
+    message text NOT NULL DEFAULT '',
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied')),
+    decided_by uuid REFERENCES users(id)
+        ON DELETE SET NULL,
+    decided_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_access_requests_pending" ON "access_requests" ("document_id", "user_id") WHERE status = 'pending';
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS document_publications (
+    document_id uuid PRIMARY KEY REFERENCES documents(id)
+        ON DELETE CASCADE,
+    slug text NOT NULL UNIQUE,
+    published boolean NOT NULL DEFAULT true,
+    published_by uuid REFERENCES users(id)
+        ON DELETE SET NULL,
+    published_at timestamptz NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS document_snapshots;
+
+ALTER TABLE document_updates
+    DROP COLUMN IF EXISTS created_at;
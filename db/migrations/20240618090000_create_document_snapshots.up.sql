@@ -0,0 +1,15 @@
+ALTER TABLE document_updates
+    ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS document_snapshots (
+    id uuid PRIMARY KEY DEFAULT UUID_GENERATE_V4(),
+    document_id uuid NOT NULL REFERENCES documents(id)
+        ON DELETE CASCADE,
+    clock integer NOT NULL,
+    name text NOT NULL,
+    created_by uuid REFERENCES users(id)
+        ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS "idx_document_snapshots_document" ON "document_snapshots" ("document_id", "clock");
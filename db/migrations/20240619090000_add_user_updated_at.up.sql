@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS "idx_users_updated_at" ON "users" ("updated_at");
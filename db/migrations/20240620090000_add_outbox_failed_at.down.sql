@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "idx_outbox_pending";
+CREATE INDEX IF NOT EXISTS "idx_outbox_pending" ON "outbox" ("id") WHERE delivered_at IS NULL;
+
+ALTER TABLE outbox
+    DROP COLUMN IF EXISTS failed_at;
@@ -0,0 +1,6 @@
+ALTER TABLE outbox
+    ADD COLUMN IF NOT EXISTS failed_at timestamptz;
+
+DROP INDEX IF EXISTS "idx_outbox_pending";
+CREATE INDEX IF NOT EXISTS "idx_outbox_pending" ON "outbox" ("id")
+    WHERE delivered_at IS NULL AND failed_at IS NULL;
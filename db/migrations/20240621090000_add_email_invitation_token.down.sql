@@ -0,0 +1,2 @@
+ALTER TABLE email_invitations
+    DROP COLUMN IF EXISTS token;
@@ -0,0 +1,10 @@
+ALTER TABLE email_invitations
+    ADD COLUMN IF NOT EXISTS token text;
+
+UPDATE email_invitations
+SET token = replace(uuid_generate_v4()::text, '-', '')
+WHERE token IS NULL;
+
+ALTER TABLE email_invitations
+    ALTER COLUMN token SET NOT NULL,
+    ADD CONSTRAINT email_invitations_token_key UNIQUE (token);
@@ -0,0 +1,33 @@
+-- name: UpsertAccessRequest :one
+INSERT INTO access_requests (document_id, user_id, role, message)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (document_id, user_id) WHERE status = 'pending' DO UPDATE
+SET role = EXCLUDED.role,
+    message = EXCLUDED.message
+RETURNING *;
+
+-- name: HasDeniedAccessRequestSince :one
+SELECT EXISTS (
+    SELECT 1 FROM access_requests
+    WHERE document_id = @document_id
+        AND user_id = @user_id
+        AND status = 'denied'
+        AND decided_at > @denied_since
+);
+
+-- name: ListPendingAccessRequests :many
+SELECT * FROM access_requests
+WHERE document_id = $1 AND status = 'pending'
+ORDER BY created_at;
+
+-- name: GetPendingAccessRequest :one
+SELECT * FROM access_requests
+WHERE id = $1 AND document_id = $2 AND status = 'pending'
+FOR UPDATE;
+
+-- name: DecideAccessRequest :exec
+UPDATE access_requests
+SET status = $2,
+    decided_by = $3,
+    decided_at = NOW()
+WHERE id = $1;
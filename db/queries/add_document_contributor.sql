@@ -1,3 +1,21 @@
 -- name: CreateDocumentContributor :exec
-INSERT INTO document_contributors (document_id, user_id)
-VALUES ($1, $2);
+INSERT INTO document_contributors (document_id, user_id, role)
+VALUES ($1, $2, $3);
+
+-- name: DeleteDocumentContributor :execrows
+DELETE FROM document_contributors
+WHERE document_id = $1 AND user_id = $2;
+
+-- name: CreateDocumentContributorIfNotExists :execrows
+INSERT INTO document_contributors (document_id, user_id, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (document_id, user_id) DO NOTHING;
+
+-- name: UpdateDocumentContributorRole :exec
+UPDATE document_contributors
+SET role = $3
+WHERE document_id = $1 AND user_id = $2;
+
+-- name: GetDocumentContributorRole :one
+SELECT role FROM document_contributors
+WHERE document_id = $1 AND user_id = $2;
@@ -0,0 +1,3 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (document_id, actor_id, action, details)
+VALUES ($1, $2, $3, $4);
@@ -1,21 +1,40 @@
 -- name: GetDocumnetByID :one
 SELECT * FROM documents WHERE id=$1;
 
+-- name: GetDocumentForUpdate :one
+SELECT * FROM documents WHERE id=$1 FOR UPDATE;
+
 -- name: GetDocumentWithContributorsByID :many
 SELECT d.id as id, d.owner_id as owner_id, d.name as name,
-    c.user_id as contributor_id
+    d.created_at as created_at, d.updated_at as updated_at,
+    d.last_edited_by as last_edited_by, d.description as description,
+    d.icon as icon, c.user_id as contributor_id, c.role as contributor_role
 FROM documents d
 JOIN document_contributors c on d.id = c.document_id
-WHERE d.id = $1;
-
--- name: GetDocumentsByOwnerID :many
-SELECT * FROM documents WHERE owner_id = $1;
+WHERE d.id = $1 AND d.deleted_at IS NULL;
 
 -- name: CreateDocument :one
 INSERT INTO documents (name, owner_id)
     VALUES ($1, $2)
 RETURNING *;
 
--- name: DeleteDocument :exec
-DELETE FROM documents 
-WHERE id=$1;
+-- name: UpdateDocumentLastOpened :exec
+UPDATE document_contributors
+SET last_opened_at = NOW()
+WHERE document_id = $1 AND user_id = $2;
+
+-- name: UpdateDocumentMetadata :one
+UPDATE documents
+SET name = COALESCE(sqlc.narg('name'), name),
+    description = COALESCE(sqlc.narg('description'), description),
+    icon = CASE WHEN sqlc.narg('icon')::text IS NULL THEN icon
+        ELSE NULLIF(sqlc.narg('icon')::text, '') END,
+    updated_at = NOW(),
+    last_edited_by = @edited_by
+WHERE id = @id
+RETURNING *;
+
+-- name: UpdateDocumentStateVector :exec
+UPDATE documents
+SET state_vector = $2
+WHERE id = $1;
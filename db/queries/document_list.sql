@@ -0,0 +1,55 @@
+-- name: ListDocumentsByName :many
+SELECT d.id, d.name, d.owner_id, d.created_at, d.updated_at, d.last_edited_by, d.icon, c.last_opened_at, c.role
+FROM documents d
+LEFT JOIN document_contributors c ON c.document_id = d.id AND c.user_id = @user_id
+WHERE (d.owner_id = @user_id OR c.user_id IS NOT NULL)
+    AND (@owner_filter::text = 'any'
+        OR (@owner_filter::text = 'me' AND d.owner_id = @user_id)
+        OR (@owner_filter::text = 'others' AND d.owner_id <> @user_id))
+    AND d.deleted_at IS NULL
+    AND starts_with(lower(d.name), lower(@name_prefix::text))
+    AND (d.name, d.id) > (@after_name::text, @after_id::uuid)
+ORDER BY d.name, d.id
+LIMIT @page_size;
+
+-- name: ListDocumentsByCreatedAt :many
+SELECT d.id, d.name, d.owner_id, d.created_at, d.updated_at, d.last_edited_by, d.icon, c.last_opened_at, c.role
+FROM documents d
+LEFT JOIN document_contributors c ON c.document_id = d.id AND c.user_id = @user_id
+WHERE (d.owner_id = @user_id OR c.user_id IS NOT NULL)
+    AND (@owner_filter::text = 'any'
+        OR (@owner_filter::text = 'me' AND d.owner_id = @user_id)
+        OR (@owner_filter::text = 'others' AND d.owner_id <> @user_id))
+    AND d.deleted_at IS NULL
+    AND starts_with(lower(d.name), lower(@name_prefix::text))
+    AND (d.created_at, d.id) < (@after_created_at::timestamptz, @after_id::uuid)
+ORDER BY d.created_at DESC, d.id DESC
+LIMIT @page_size;
+
+-- name: ListDocumentsByUpdatedAt :many
+SELECT d.id, d.name, d.owner_id, d.created_at, d.updated_at, d.last_edited_by, d.icon, c.last_opened_at, c.role
+FROM documents d
+LEFT JOIN document_contributors c ON c.document_id = d.id AND c.user_id = @user_id
+WHERE (d.owner_id = @user_id OR c.user_id IS NOT NULL)
+    AND (@owner_filter::text = 'any'
+        OR (@owner_filter::text = 'me' AND d.owner_id = @user_id)
+        OR (@owner_filter::text = 'others' AND d.owner_id <> @user_id))
+    AND d.deleted_at IS NULL
+    AND starts_with(lower(d.name), lower(@name_prefix::text))
+    AND (d.updated_at, d.id) < (@after_updated_at::timestamptz, @after_id::uuid)
+ORDER BY d.updated_at DESC, d.id DESC
+LIMIT @page_size;
+
+-- name: ListDocumentsByLastOpenedAt :many
+SELECT d.id, d.name, d.owner_id, d.created_at, d.updated_at, d.last_edited_by, d.icon, c.last_opened_at, c.role
+FROM documents d
+LEFT JOIN document_contributors c ON c.document_id = d.id AND c.user_id = @user_id
+WHERE (d.owner_id = @user_id OR c.user_id IS NOT NULL)
+    AND (@owner_filter::text = 'any'
+        OR (@owner_filter::text = 'me' AND d.owner_id = @user_id)
+        OR (@owner_filter::text = 'others' AND d.owner_id <> @user_id))
+    AND d.deleted_at IS NULL
+    AND starts_with(lower(d.name), lower(@name_prefix::text))
+    AND (COALESCE(c.last_opened_at, '-infinity'), d.id) < (@after_last_opened_at::timestamptz, @after_id::uuid)
+ORDER BY COALESCE(c.last_opened_at, '-infinity') DESC, d.id DESC
+LIMIT @page_size;
@@ -0,0 +1,38 @@
+-- name: AppendDocumentUpdate :one
+INSERT INTO document_updates (document_id, clock, value, author_id)
+SELECT $1, COALESCE(MAX(clock), -1) + 1, $2, $3
+FROM document_updates
+WHERE document_id = $1
+RETURNING clock;
+
+-- name: GetDocumentUpdateInfo :one
+SELECT clock, author_id, created_at FROM document_updates
+WHERE document_id = $1 AND clock = $2;
+
+-- name: GetLatestDocumentClock :one
+SELECT COALESCE(MAX(clock), -1)::integer AS clock
+FROM document_updates
+WHERE document_id = $1;
+
+-- name: ListDocumentUpdateHistory :many
+SELECT clock, author_id, created_at FROM document_updates
+WHERE document_id = $1
+ORDER BY clock;
+
+-- name: ListDocumentUpdateValues :many
+SELECT value FROM document_updates
+WHERE document_id = $1
+ORDER BY clock;
+
+-- name: ListDocumentUpdateValuesUntil :many
+SELECT value FROM document_updates
+WHERE document_id = $1 AND clock <= $2
+ORDER BY clock;
+
+-- name: ListDocumentUpdatesUntil :many
+SELECT clock, value, author_id FROM document_updates
+WHERE document_id = $1 AND clock <= $2
+ORDER BY clock;
+
+-- name: NotifyDocumentUpdate :exec
+SELECT pg_notify('document_updates', @payload::text);
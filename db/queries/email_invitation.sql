@@ -0,0 +1,25 @@
+-- name: UpsertEmailInvitation :one
+INSERT INTO email_invitations (document_id, email, role, invited_by, token)
+VALUES (@document_id, lower(@email), @role, @invited_by, @token)
+ON CONFLICT (document_id, email) DO UPDATE
+SET role = EXCLUDED.role,
+    invited_by = EXCLUDED.invited_by
+RETURNING *;
+
+-- name: ListEmailInvitations :many
+SELECT * FROM email_invitations
+WHERE document_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteEmailInvitation :execrows
+DELETE FROM email_invitations
+WHERE id = $1 AND document_id = $2;
+
+-- name: ClaimEmailInvitations :many
+DELETE FROM email_invitations
+WHERE email = lower(@email)
+    AND EXISTS (
+        SELECT 1 FROM email_invitations
+        WHERE token = @token AND email = lower(@email)
+    )
+RETURNING *;
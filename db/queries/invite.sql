@@ -0,0 +1,24 @@
+-- name: CreateDocumentInvite :one
+INSERT INTO document_invites (document_id, token, role, created_by, expires_at, max_uses)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListDocumentInvites :many
+SELECT * FROM document_invites
+WHERE document_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: GetDocumentInviteByToken :one
+SELECT * FROM document_invites
+WHERE token = $1
+FOR UPDATE;
+
+-- name: RevokeDocumentInvite :execrows
+UPDATE document_invites
+SET revoked_at = NOW()
+WHERE id = $1 AND document_id = $2 AND revoked_at IS NULL;
+
+-- name: IncrementDocumentInviteUses :exec
+UPDATE document_invites
+SET uses = uses + 1
+WHERE id = $1;
@@ -0,0 +1,23 @@
+-- name: CreateOutboxMessage :exec
+INSERT INTO outbox (topic, key, value)
+VALUES ($1, $2, $3);
+
+-- name: GetPendingOutboxMessages :many
+SELECT * FROM outbox
+WHERE delivered_at IS NULL AND failed_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxMessagesDelivered :exec
+UPDATE outbox
+SET delivered_at = NOW()
+WHERE id = ANY(@ids::bigint[]);
+
+-- name: MarkOutboxMessageFailed :one
+UPDATE outbox
+SET attempts = attempts + 1,
+    last_error = @last_error,
+    failed_at = CASE WHEN attempts + 1 >= @max_attempts::integer THEN NOW() END
+WHERE id = @id
+RETURNING failed_at;
@@ -0,0 +1,19 @@
+-- name: PublishDocument :one
+INSERT INTO document_publications (document_id, slug, published_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (document_id) DO UPDATE
+SET published = true,
+    published_by = EXCLUDED.published_by,
+    published_at = NOW()
+RETURNING *;
+
+-- name: UnpublishDocument :execrows
+UPDATE document_publications
+SET published = false
+WHERE document_id = $1 AND published;
+
+-- name: GetPublishedDocumentBySlug :one
+SELECT d.id, d.name, d.description, d.icon, d.updated_at, p.published_at
+FROM document_publications p
+JOIN documents d ON d.id = p.document_id
+WHERE p.slug = $1 AND p.published AND d.deleted_at IS NULL;
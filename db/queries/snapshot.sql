@@ -0,0 +1,9 @@
+-- name: CreateDocumentSnapshot :one
+INSERT INTO document_snapshots (document_id, clock, name, created_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListDocumentSnapshots :many
+SELECT * FROM document_snapshots
+WHERE document_id = $1
+ORDER BY clock, created_at;
@@ -0,0 +1,18 @@
+-- name: UpsertDocumentTransfer :exec
+INSERT INTO document_transfers (document_id, from_user_id, to_user_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (document_id) DO UPDATE
+SET from_user_id = EXCLUDED.from_user_id,
+    to_user_id = EXCLUDED.to_user_id,
+    created_at = NOW();
+
+-- name: GetDocumentTransfer :one
+SELECT * FROM document_transfers WHERE document_id = $1;
+
+-- name: DeleteDocumentTransfer :exec
+DELETE FROM document_transfers WHERE document_id = $1;
+
+-- name: UpdateDocumentOwner :exec
+UPDATE documents
+SET owner_id = $2
+WHERE id = $1;
@@ -0,0 +1,29 @@
+-- name: SoftDeleteDocument :exec
+UPDATE documents
+SET deleted_at = NOW(), deleted_by = @deleted_by
+WHERE id = @id AND deleted_at IS NULL;
+
+-- name: RestoreDocument :exec
+UPDATE documents
+SET deleted_at = NULL, deleted_by = NULL
+WHERE id = $1;
+
+-- name: ListDeletedDocuments :many
+SELECT id, name, icon, deleted_at, deleted_by
+FROM documents
+WHERE owner_id = @owner_id
+    AND deleted_at IS NOT NULL
+    AND (deleted_at, id) < (@after_deleted_at::timestamptz, @after_id::uuid)
+ORDER BY deleted_at DESC, id DESC
+LIMIT @page_size;
+
+-- name: PurgeDeletedDocuments :many
+DELETE FROM documents
+WHERE id IN (
+    SELECT id FROM documents
+    WHERE deleted_at < @deleted_before::timestamptz
+    ORDER BY deleted_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, owner_id;
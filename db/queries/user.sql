@@ -1,6 +1,15 @@
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
+-- name: UserEmailExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE lower(email) = lower(@email));
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetUserByUsername :one
+SELECT * FROM users WHERE username = $1;
+
 -- name: CreateUser :one
 INSERT INTO users (email, username, passhash)
     VALUES ($1, $2, $3)
@@ -8,5 +17,19 @@ RETURNING *;
 
 -- name: UpdateUserImage :exec
 UPDATE users
-SET image_url=$2
+SET image_url=$2, updated_at=NOW()
 WHERE id=$1;
+
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users;
+
+-- name: ListUsersAfterID :many
+SELECT * FROM users
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: ListUsersUpdatedSince :many
+SELECT * FROM users
+WHERE updated_at >= $1
+ORDER BY updated_at, id;
@@ -1,24 +1,30 @@
 package application
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rejdeboer/multiplayer-server/internal/configuration"
+	"github.com/rejdeboer/multiplayer-server/internal/eventbus"
 	"github.com/rejdeboer/multiplayer-server/internal/logger"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
 	"github.com/rejdeboer/multiplayer-server/internal/routes"
-	"github.com/segmentio/kafka-go"
+	"github.com/rejdeboer/multiplayer-server/internal/trash"
 )
 
 var log = logger.Get()
 
 type Application struct {
-	pool     *pgxpool.Pool
-	producer *kafka.Writer
-	handler  http.Handler
-	addr     string
+	pool      *pgxpool.Pool
+	publisher eventbus.EventPublisher
+	relay     *outbox.Relay
+	purger    *trash.Purger
+	handler   http.Handler
+	addr      string
 }
 
 func Build(settings configuration.Settings) Application {
@@ -27,10 +33,7 @@ func Build(settings configuration.Settings) Application {
 
 	pool := GetDbConnectionPool(settings.Database)
 
-	producer := &kafka.Writer{
-		Addr:     kafka.TCP(settings.Application.KafkaEndpoint),
-		Balancer: &kafka.LeastBytes{},
-	}
+	publisher := GetEventPublisher(settings.Application)
 
 	searchClient, err := elasticsearch.NewTypedClient(elasticsearch.Config{
 		Addresses: []string{settings.Application.ElasticsearchEndpoint},
@@ -40,29 +43,78 @@ func Build(settings configuration.Settings) Application {
 	}
 
 	handler := routes.CreateHandler(settings, &routes.Env{
-		Pool:         pool,
-		Producer:     producer,
-		Blob:         GetBlobClient(settings.Azure),
-		SearchClient: searchClient,
+		Pool:           pool,
+		Storage:        GetObjectStore(settings),
+		SearchClient:   searchClient,
+		TrashRetention: settings.Trash.Retention(),
+		Mailer:         GetMailer(settings.Mailer),
+		SignupUrl:      settings.Mailer.SignupUrl,
 	})
 
+	relay := &outbox.Relay{
+		Pool:           pool,
+		Publisher:      publisher,
+		BatchSize:      settings.Outbox.BatchSize,
+		PollInterval:   time.Duration(settings.Outbox.PollIntervalMs) * time.Millisecond,
+		MaxBackoff:     time.Duration(settings.Outbox.MaxBackoffSeconds) * time.Second,
+		PublishTimeout: time.Duration(settings.Outbox.PublishTimeoutSeconds) * time.Second,
+		MaxAttempts:    settings.Outbox.MaxAttempts,
+	}
+	err = relay.Validate()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid outbox settings")
+	}
+
+	purger := &trash.Purger{
+		Pool:      pool,
+		Retention: settings.Trash.Retention(),
+		Interval:  time.Duration(settings.Trash.PurgeIntervalMinutes) * time.Minute,
+		BatchSize: settings.Trash.BatchSize,
+	}
+	err = purger.Validate()
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid trash settings")
+	}
+
 	return Application{
-		addr:     addr,
-		pool:     pool,
-		producer: producer,
-		handler:  handler,
+		addr:      addr,
+		pool:      pool,
+		publisher: publisher,
+		relay:     relay,
+		purger:    purger,
+		handler:   handler,
 	}
 }
 
 func (app *Application) Start() error {
 	defer app.close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go app.relay.Run(ctx)
+	go app.purger.Run(ctx)
+
 	log.Info().Msg(fmt.Sprintf("Server listening on port %s", app.addr))
 	return http.ListenAndServe(app.addr, app.handler)
 }
 
 func (app *Application) close() {
 	app.pool.Close()
-	app.producer.Close()
+	app.publisher.Close()
+}
+
+func GetEventPublisher(settings configuration.ApplicationSettings) eventbus.EventPublisher {
+	switch settings.EventBus {
+	case eventbus.KAFKA:
+		return eventbus.NewKafkaPublisher(settings.KafkaEndpoint)
+	case eventbus.MEMORY:
+		return eventbus.NewMemoryPublisher()
+	case eventbus.NOOP:
+		return eventbus.NoopPublisher{}
+	default:
+		log.Fatal().Str("event_bus", settings.EventBus).Msg("unknown event bus")
+		return nil
+	}
 }
 
 func GetSearchClient(endpoint string) *elasticsearch.TypedClient {
@@ -0,0 +1,32 @@
+package application
+
+import (
+	"time"
+
+	"github.com/rejdeboer/multiplayer-server/internal/configuration"
+	"github.com/rejdeboer/multiplayer-server/internal/mailer"
+)
+
+func GetMailer(settings configuration.MailerSettings) mailer.Mailer {
+	switch settings.Backend {
+	case mailer.SMTP:
+		if settings.TimeoutSeconds == 0 {
+			log.Fatal().Msg("smtp mailer timeout must be positive")
+		}
+		return mailer.NewSmtpMailer(
+			settings.SmtpHost,
+			settings.SmtpPort,
+			settings.SmtpUser,
+			settings.SmtpPass,
+			settings.From,
+			time.Duration(settings.TimeoutSeconds)*time.Second,
+		)
+	case mailer.LOG:
+		return mailer.LogMailer{}
+	case mailer.MEMORY:
+		return mailer.NewMemoryMailer()
+	default:
+		log.Fatal().Str("backend", settings.Backend).Msg("unknown mailer backend")
+		return nil
+	}
+}
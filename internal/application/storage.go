@@ -0,0 +1,39 @@
+package application
+
+import (
+	"github.com/rejdeboer/multiplayer-server/internal/configuration"
+	"github.com/rejdeboer/multiplayer-server/internal/storage"
+)
+
+func GetObjectStore(settings configuration.Settings) storage.ObjectStore {
+	switch settings.Storage.Backend {
+	case storage.AZURE:
+		return storage.NewAzureStore(GetBlobClient(settings.Azure))
+	case storage.LOCAL:
+		store, err := storage.NewLocalStore(
+			settings.Storage.LocalPath,
+			settings.Storage.PublicUrl,
+			settings.Application.SigningKey,
+			settings.Storage.PublicContainers,
+		)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error creating local object store")
+		}
+		return store
+	case storage.S3:
+		store, err := storage.NewS3Store(
+			settings.Storage.S3Endpoint,
+			settings.Storage.S3Region,
+			settings.Storage.S3AccessKey,
+			settings.Storage.S3SecretKey,
+			settings.Storage.S3UseSsl,
+		)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error creating s3 object store")
+		}
+		return store
+	default:
+		log.Fatal().Str("backend", settings.Storage.Backend).Msg("unknown storage backend")
+		return nil
+	}
+}
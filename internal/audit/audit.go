@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+)
+
+const (
+	OWNERSHIP_TRANSFER_REQUESTED = "ownership_transfer.requested"
+	OWNERSHIP_TRANSFER_ACCEPTED  = "ownership_transfer.accepted"
+	OWNERSHIP_TRANSFER_CANCELLED = "ownership_transfer.cancelled"
+	INVITE_CREATED               = "invite.created"
+	INVITE_REVOKED               = "invite.revoked"
+	INVITE_ACCEPTED              = "invite.accepted"
+	EMAIL_INVITATION_CREATED     = "email_invitation.created"
+	EMAIL_INVITATION_CANCELLED   = "email_invitation.cancelled"
+	EMAIL_INVITATION_ACCEPTED    = "email_invitation.accepted"
+	ACCESS_REQUEST_CREATED       = "access_request.created"
+	ACCESS_REQUEST_APPROVED      = "access_request.approved"
+	ACCESS_REQUEST_DENIED        = "access_request.denied"
+	DOCUMENT_PUBLISHED           = "document.published"
+	DOCUMENT_UNPUBLISHED         = "document.unpublished"
+	SNAPSHOT_CREATED             = "snapshot.created"
+	VERSION_RESTORED             = "version.restored"
+)
+
+type Entry struct {
+	DocumentID uuid.UUID
+	ActorID    uuid.UUID
+	Action     string
+	Details    any
+}
+
+// Record stores the entry in the audit log, pass queries bound to the
+// transaction of the change that is being audited
+func Record(ctx context.Context, q *db.Queries, entry Entry) error {
+	details := []byte("{}")
+	if entry.Details != nil {
+		var err error
+		details, err = json.Marshal(entry.Details)
+		if err != nil {
+			return err
+		}
+	}
+
+	return q.CreateAuditLogEntry(ctx, db.CreateAuditLogEntryParams{
+		DocumentID: pgtype.UUID{Bytes: entry.DocumentID, Valid: true},
+		ActorID:    pgtype.UUID{Bytes: entry.ActorID, Valid: true},
+		Action:     entry.Action,
+		Details:    details,
+	})
+}
@@ -3,6 +3,7 @@ package configuration
 import (
 	"fmt"
 	"os"
+	"time"
 
 	yaml "gopkg.in/yaml.v3"
 
@@ -13,6 +14,10 @@ type Settings struct {
 	Database    DatabaseSettings    `yaml:"database"`
 	Application ApplicationSettings `yaml:"application"`
 	Azure       AzureSettings       `yaml:"azure"`
+	Outbox      OutboxSettings      `yaml:"outbox"`
+	Storage     StorageSettings     `yaml:"storage"`
+	Trash       TrashSettings       `yaml:"trash"`
+	Mailer      MailerSettings      `yaml:"mailer"`
 }
 
 type DatabaseSettings struct {
@@ -29,6 +34,7 @@ type ApplicationSettings struct {
 	SigningKey             string `yaml:"signing_key" envconfig:"JWT_SECRET_KEY"`
 	TokenExpirationSeconds uint16 `yaml:"token_expiration_seconds"`
 	KafkaEndpoint          string `yaml:"kafka_endpoint" envconfig:"KAFKA_ENDPOINT"`
+	EventBus               string `yaml:"event_bus" envconfig:"EVENT_BUS"`
 	ElasticsearchEndpoint  string `yaml:"elasticsearch_endpoint" envconfig:"ELASTICSEARCH_ENDPOINT"`
 }
 
@@ -42,6 +48,47 @@ type AzureSettings struct {
 	BlobConnectionString string `yaml:"blob_connection_string"`
 }
 
+type OutboxSettings struct {
+	BatchSize             int32  `yaml:"batch_size"`
+	PollIntervalMs        uint32 `yaml:"poll_interval_ms"`
+	MaxBackoffSeconds     uint16 `yaml:"max_backoff_seconds"`
+	PublishTimeoutSeconds uint16 `yaml:"publish_timeout_seconds"`
+	MaxAttempts           int32  `yaml:"max_attempts"`
+}
+
+type TrashSettings struct {
+	RetentionDays        uint16 `yaml:"retention_days" envconfig:"TRASH_RETENTION_DAYS"`
+	PurgeIntervalMinutes uint32 `yaml:"purge_interval_minutes"`
+	BatchSize            int32  `yaml:"batch_size"`
+}
+
+func (s TrashSettings) Retention() time.Duration {
+	return time.Duration(s.RetentionDays) * 24 * time.Hour
+}
+
+type MailerSettings struct {
+	Backend        string `yaml:"backend" envconfig:"MAILER_BACKEND"`
+	From           string `yaml:"from" envconfig:"MAILER_FROM"`
+	SmtpHost       string `yaml:"smtp_host" envconfig:"SMTP_HOST"`
+	SmtpPort       uint16 `yaml:"smtp_port" envconfig:"SMTP_PORT"`
+	SmtpUser       string `yaml:"smtp_user" envconfig:"SMTP_USER"`
+	SmtpPass       string `yaml:"smtp_pass" envconfig:"SMTP_PASS"`
+	SignupUrl      string `yaml:"signup_url" envconfig:"SIGNUP_URL"`
+	TimeoutSeconds uint16 `yaml:"timeout_seconds"`
+}
+
+type StorageSettings struct {
+	Backend          string   `yaml:"backend" envconfig:"STORAGE_BACKEND"`
+	LocalPath        string   `yaml:"local_path" envconfig:"STORAGE_LOCAL_PATH"`
+	PublicUrl        string   `yaml:"public_url" envconfig:"STORAGE_PUBLIC_URL"`
+	PublicContainers []string `yaml:"public_containers"`
+	S3Endpoint       string   `yaml:"s3_endpoint" envconfig:"S3_ENDPOINT"`
+	S3Region         string   `yaml:"s3_region" envconfig:"S3_REGION"`
+	S3AccessKey      string   `yaml:"s3_access_key" envconfig:"S3_ACCESS_KEY"`
+	S3SecretKey      string   `yaml:"s3_secret_key" envconfig:"S3_SECRET_KEY"`
+	S3UseSsl         bool     `yaml:"s3_use_ssl"`
+}
+
 func ReadConfiguration(path string) Settings {
 	var settings Settings
 	readFiles(&settings, path)
@@ -12,16 +12,89 @@ import (
 )
 
 const createDocumentContributor = `-- name: CreateDocumentContributor :exec
-INSERT INTO document_contributors (document_id, user_id)
-VALUES ($1, $2)
+INSERT INTO document_contributors (document_id, user_id, role)
+VALUES ($1, $2, $3)
 `
 
 type CreateDocumentContributorParams struct {
 	DocumentID uuid.UUID
 	UserID     uuid.UUID
+	Role       string
 }
 
 func (q *Queries) CreateDocumentContributor(ctx context.Context, arg CreateDocumentContributorParams) error {
-	_, err := q.db.Exec(ctx, createDocumentContributor, arg.DocumentID, arg.UserID)
+	_, err := q.db.Exec(ctx, createDocumentContributor, arg.DocumentID, arg.UserID, arg.Role)
+	return err
+}
+
+const createDocumentContributorIfNotExists = `-- name: CreateDocumentContributorIfNotExists :execrows
+INSERT INTO document_contributors (document_id, user_id, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (document_id, user_id) DO NOTHING
+`
+
+type CreateDocumentContributorIfNotExistsParams struct {
+	DocumentID uuid.UUID
+	UserID     uuid.UUID
+	Role       string
+}
+
+func (q *Queries) CreateDocumentContributorIfNotExists(ctx context.Context, arg CreateDocumentContributorIfNotExistsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createDocumentContributorIfNotExists, arg.DocumentID, arg.UserID, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteDocumentContributor = `-- name: DeleteDocumentContributor :execrows
+DELETE FROM document_contributors
+WHERE document_id = $1 AND user_id = $2
+`
+
+type DeleteDocumentContributorParams struct {
+	DocumentID uuid.UUID
+	UserID     uuid.UUID
+}
+
+func (q *Queries) DeleteDocumentContributor(ctx context.Context, arg DeleteDocumentContributorParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDocumentContributor, arg.DocumentID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getDocumentContributorRole = `-- name: GetDocumentContributorRole :one
+SELECT role FROM document_contributors
+WHERE document_id = $1 AND user_id = $2
+`
+
+type GetDocumentContributorRoleParams struct {
+	DocumentID uuid.UUID
+	UserID     uuid.UUID
+}
+
+func (q *Queries) GetDocumentContributorRole(ctx context.Context, arg GetDocumentContributorRoleParams) (string, error) {
+	row := q.db.QueryRow(ctx, getDocumentContributorRole, arg.DocumentID, arg.UserID)
+	var role string
+	err := row.Scan(&role)
+	return role, err
+}
+
+const updateDocumentContributorRole = `-- name: UpdateDocumentContributorRole :exec
+UPDATE document_contributors
+SET role = $3
+WHERE document_id = $1 AND user_id = $2
+`
+
+type UpdateDocumentContributorRoleParams struct {
+	DocumentID uuid.UUID
+	UserID     uuid.UUID
+	Role       string
+}
+
+func (q *Queries) UpdateDocumentContributorRole(ctx context.Context, arg UpdateDocumentContributorRoleParams) error {
+	_, err := q.db.Exec(ctx, updateDocumentContributorRole, arg.DocumentID, arg.UserID, arg.Role)
 	return err
 }
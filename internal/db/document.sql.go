@@ -9,12 +9,13 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createDocument = `-- name: CreateDocument :one
 INSERT INTO documents (name, owner_id)
     VALUES ($1, $2)
-RETURNING id, name, owner_id, state_vector
+RETURNING id, name, owner_id, state_vector, created_at, updated_at, last_edited_by, description, icon, deleted_at, deleted_by
 `
 
 type CreateDocumentParams struct {
@@ -30,33 +31,61 @@ func (q *Queries) CreateDocument(ctx context.Context, arg CreateDocumentParams)
 		&i.Name,
 		&i.OwnerID,
 		&i.StateVector,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastEditedBy,
+		&i.Description,
+		&i.Icon,
+		&i.DeletedAt,
+		&i.DeletedBy,
 	)
 	return i, err
 }
 
-const deleteDocument = `-- name: DeleteDocument :exec
-DELETE FROM documents 
-WHERE id=$1
+const getDocumentForUpdate = `-- name: GetDocumentForUpdate :one
+SELECT id, name, owner_id, state_vector, created_at, updated_at, last_edited_by, description, icon, deleted_at, deleted_by FROM documents WHERE id=$1 FOR UPDATE
 `
 
-func (q *Queries) DeleteDocument(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.Exec(ctx, deleteDocument, id)
-	return err
+func (q *Queries) GetDocumentForUpdate(ctx context.Context, id uuid.UUID) (Document, error) {
+	row := q.db.QueryRow(ctx, getDocumentForUpdate, id)
+	var i Document
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.OwnerID,
+		&i.StateVector,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastEditedBy,
+		&i.Description,
+		&i.Icon,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
 }
 
 const getDocumentWithContributorsByID = `-- name: GetDocumentWithContributorsByID :many
 SELECT d.id as id, d.owner_id as owner_id, d.name as name,
-    c.user_id as contributor_id
+    d.created_at as created_at, d.updated_at as updated_at,
+    d.last_edited_by as last_edited_by, d.description as description,
+    d.icon as icon, c.user_id as contributor_id, c.role as contributor_role
 FROM documents d
 JOIN document_contributors c on d.id = c.document_id
-WHERE d.id = $1
+WHERE d.id = $1 AND d.deleted_at IS NULL
 `
 
 type GetDocumentWithContributorsByIDRow struct {
-	ID            uuid.UUID
-	OwnerID       uuid.UUID
-	Name          string
-	ContributorID uuid.UUID
+	ID              uuid.UUID
+	OwnerID         uuid.UUID
+	Name            string
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+	LastEditedBy    pgtype.UUID
+	Description     string
+	Icon            *string
+	ContributorID   uuid.UUID
+	ContributorRole string
 }
 
 func (q *Queries) GetDocumentWithContributorsByID(ctx context.Context, id uuid.UUID) ([]GetDocumentWithContributorsByIDRow, error) {
@@ -72,7 +101,13 @@ func (q *Queries) GetDocumentWithContributorsByID(ctx context.Context, id uuid.U
 			&i.ID,
 			&i.OwnerID,
 			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastEditedBy,
+			&i.Description,
+			&i.Icon,
 			&i.ContributorID,
+			&i.ContributorRole,
 		); err != nil {
 			return nil, err
 		}
@@ -84,47 +119,102 @@ func (q *Queries) GetDocumentWithContributorsByID(ctx context.Context, id uuid.U
 	return items, nil
 }
 
-const getDocumentsByOwnerID = `-- name: GetDocumentsByOwnerID :many
-SELECT id, name, owner_id, state_vector FROM documents WHERE owner_id = $1
+const getDocumnetByID = `-- name: GetDocumnetByID :one
+SELECT id, name, owner_id, state_vector, created_at, updated_at, last_edited_by, description, icon, deleted_at, deleted_by FROM documents WHERE id=$1
 `
 
-func (q *Queries) GetDocumentsByOwnerID(ctx context.Context, ownerID uuid.UUID) ([]Document, error) {
-	rows, err := q.db.Query(ctx, getDocumentsByOwnerID, ownerID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Document
-	for rows.Next() {
-		var i Document
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.OwnerID,
-			&i.StateVector,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) GetDocumnetByID(ctx context.Context, id uuid.UUID) (Document, error) {
+	row := q.db.QueryRow(ctx, getDocumnetByID, id)
+	var i Document
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.OwnerID,
+		&i.StateVector,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastEditedBy,
+		&i.Description,
+		&i.Icon,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
 }
 
-const getDocumnetByID = `-- name: GetDocumnetByID :one
-SELECT id, name, owner_id, state_vector FROM documents WHERE id=$1
+const updateDocumentLastOpened = `-- name: UpdateDocumentLastOpened :exec
+UPDATE document_contributors
+SET last_opened_at = NOW()
+WHERE document_id = $1 AND user_id = $2
 `
 
-func (q *Queries) GetDocumnetByID(ctx context.Context, id uuid.UUID) (Document, error) {
-	row := q.db.QueryRow(ctx, getDocumnetByID, id)
+type UpdateDocumentLastOpenedParams struct {
+	DocumentID uuid.UUID
+	UserID     uuid.UUID
+}
+
+func (q *Queries) UpdateDocumentLastOpened(ctx context.Context, arg UpdateDocumentLastOpenedParams) error {
+	_, err := q.db.Exec(ctx, updateDocumentLastOpened, arg.DocumentID, arg.UserID)
+	return err
+}
+
+const updateDocumentMetadata = `-- name: UpdateDocumentMetadata :one
+UPDATE documents
+SET name = COALESCE($1, name),
+    description = COALESCE($2, description),
+    icon = CASE WHEN $3::text IS NULL THEN icon
+        ELSE NULLIF($3::text, '') END,
+    updated_at = NOW(),
+    last_edited_by = $4
+WHERE id = $5
+RETURNING id, name, owner_id, state_vector, created_at, updated_at, last_edited_by, description, icon, deleted_at, deleted_by
+`
+
+type UpdateDocumentMetadataParams struct {
+	Name        *string
+	Description *string
+	Icon        *string
+	EditedBy    pgtype.UUID
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateDocumentMetadata(ctx context.Context, arg UpdateDocumentMetadataParams) (Document, error) {
+	row := q.db.QueryRow(ctx, updateDocumentMetadata,
+		arg.Name,
+		arg.Description,
+		arg.Icon,
+		arg.EditedBy,
+		arg.ID,
+	)
 	var i Document
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.OwnerID,
 		&i.StateVector,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastEditedBy,
+		&i.Description,
+		&i.Icon,
+		&i.DeletedAt,
+		&i.DeletedBy,
 	)
 	return i, err
 }
+
+const updateDocumentStateVector = `-- name: UpdateDocumentStateVector :exec
+UPDATE documents
+SET state_vector = $2
+WHERE id = $1
+`
+
+type UpdateDocumentStateVectorParams struct {
+	ID          uuid.UUID
+	StateVector []byte
+}
+
+func (q *Queries) UpdateDocumentStateVector(ctx context.Context, arg UpdateDocumentStateVectorParams) error {
+	_, err := q.db.Exec(ctx, updateDocumentStateVector, arg.ID, arg.StateVector)
+	return err
+}
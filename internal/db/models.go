@@ -6,30 +6,123 @@ package db
 
 import (
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AccessRequest struct {
+	ID         uuid.UUID
+	DocumentID uuid.UUID
+	UserID     uuid.UUID
+	Role       string
+	Message    string
+	Status     string
+	DecidedBy  pgtype.UUID
+	DecidedAt  pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
+}
+
+type AuditLog struct {
+	ID         int64
+	DocumentID pgtype.UUID
+	ActorID    pgtype.UUID
+	Action     string
+	Details    []byte
+	CreatedAt  pgtype.Timestamptz
+}
+
 type Document struct {
-	ID          uuid.UUID
-	Name        string
-	OwnerID     uuid.UUID
-	StateVector []byte
+	ID           uuid.UUID
+	Name         string
+	OwnerID      uuid.UUID
+	StateVector  []byte
+	CreatedAt    pgtype.Timestamptz
+	UpdatedAt    pgtype.Timestamptz
+	LastEditedBy pgtype.UUID
+	Description  string
+	Icon         *string
+	DeletedAt    pgtype.Timestamptz
+	DeletedBy    pgtype.UUID
 }
 
 type DocumentContributor struct {
+	DocumentID   uuid.UUID
+	UserID       uuid.UUID
+	LastOpenedAt pgtype.Timestamptz
+	Role         string
+}
+
+type DocumentInvite struct {
+	ID         uuid.UUID
 	DocumentID uuid.UUID
-	UserID     uuid.UUID
+	Token      string
+	Role       string
+	CreatedBy  uuid.UUID
+	ExpiresAt  pgtype.Timestamptz
+	MaxUses    *int32
+	Uses       int32
+	RevokedAt  pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
+}
+
+type DocumentPublication struct {
+	DocumentID  uuid.UUID
+	Slug        string
+	Published   bool
+	PublishedBy pgtype.UUID
+	PublishedAt pgtype.Timestamptz
+}
+
+type DocumentSnapshot struct {
+	ID         uuid.UUID
+	DocumentID uuid.UUID
+	Clock      int32
+	Name       string
+	CreatedBy  pgtype.UUID
+	CreatedAt  pgtype.Timestamptz
+}
+
+type DocumentTransfer struct {
+	DocumentID uuid.UUID
+	FromUserID uuid.UUID
+	ToUserID   uuid.UUID
+	CreatedAt  pgtype.Timestamptz
 }
 
 type DocumentUpdate struct {
 	DocumentID uuid.UUID
 	Clock      int32
 	Value      []byte
+	AuthorID   pgtype.UUID
+	CreatedAt  pgtype.Timestamptz
+}
+
+type EmailInvitation struct {
+	ID         uuid.UUID
+	DocumentID uuid.UUID
+	Email      string
+	Role       string
+	InvitedBy  uuid.UUID
+	CreatedAt  pgtype.Timestamptz
+	Token      string
+}
+
+type Outbox struct {
+	ID          int64
+	Topic       string
+	Key         []byte
+	Value       []byte
+	Attempts    int32
+	LastError   *string
+	CreatedAt   pgtype.Timestamptz
+	DeliveredAt pgtype.Timestamptz
+	FailedAt    pgtype.Timestamptz
 }
 
 type User struct {
-	ID       uuid.UUID
-	Email    string
-	Username string
-	Passhash string
-	ImageUrl *string
+	ID        uuid.UUID
+	Email     string
+	Username  string
+	Passhash  string
+	ImageUrl  *string
+	UpdatedAt pgtype.Timestamptz
 }
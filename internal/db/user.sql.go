@@ -9,12 +9,24 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countUsers = `-- name: CountUsers :one
+SELECT COUNT(*) FROM users
+`
+
+func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (email, username, passhash)
     VALUES ($1, $2, $3)
-RETURNING id, email, username, passhash, image_url
+RETURNING id, email, username, passhash, image_url, updated_at
 `
 
 type CreateUserParams struct {
@@ -32,12 +44,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Username,
 		&i.Passhash,
 		&i.ImageUrl,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, username, passhash, image_url FROM users WHERE email = $1
+SELECT id, email, username, passhash, image_url, updated_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -49,13 +62,122 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Username,
 		&i.Passhash,
 		&i.ImageUrl,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, username, passhash, image_url, updated_at FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Passhash,
+		&i.ImageUrl,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByUsername = `-- name: GetUserByUsername :one
+SELECT id, email, username, passhash, image_url, updated_at FROM users WHERE username = $1
+`
+
+func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByUsername, username)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Passhash,
+		&i.ImageUrl,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const listUsersAfterID = `-- name: ListUsersAfterID :many
+SELECT id, email, username, passhash, image_url, updated_at FROM users
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListUsersAfterIDParams struct {
+	ID    uuid.UUID
+	Limit int32
+}
+
+func (q *Queries) ListUsersAfterID(ctx context.Context, arg ListUsersAfterIDParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsersAfterID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Username,
+			&i.Passhash,
+			&i.ImageUrl,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersUpdatedSince = `-- name: ListUsersUpdatedSince :many
+SELECT id, email, username, passhash, image_url, updated_at FROM users
+WHERE updated_at >= $1
+ORDER BY updated_at, id
+`
+
+func (q *Queries) ListUsersUpdatedSince(ctx context.Context, updatedAt pgtype.Timestamptz) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsersUpdatedSince, updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Username,
+			&i.Passhash,
+			&i.ImageUrl,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateUserImage = `-- name: UpdateUserImage :exec
 UPDATE users
-SET image_url=$2
+SET image_url=$2, updated_at=NOW()
 WHERE id=$1
 `
 
@@ -68,3 +190,14 @@ func (q *Queries) UpdateUserImage(ctx context.Context, arg UpdateUserImageParams
 	_, err := q.db.Exec(ctx, updateUserImage, arg.ID, arg.ImageUrl)
 	return err
 }
+
+const userEmailExists = `-- name: UserEmailExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE lower(email) = lower($1))
+`
+
+func (q *Queries) UserEmailExists(ctx context.Context, email string) (bool, error) {
+	row := q.db.QueryRow(ctx, userEmailExists, email)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,23 @@
+package eventbus
+
+import (
+	"context"
+)
+
+const (
+	KAFKA  = "kafka"
+	MEMORY = "memory"
+	NOOP   = "noop"
+)
+
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+type EventPublisher interface {
+	// Publish delivers the messages in order, it returns once all of them are acknowledged
+	Publish(ctx context.Context, messages ...Message) error
+	Close() error
+}
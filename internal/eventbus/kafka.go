@@ -0,0 +1,36 @@
+package eventbus
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaPublisher(endpoint string) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(endpoint),
+			Balancer: &kafka.Hash{},
+		},
+	}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, messages ...Message) error {
+	kafkaMessages := []kafka.Message{}
+	for _, message := range messages {
+		kafkaMessages = append(kafkaMessages, kafka.Message{
+			Topic: message.Topic,
+			Key:   message.Key,
+			Value: message.Value,
+		})
+	}
+	return p.writer.WriteMessages(ctx, kafkaMessages...)
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
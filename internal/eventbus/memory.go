@@ -0,0 +1,48 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// MemoryPublisher keeps published messages in memory so they can be inspected in tests
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, messages ...Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+// Messages returns the messages published to a topic with the given key, in publishing order
+func (p *MemoryPublisher) Messages(topic string, key string) []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	messages := []Message{}
+	for _, message := range p.messages {
+		if message.Topic == topic && bytes.Equal(message.Key, []byte(key)) {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+func (p *MemoryPublisher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = nil
+}
+
+func (p *MemoryPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,16 @@
+package eventbus
+
+import (
+	"context"
+)
+
+// NoopPublisher drops every message, useful for running the server without a broker
+type NoopPublisher struct{}
+
+func (p NoopPublisher) Publish(ctx context.Context, messages ...Message) error {
+	return nil
+}
+
+func (p NoopPublisher) Close() error {
+	return nil
+}
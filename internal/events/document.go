@@ -0,0 +1,97 @@
+package events
+
+import (
+	"github.com/google/uuid"
+)
+
+const DOCUMENTS_TOPIC = "documents"
+
+type DocumentCreated struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	OwnerID uuid.UUID `json:"ownerId"`
+}
+
+func (e DocumentCreated) Type() string       { return "document.created" }
+func (e DocumentCreated) SchemaVersion() int { return 1 }
+func (e DocumentCreated) Topic() string      { return DOCUMENTS_TOPIC }
+func (e DocumentCreated) Subject() string    { return e.ID.String() }
+
+type DocumentRenamed struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	PreviousName string    `json:"previousName"`
+	RenamedBy    uuid.UUID `json:"renamedBy"`
+}
+
+func (e DocumentRenamed) Type() string       { return "document.renamed" }
+func (e DocumentRenamed) SchemaVersion() int { return 1 }
+func (e DocumentRenamed) Topic() string      { return DOCUMENTS_TOPIC }
+func (e DocumentRenamed) Subject() string    { return e.ID.String() }
+
+type DocumentDeleted struct {
+	ID        uuid.UUID `json:"id"`
+	DeletedBy uuid.UUID `json:"deletedBy"`
+}
+
+func (e DocumentDeleted) Type() string       { return "document.deleted" }
+func (e DocumentDeleted) SchemaVersion() int { return 1 }
+func (e DocumentDeleted) Topic() string      { return DOCUMENTS_TOPIC }
+func (e DocumentDeleted) Subject() string    { return e.ID.String() }
+
+type DocumentRestored struct {
+	ID         uuid.UUID `json:"id"`
+	RestoredBy uuid.UUID `json:"restoredBy"`
+}
+
+func (e DocumentRestored) Type() string       { return "document.restored" }
+func (e DocumentRestored) SchemaVersion() int { return 1 }
+func (e DocumentRestored) Topic() string      { return DOCUMENTS_TOPIC }
+func (e DocumentRestored) Subject() string    { return e.ID.String() }
+
+// DocumentPurged is emitted once a deleted document is permanently removed
+// from the trash
+type DocumentPurged struct {
+	ID      uuid.UUID `json:"id"`
+	OwnerID uuid.UUID `json:"ownerId"`
+}
+
+func (e DocumentPurged) Type() string       { return "document.purged" }
+func (e DocumentPurged) SchemaVersion() int { return 1 }
+func (e DocumentPurged) Topic() string      { return DOCUMENTS_TOPIC }
+func (e DocumentPurged) Subject() string    { return e.ID.String() }
+
+type ContributorAdded struct {
+	DocumentID uuid.UUID `json:"documentId"`
+	UserID     uuid.UUID `json:"userId"`
+	AddedBy    uuid.UUID `json:"addedBy"`
+}
+
+func (e ContributorAdded) Type() string       { return "contributor.added" }
+func (e ContributorAdded) SchemaVersion() int { return 1 }
+func (e ContributorAdded) Topic() string      { return DOCUMENTS_TOPIC }
+func (e ContributorAdded) Subject() string    { return e.DocumentID.String() }
+
+type ContributorRemoved struct {
+	DocumentID uuid.UUID `json:"documentId"`
+	UserID     uuid.UUID `json:"userId"`
+	RemovedBy  uuid.UUID `json:"removedBy"`
+}
+
+func (e ContributorRemoved) Type() string       { return "contributor.removed" }
+func (e ContributorRemoved) SchemaVersion() int { return 1 }
+func (e ContributorRemoved) Topic() string      { return DOCUMENTS_TOPIC }
+func (e ContributorRemoved) Subject() string    { return e.DocumentID.String() }
+
+// DocumentOwnershipTransferred is emitted once the recipient of a transfer
+// accepted it, the previous owner stays on as an editor
+type DocumentOwnershipTransferred struct {
+	ID              uuid.UUID `json:"id"`
+	PreviousOwnerID uuid.UUID `json:"previousOwnerId"`
+	OwnerID         uuid.UUID `json:"ownerId"`
+}
+
+func (e DocumentOwnershipTransferred) Type() string       { return "document.ownership_transferred" }
+func (e DocumentOwnershipTransferred) SchemaVersion() int { return 1 }
+func (e DocumentOwnershipTransferred) Topic() string      { return DOCUMENTS_TOPIC }
+func (e DocumentOwnershipTransferred) Subject() string    { return e.ID.String() }
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SPEC_VERSION      = "1.0"
+	SOURCE            = "multiplayer-server"
+	DATA_CONTENT_TYPE = "application/json"
+)
+
+// Envelope wraps every event published to Kafka, the attributes follow the CloudEvents spec
+type Envelope struct {
+	ID              uuid.UUID       `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	SpecVersion     string          `json:"specversion"`
+	SchemaVersion   int             `json:"schemaversion"`
+	Time            time.Time       `json:"time"`
+	Subject         string          `json:"subject"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+type Event interface {
+	// Type is the name of the event, e.g. user.created
+	Type() string
+	// SchemaVersion is bumped on every breaking change to the event data
+	SchemaVersion() int
+	// Topic is the Kafka topic the event is published to
+	Topic() string
+	// Subject identifies the entity the event is about and is used as message key
+	Subject() string
+}
+
+func New(event Event) (Envelope, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{
+		ID:              uuid.New(),
+		Type:            event.Type(),
+		Source:          SOURCE,
+		SpecVersion:     SPEC_VERSION,
+		SchemaVersion:   event.SchemaVersion(),
+		Time:            time.Now().UTC(),
+		Subject:         event.Subject(),
+		DataContentType: DATA_CONTENT_TYPE,
+		Data:            data,
+	}, nil
+}
@@ -0,0 +1,19 @@
+package events
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+// EnvelopeSchema returns the JSON Schema every published message conforms to
+func EnvelopeSchema() ([]byte, error) {
+	return schemas.ReadFile("schemas/envelope.json")
+}
+
+// DataSchema returns the JSON Schema of the data of an event type at a schema version
+func DataSchema(eventType string, schemaVersion int) ([]byte, error) {
+	return schemas.ReadFile(fmt.Sprintf("schemas/%s.v%d.json", eventType, schemaVersion))
+}
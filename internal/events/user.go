@@ -0,0 +1,19 @@
+package events
+
+import (
+	"github.com/google/uuid"
+)
+
+const USERS_TOPIC = "users"
+
+type UserCreated struct {
+	ID       uuid.UUID `json:"id"`
+	Email    string    `json:"email"`
+	Username string    `json:"username"`
+	ImageUrl string    `json:"imageUrl"`
+}
+
+func (e UserCreated) Type() string       { return "user.created" }
+func (e UserCreated) SchemaVersion() int { return 1 }
+func (e UserCreated) Topic() string      { return USERS_TOPIC }
+func (e UserCreated) Subject() string    { return e.ID.String() }
@@ -0,0 +1,332 @@
+package indexing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/logger"
+)
+
+var log = logger.Get()
+
+// Rows changed this long before a catch up pass started are written again,
+// so a clock difference between the database and the reindexer loses nothing
+const CATCH_UP_MARGIN = time.Minute
+
+type Reindexer struct {
+	Pool           *pgxpool.Pool
+	SearchClient   *elasticsearch.TypedClient
+	BatchSize      int32
+	CheckpointPath string
+}
+
+// Checkpoint is persisted after every batch so an interrupted reindex can continue where it stopped
+type Checkpoint struct {
+	Alias     string    `json:"alias"`
+	Index     string    `json:"index"`
+	LastID    uuid.UUID `json:"lastId"`
+	Indexed   int64     `json:"indexed"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+type document struct {
+	id   uuid.UUID
+	body interface{}
+}
+
+// changed returns the rows written since the given time, they reach the
+// old index while the new one is built and are copied before the swap
+type source struct {
+	count   func(ctx context.Context, q *db.Queries) (int64, error)
+	batch   func(ctx context.Context, q *db.Queries, afterID uuid.UUID, limit int32) ([]document, error)
+	changed func(ctx context.Context, q *db.Queries, since time.Time) ([]document, error)
+}
+
+var sources = map[string]source{
+	USERS_INDEX: {
+		count: func(ctx context.Context, q *db.Queries) (int64, error) {
+			return q.CountUsers(ctx)
+		},
+		batch: func(ctx context.Context, q *db.Queries, afterID uuid.UUID, limit int32) ([]document, error) {
+			users, err := q.ListUsersAfterID(ctx, db.ListUsersAfterIDParams{
+				ID:    afterID,
+				Limit: limit,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return userDocuments(users), nil
+		},
+		changed: func(ctx context.Context, q *db.Queries, since time.Time) ([]document, error) {
+			users, err := q.ListUsersUpdatedSince(ctx, pgtype.Timestamptz{Time: since, Valid: true})
+			if err != nil {
+				return nil, err
+			}
+			return userDocuments(users), nil
+		},
+	},
+}
+
+func userDocuments(users []db.User) []document {
+	documents := []document{}
+	for _, user := range users {
+		imageUrl := ""
+		if user.ImageUrl != nil {
+			imageUrl = *user.ImageUrl
+		}
+		documents = append(documents, document{
+			id: user.ID,
+			body: UserDocument{
+				ID:       user.ID.String(),
+				Email:    user.Email,
+				Username: user.Username,
+				ImageUrl: imageUrl,
+			},
+		})
+	}
+	return documents
+}
+
+// Run copies all rows behind the alias from Postgres into a new versioned index
+// and atomically points the alias to it once every row has been written. Rows
+// written to the old index in the meantime are copied by catch up passes.
+func (r *Reindexer) Run(ctx context.Context, alias string) error {
+	src, ok := sources[alias]
+	if !ok {
+		return fmt.Errorf("unknown index: %s", alias)
+	}
+
+	checkpoint, err := r.loadCheckpoint(alias)
+	if err != nil {
+		return err
+	}
+
+	if checkpoint.Index == "" {
+		checkpoint.StartedAt = time.Now()
+		checkpoint.Index = VersionedIndexName(alias, checkpoint.StartedAt)
+		err = CreateIndex(ctx, r.SearchClient, alias, checkpoint.Index)
+		if err != nil {
+			return fmt.Errorf("error creating index %s: %w", checkpoint.Index, err)
+		}
+		log.Info().Str("index", checkpoint.Index).Msg("created index")
+
+		err = r.saveCheckpoint(checkpoint)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Info().
+			Str("index", checkpoint.Index).
+			Str("last_id", checkpoint.LastID.String()).
+			Int64("indexed", checkpoint.Indexed).
+			Msg("resuming from checkpoint")
+	}
+
+	q := db.New(r.Pool)
+
+	total, err := src.count(ctx, q)
+	if err != nil {
+		return fmt.Errorf("error counting rows: %w", err)
+	}
+
+	for {
+		documents, err := src.batch(ctx, q, checkpoint.LastID, r.BatchSize)
+		if err != nil {
+			return fmt.Errorf("error fetching batch: %w", err)
+		}
+		if len(documents) == 0 {
+			break
+		}
+
+		err = r.writeBatch(ctx, checkpoint.Index, documents)
+		if err != nil {
+			return err
+		}
+
+		checkpoint.LastID = documents[len(documents)-1].id
+		checkpoint.Indexed += int64(len(documents))
+		err = r.saveCheckpoint(checkpoint)
+		if err != nil {
+			return err
+		}
+
+		log.Info().
+			Str("index", checkpoint.Index).
+			Int64("indexed", checkpoint.Indexed).
+			Int64("total", total).
+			Msg("indexed batch")
+	}
+
+	since, err := r.catchUp(ctx, src, q, checkpoint.Index, checkpoint.StartedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.SearchClient.Indices.Refresh().Index(checkpoint.Index).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("error refreshing index %s: %w", checkpoint.Index, err)
+	}
+
+	err = r.swapAlias(ctx, alias, checkpoint.Index)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("alias", alias).Str("index", checkpoint.Index).Msg("swapped alias")
+
+	// Rows written between the catch up and the swap went to the old index
+	_, err = r.catchUp(ctx, src, q, checkpoint.Index, since)
+	if err != nil {
+		return err
+	}
+
+	return r.removeCheckpoint()
+}
+
+// catchUp writes the rows changed since the given time to the index and
+// returns when the pass started, the next pass continues from there
+func (r *Reindexer) catchUp(ctx context.Context, src source, q *db.Queries, index string, since time.Time) (time.Time, error) {
+	started := time.Now()
+	documents, err := src.changed(ctx, q, since.Add(-CATCH_UP_MARGIN))
+	if err != nil {
+		return since, fmt.Errorf("error fetching changed rows: %w", err)
+	}
+
+	for start := 0; start < len(documents); start += int(r.BatchSize) {
+		end := min(start+int(r.BatchSize), len(documents))
+		err = r.writeBatch(ctx, index, documents[start:end])
+		if err != nil {
+			return since, err
+		}
+	}
+
+	log.Info().Str("index", index).Int("changed", len(documents)).Msg("caught up with changes")
+	return started, nil
+}
+
+func (r *Reindexer) writeBatch(ctx context.Context, index string, documents []document) error {
+	bulk := r.SearchClient.Bulk().Index(index)
+	for _, doc := range documents {
+		id := doc.id.String()
+		err := bulk.IndexOp(types.IndexOperation{Id_: &id}, doc.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	res, err := bulk.Do(ctx)
+	if err != nil {
+		return fmt.Errorf("error executing bulk request: %w", err)
+	}
+
+	if res.Errors {
+		for _, item := range res.Items {
+			for _, result := range item {
+				if result.Error != nil && result.Error.Reason != nil {
+					return fmt.Errorf("error indexing document %v: %s", result.Id_, *result.Error.Reason)
+				}
+			}
+		}
+		return errors.New("bulk request contained errors")
+	}
+
+	return nil
+}
+
+func (r *Reindexer) swapAlias(ctx context.Context, alias string, index string) error {
+	actions := []types.IndicesAction{
+		{Add: &types.AddAction{Index: &index, Alias: &alias}},
+	}
+
+	aliasExists, err := r.SearchClient.Indices.ExistsAlias(alias).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("error checking alias %s: %w", alias, err)
+	}
+
+	if aliasExists {
+		current, err := r.SearchClient.Indices.GetAlias().Name(alias).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("error fetching alias %s: %w", alias, err)
+		}
+		for old := range current {
+			if old == index {
+				continue
+			}
+			actions = append(actions, types.IndicesAction{
+				Remove: &types.RemoveAction{Index: &old, Alias: &alias},
+			})
+		}
+	} else {
+		// Older deployments created a concrete index with the name of the alias,
+		// it has to be dropped in the same request for the alias to be created
+		indexExists, err := r.SearchClient.Indices.Exists(alias).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("error checking index %s: %w", alias, err)
+		}
+		if indexExists {
+			actions = append(actions, types.IndicesAction{
+				RemoveIndex: &types.RemoveIndexAction{Index: &alias},
+			})
+		}
+	}
+
+	_, err = r.SearchClient.Indices.UpdateAliases().Actions(actions...).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("error updating alias %s: %w", alias, err)
+	}
+	return nil
+}
+
+func (r *Reindexer) loadCheckpoint(alias string) (Checkpoint, error) {
+	checkpoint := Checkpoint{Alias: alias}
+
+	content, err := os.ReadFile(r.CheckpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return checkpoint, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+
+	var stored Checkpoint
+	err = json.Unmarshal(content, &stored)
+	if err != nil {
+		return checkpoint, fmt.Errorf("error decoding checkpoint: %w", err)
+	}
+
+	if stored.Alias != alias {
+		return checkpoint, fmt.Errorf("checkpoint %s belongs to index %s", r.CheckpointPath, stored.Alias)
+	}
+
+	return stored, nil
+}
+
+func (r *Reindexer) saveCheckpoint(checkpoint Checkpoint) error {
+	content, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(r.CheckpointPath, content, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *Reindexer) removeCheckpoint() error {
+	err := os.Remove(r.CheckpointPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing checkpoint: %w", err)
+	}
+	return nil
+}
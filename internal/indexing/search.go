@@ -0,0 +1,56 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/create"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+const USERS_INDEX = "users"
+
+type UserDocument struct {
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	ImageUrl string `json:"imageUrl"`
+}
+
+// Mappings returns the mappings of the index behind the given alias
+func Mappings(alias string) (*types.TypeMapping, error) {
+	switch alias {
+	case USERS_INDEX:
+		return &types.TypeMapping{
+			Properties: map[string]types.Property{
+				"id":       types.NewTextProperty(),
+				"username": types.NewTextProperty(),
+				"email":    types.NewTextProperty(),
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown index: %s", alias)
+	}
+}
+
+// VersionedIndexName returns a new concrete index name for an alias, e.g. users_20240601T120000
+func VersionedIndexName(alias string, now time.Time) string {
+	return fmt.Sprintf("%s_%s", alias, now.UTC().Format("20060102T150405"))
+}
+
+// CreateIndex creates a concrete index with the mappings that belong to the alias
+func CreateIndex(ctx context.Context, client *elasticsearch.TypedClient, alias string, index string) error {
+	mappings, err := Mappings(alias)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Indices.Create(index).
+		Request(&create.Request{
+			Mappings: mappings,
+		}).
+		Do(ctx)
+	return err
+}
@@ -0,0 +1,22 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/rejdeboer/multiplayer-server/internal/logger"
+)
+
+var log = logger.Get()
+
+// LogMailer writes messages to the log instead of sending them, useful for
+// running the server without a mail relay
+type LogMailer struct{}
+
+func (m LogMailer) Send(ctx context.Context, message Message) error {
+	log.Info().
+		Str("to", message.To).
+		Str("subject", message.Subject).
+		Str("body", message.Body).
+		Msg("sending mail")
+	return nil
+}
@@ -0,0 +1,21 @@
+package mailer
+
+import (
+	"context"
+)
+
+const (
+	SMTP   = "smtp"
+	LOG    = "log"
+	MEMORY = "memory"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer keeps sent messages in memory so they can be inspected in tests
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, message Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Messages returns the messages sent to the given address, in sending order
+func (m *MemoryMailer) Messages(to string) []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := []Message{}
+	for _, message := range m.messages {
+		if message.To == to {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,131 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SmtpMailer delivers plain text messages through an SMTP relay, it
+// authenticates only when a username is configured
+type SmtpMailer struct {
+	host    string
+	addr    string
+	auth    smtp.Auth
+	from    string
+	timeout time.Duration
+}
+
+func NewSmtpMailer(host string, port uint16, username string, password string, from string, timeout time.Duration) *SmtpMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SmtpMailer{
+		host:    host,
+		addr:    net.JoinHostPort(host, strconv.Itoa(int(port))),
+		auth:    auth,
+		from:    from,
+		timeout: timeout,
+	}
+}
+
+// Send delivers the message within the timeout of the mailer, or earlier
+// when ctx is done
+func (m *SmtpMailer) Send(ctx context.Context, message Message) error {
+	if strings.ContainsAny(message.To, "\r\n") || strings.ContainsAny(m.from, "\r\n") {
+		return errors.New("mail address contains a line break")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The deadline bounds the whole conversation, closing the connection
+	// unblocks it early when ctx is cancelled
+	deadline, _ := ctx.Deadline()
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = m.deliver(client, message)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("sending mail: %w", ctx.Err())
+	}
+	return err
+}
+
+func (m *SmtpMailer) deliver(client *smtp.Client, message Message) error {
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err := client.StartTLS(&tls.Config{ServerName: m.host})
+		if err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		err := client.Auth(m.auth)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := client.Mail(m.from)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(message.To)
+	if err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte(formatMessage(m.from, message)))
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// formatMessage encodes the subject so user supplied text, like a document
+// name, can not break out of the header
+func formatMessage(from string, message Message) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", from)
+	fmt.Fprintf(&body, "To: %s\r\n", message.To)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	body.WriteString(message.Body)
+	return body.String()
+}
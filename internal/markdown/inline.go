@@ -0,0 +1,422 @@
+package markdown
+
+import (
+	"maps"
+	"reflect"
+	"strings"
+
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+)
+
+// Marks written by the editor for the inline delimiters
+var delimiterMarks = map[string]string{
+	"**": "bold",
+	"__": "bold",
+	"*":  "italic",
+	"_":  "italic",
+	"~~": "strike",
+}
+
+var autolinkSchemes = []string{"http://", "https://", "mailto:"}
+
+// parseInline converts the inline content of a block into xml texts and the
+// inline nodes between them, hard breaks and images
+func parseInline(source string) []yjs.XmlNode {
+	p := &inlineParser{children: []yjs.XmlNode{}}
+	p.parse(source, nil)
+	p.flushText()
+	return p.children
+}
+
+type inlineParser struct {
+	children []yjs.XmlNode
+
+	// Consecutive text with the same attributes is collected before it is
+	// added as a single delta, instead of concatenating it piece by piece
+	pending           strings.Builder
+	pendingAttributes map[string]any
+}
+
+func (p *inlineParser) text(text string, attributes map[string]any) {
+	if text == "" {
+		return
+	}
+	if p.pending.Len() > 0 && !reflect.DeepEqual(p.pendingAttributes, attributes) {
+		p.flushText()
+	}
+	p.pending.WriteString(text)
+	p.pendingAttributes = attributes
+}
+
+func (p *inlineParser) node(node yjs.XmlNode) {
+	p.flushText()
+	p.children = append(p.children, node)
+}
+
+// flushText adds the pending text to the xml text the children end with
+func (p *inlineParser) flushText() {
+	if p.pending.Len() == 0 {
+		return
+	}
+	delta := yjs.Delta{Insert: p.pending.String(), Attributes: p.pendingAttributes}
+	p.pending.Reset()
+
+	if n := len(p.children); n > 0 && p.children[n-1].Name == "" {
+		p.children[n-1].Delta = append(p.children[n-1].Delta, delta)
+		return
+	}
+	p.children = append(p.children, yjs.XmlNode{Delta: []yjs.Delta{delta}})
+}
+
+func (p *inlineParser) parse(source string, attributes map[string]any) {
+	s := newInlineScan(source)
+
+	var plain []byte
+	flush := func() {
+		p.text(string(plain), attributes)
+		plain = plain[:0]
+	}
+
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == '\\' && i+1 < len(source) && isPunctuation(source[i+1]):
+			plain = append(plain, source[i+1])
+			i += 2
+		case c == '\\' && i+1 < len(source) && source[i+1] == '\n':
+			flush()
+			p.node(yjs.XmlNode{Name: "hardBreak"})
+			i += 2
+		case c == '\n':
+			// Two trailing spaces make a hard break, otherwise it is a space
+			trimmed := len(plain)
+			for trimmed > 0 && plain[trimmed-1] == ' ' {
+				trimmed--
+			}
+			hardBreak := len(plain)-trimmed >= 2
+			plain = plain[:trimmed]
+			if hardBreak {
+				flush()
+				p.node(yjs.XmlNode{Name: "hardBreak"})
+			} else {
+				plain = append(plain, ' ')
+			}
+			i++
+			for i < len(source) && source[i] == ' ' {
+				i++
+			}
+		case c == '`':
+			fence := runLength(source, i, '`')
+			end := s.findCodeClose(i+fence, fence)
+			if end < 0 {
+				plain = append(plain, source[i:i+fence]...)
+				i += fence
+				continue
+			}
+			flush()
+			p.text(codeContent(source[i+fence:end]), with(attributes, "code", true))
+			i = end + fence
+		case c == '!' && i+1 < len(source) && source[i+1] == '[':
+			label, src, end, ok := s.parseLink(i + 1)
+			if !ok {
+				plain = append(plain, c)
+				i++
+				continue
+			}
+			flush()
+			p.node(yjs.XmlNode{
+				Name:       "image",
+				Attributes: map[string]any{"src": src, "alt": unescape(label)},
+			})
+			i = end
+		case c == '[':
+			label, href, end, ok := s.parseLink(i)
+			if !ok {
+				plain = append(plain, c)
+				i++
+				continue
+			}
+			flush()
+			p.parse(label, with(attributes, "link", map[string]any{"href": href}))
+			i = end
+		case c == '<':
+			end := s.closingAngle(i)
+			if end > i && isAutolink(source[i+1:end]) {
+				flush()
+				url := source[i+1 : end]
+				p.text(url, with(attributes, "link", map[string]any{"href": url}))
+				i = end + 1
+				continue
+			}
+			plain = append(plain, c)
+			i++
+		case c == '*' || c == '_' || c == '~':
+			delimiter := source[i : i+min(delimiterRun(source, i, c), 2)]
+			mark, ok := delimiterMarks[delimiter]
+			opensWord := i+len(delimiter) < len(source) && !isSpace(source[i+len(delimiter)])
+			intraword := c == '_' && i > 0 && isAlphanumeric(source[i-1])
+			if !ok || !opensWord || intraword {
+				plain = append(plain, delimiter...)
+				i += len(delimiter)
+				continue
+			}
+
+			end := s.findClosing(i+len(delimiter), delimiter)
+			if end < 0 {
+				plain = append(plain, delimiter...)
+				i += len(delimiter)
+				continue
+			}
+			flush()
+			p.parse(source[i+len(delimiter):end], with(attributes, mark, true))
+			i = end + len(delimiter)
+		default:
+			plain = append(plain, c)
+			i++
+		}
+	}
+	flush()
+}
+
+// inlineScan answers the searches parse makes in a source. Searches that
+// fail are remembered and brackets are matched up front, so a source full
+// of unclosed delimiters, code spans or links is still parsed in linear time.
+type inlineScan struct {
+	source string
+	// brackets maps the index of every opening bracket or parenthesis to
+	// the index of the one that closes it
+	brackets map[int]int
+	// visited marks, per delimiter, the positions a search for a closing
+	// delimiter passed. Searches that found one are never overtaken by later
+	// searches, so reaching a visited position means the search fails.
+	visited map[string][]bool
+	// unclosedCode is the earliest start, per fence length, from which no
+	// closing fence exists
+	unclosedCode map[int]int
+	// angle is the index of the first '>' after the start of the previous
+	// search, -1 when there is none
+	angle         int
+	angleSearched bool
+}
+
+func newInlineScan(source string) *inlineScan {
+	return &inlineScan{
+		source:       source,
+		brackets:     matchBrackets(source),
+		visited:      make(map[string][]bool),
+		unclosedCode: make(map[int]int),
+	}
+}
+
+// findClosing returns the index of the delimiter that closes the one that
+// ends at start, skipping code spans, escapes and longer delimiter runs
+func (s *inlineScan) findClosing(start int, delimiter string) int {
+	source := s.source
+	visited, ok := s.visited[delimiter]
+	if !ok {
+		visited = make([]bool, len(source))
+		s.visited[delimiter] = visited
+	}
+
+	c := delimiter[0]
+	for j := start + 1; j < len(source); j++ {
+		if visited[j] {
+			return -1
+		}
+		visited[j] = true
+
+		switch source[j] {
+		case '\\':
+			j++
+			continue
+		case '`':
+			fence := runLength(source, j, '`')
+			if end := s.findCodeClose(j+fence, fence); end >= 0 {
+				j = end + fence - 1
+			} else {
+				j += fence - 1
+			}
+			continue
+		}
+
+		if source[j] != c || isSpace(source[j-1]) {
+			continue
+		}
+		run := delimiterRun(source, j, c)
+		if len(delimiter) == 1 && run == 2 {
+			j++
+			continue
+		}
+		if !strings.HasPrefix(source[j:], delimiter) {
+			continue
+		}
+		if c == '_' && j+len(delimiter) < len(source) && isAlphanumeric(source[j+len(delimiter)]) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+// findCodeClose returns the index of the run of backticks with the length of
+// the fence. Start always follows a complete run, so a search that failed
+// covers every later search for the same fence.
+func (s *inlineScan) findCodeClose(start int, fence int) int {
+	if from, ok := s.unclosedCode[fence]; ok && start >= from {
+		return -1
+	}
+
+	source := s.source
+	for j := start; j < len(source); {
+		if source[j] != '`' {
+			j++
+			continue
+		}
+		run := runLength(source, j, '`')
+		if run == fence {
+			return j
+		}
+		j += run
+	}
+
+	s.unclosedCode[fence] = start
+	return -1
+}
+
+// closingAngle returns the index of the first '>' after start or -1, parse
+// asks with increasing starts so an earlier answer holds until it is passed
+func (s *inlineScan) closingAngle(start int) int {
+	if !s.angleSearched || (s.angle >= 0 && s.angle <= start) {
+		s.angle = strings.IndexByte(s.source[start:], '>')
+		if s.angle >= 0 {
+			s.angle += start
+		}
+		s.angleSearched = true
+	}
+	return s.angle
+}
+
+// codeContent strips one space on both sides like CommonMark, so code that
+// starts or ends with a backtick can be written
+func codeContent(code string) string {
+	code = strings.ReplaceAll(code, "\n", " ")
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+		return code[1 : len(code)-1]
+	}
+	return code
+}
+
+// parseLink parses [label](destination "title") starting at the opening
+// bracket and returns the index after the closing parenthesis
+func (s *inlineScan) parseLink(start int) (string, string, int, bool) {
+	source := s.source
+	labelEnd, ok := s.brackets[start]
+	if !ok || labelEnd+1 >= len(source) || source[labelEnd+1] != '(' {
+		return "", "", 0, false
+	}
+	destinationEnd, ok := s.brackets[labelEnd+1]
+	if !ok {
+		return "", "", 0, false
+	}
+
+	destination := strings.TrimSpace(source[labelEnd+2 : destinationEnd])
+	if strings.HasPrefix(destination, "<") {
+		if end := strings.IndexByte(destination, '>'); end > 0 {
+			destination = destination[1:end]
+		}
+	} else if fields := strings.Fields(destination); len(fields) > 0 {
+		destination = fields[0]
+	}
+
+	return source[start+1 : labelEnd], unescape(destination), destinationEnd + 1, true
+}
+
+// matchBrackets pairs brackets and parentheses in a single pass, escaped
+// ones are skipped and each kind is nested independently of the other
+func matchBrackets(source string) map[int]int {
+	matches := make(map[int]int)
+	var brackets, parentheses []int
+	for j := 0; j < len(source); j++ {
+		switch source[j] {
+		case '\\':
+			j++
+		case '[':
+			brackets = append(brackets, j)
+		case '(':
+			parentheses = append(parentheses, j)
+		case ']':
+			if n := len(brackets); n > 0 {
+				matches[brackets[n-1]] = j
+				brackets = brackets[:n-1]
+			}
+		case ')':
+			if n := len(parentheses); n > 0 {
+				matches[parentheses[n-1]] = j
+				parentheses = parentheses[:n-1]
+			}
+		}
+	}
+	return matches
+}
+
+func isAutolink(url string) bool {
+	if strings.ContainsAny(url, " <\n") {
+		return false
+	}
+	for _, scheme := range autolinkSchemes {
+		if strings.HasPrefix(strings.ToLower(url), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func with(attributes map[string]any, key string, value any) map[string]any {
+	result := maps.Clone(attributes)
+	if result == nil {
+		result = map[string]any{}
+	}
+	result[key] = value
+	return result
+}
+
+func unescape(text string) string {
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && isPunctuation(text[i+1]) {
+			i++
+		}
+		builder.WriteByte(text[i])
+	}
+	return builder.String()
+}
+
+// delimiterRun is the length of the run of c at start, counted up to three
+// because delimiters only care whether a run is longer than two
+func delimiterRun(source string, start int, c byte) int {
+	n := 0
+	for n < 3 && start+n < len(source) && source[start+n] == c {
+		n++
+	}
+	return n
+}
+
+func runLength(source string, start int, c byte) int {
+	n := 0
+	for start+n < len(source) && source[start+n] == c {
+		n++
+	}
+	return n
+}
+
+func isPunctuation(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n'
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,337 @@
+// Package markdown parses markdown and plain text into the xml nodes a
+// prosemirror editor stores in its Yjs fragment. It supports the CommonMark
+// blocks and inline formatting the editor can represent, together with
+// tables and strikethrough from GitHub flavored markdown.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+)
+
+var (
+	headingPattern    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fencePattern      = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	rulePattern       = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	bulletPattern     = regexp.MustCompile(`^( {0,3})([-*+])( +|$)`)
+	orderedPattern    = regexp.MustCompile(`^( {0,3})(\d{1,9})([.)])( +|$)`)
+	quotePattern      = regexp.MustCompile(`^ {0,3}> ?`)
+	setextPattern     = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	tableSepPattern   = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	imageOnlyPattern  = regexp.MustCompile(`^!\[[^\]]*\]\([^)]*\)$`)
+	blankLinePattern  = regexp.MustCompile(`^[ \t]*$`)
+	indentedCodeSpace = "    "
+)
+
+// Parse converts markdown into block nodes
+func Parse(source string) []yjs.XmlNode {
+	return parseBlocks(splitLines(source))
+}
+
+// ParseText converts plain text into paragraphs, lines separated by an
+// empty line start a new paragraph and single newlines become hard breaks
+func ParseText(source string) []yjs.XmlNode {
+	nodes := []yjs.XmlNode{}
+	paragraph := []string{}
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		children := []yjs.XmlNode{}
+		for i, line := range paragraph {
+			if i > 0 {
+				children = append(children, yjs.XmlNode{Name: "hardBreak"})
+			}
+			if line != "" {
+				children = append(children, yjs.XmlNode{Delta: []yjs.Delta{{Insert: line}}})
+			}
+		}
+		nodes = append(nodes, yjs.XmlNode{Name: "paragraph", Children: children})
+		paragraph = []string{}
+	}
+
+	for _, line := range splitLines(source) {
+		if blankLinePattern.MatchString(line) {
+			flush()
+			continue
+		}
+		paragraph = append(paragraph, strings.TrimRight(line, " \t"))
+	}
+	flush()
+	return nodes
+}
+
+func splitLines(source string) []string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	source = strings.ReplaceAll(source, "\t", "    ")
+	return strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+}
+
+func parseBlocks(lines []string) []yjs.XmlNode {
+	nodes := []yjs.XmlNode{}
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case blankLinePattern.MatchString(line):
+			i++
+		case headingPattern.MatchString(line):
+			match := headingPattern.FindStringSubmatch(line)
+			nodes = append(nodes, yjs.XmlNode{
+				Name:       "heading",
+				Attributes: map[string]any{"level": len(match[1])},
+				Children:   parseInline(match[2]),
+			})
+			i++
+		case fencePattern.MatchString(line):
+			node, next := parseFence(lines, i)
+			nodes = append(nodes, node)
+			i = next
+		case rulePattern.MatchString(line):
+			nodes = append(nodes, yjs.XmlNode{Name: "horizontalRule"})
+			i++
+		case quotePattern.MatchString(line):
+			quoted := []string{}
+			for i < len(lines) && !blankLinePattern.MatchString(lines[i]) {
+				quoted = append(quoted, quotePattern.ReplaceAllString(lines[i], ""))
+				i++
+			}
+			nodes = append(nodes, yjs.XmlNode{Name: "blockquote", Children: parseBlocks(quoted)})
+		case bulletPattern.MatchString(line) || orderedPattern.MatchString(line):
+			node, next := parseList(lines, i)
+			nodes = append(nodes, node)
+			i = next
+		case strings.HasPrefix(line, indentedCodeSpace):
+			code := []string{}
+			for i < len(lines) && (strings.HasPrefix(lines[i], indentedCodeSpace) || blankLinePattern.MatchString(lines[i])) {
+				code = append(code, strings.TrimPrefix(lines[i], indentedCodeSpace))
+				i++
+			}
+			nodes = append(nodes, codeBlock(strings.TrimRight(strings.Join(code, "\n"), "\n"), ""))
+		case strings.Contains(line, "|") && i+1 < len(lines) && tableSepPattern.MatchString(lines[i+1]):
+			node, next := parseTable(lines, i)
+			nodes = append(nodes, node)
+			i = next
+		default:
+			node, next := parseParagraph(lines, i)
+			nodes = append(nodes, node)
+			i = next
+		}
+	}
+	return nodes
+}
+
+func parseParagraph(lines []string, i int) (yjs.XmlNode, int) {
+	paragraph := []string{}
+	for i < len(lines) {
+		line := lines[i]
+		if len(paragraph) > 0 && setextPattern.MatchString(line) {
+			level := 2
+			if strings.Contains(line, "=") {
+				level = 1
+			}
+			return yjs.XmlNode{
+				Name:       "heading",
+				Attributes: map[string]any{"level": level},
+				Children:   parseInline(strings.Join(paragraph, "\n")),
+			}, i + 1
+		}
+		if blankLinePattern.MatchString(line) || (len(paragraph) > 0 && interruptsParagraph(line)) {
+			break
+		}
+		paragraph = append(paragraph, strings.TrimLeft(line, " "))
+		i++
+	}
+
+	text := strings.TrimRight(strings.Join(paragraph, "\n"), " ")
+	if imageOnlyPattern.MatchString(text) {
+		children := parseInline(text)
+		if len(children) == 1 && children[0].Name == "image" {
+			return children[0], i
+		}
+	}
+	return yjs.XmlNode{Name: "paragraph", Children: parseInline(text)}, i
+}
+
+func interruptsParagraph(line string) bool {
+	return headingPattern.MatchString(line) ||
+		fencePattern.MatchString(line) ||
+		rulePattern.MatchString(line) ||
+		quotePattern.MatchString(line) ||
+		bulletPattern.MatchString(line) ||
+		orderedPattern.MatchString(line)
+}
+
+func parseFence(lines []string, i int) (yjs.XmlNode, int) {
+	match := fencePattern.FindStringSubmatch(lines[i])
+	fence := match[1]
+	language := match[2]
+
+	code := []string{}
+	i++
+	for i < len(lines) {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+		i++
+	}
+	return codeBlock(strings.Join(code, "\n"), language), i
+}
+
+func codeBlock(code string, language string) yjs.XmlNode {
+	node := yjs.XmlNode{Name: "codeBlock"}
+	if language != "" {
+		node.Attributes = map[string]any{"language": language}
+	}
+	if code != "" {
+		node.Children = []yjs.XmlNode{{Delta: []yjs.Delta{{Insert: code}}}}
+	}
+	return node
+}
+
+// parseList collects items with the same kind of marker, lines indented to
+// the content of an item belong to that item and are parsed as blocks
+func parseList(lines []string, i int) (yjs.XmlNode, int) {
+	_, ordered, start, delimiter, _ := listMarker(lines[i])
+
+	node := yjs.XmlNode{Name: "bulletList"}
+	if ordered {
+		node.Name = "orderedList"
+		if start != 1 {
+			node.Attributes = map[string]any{"start": start}
+		}
+	}
+
+	for i < len(lines) {
+		isMarker, isOrdered, _, itemDelimiter, width := listMarker(lines[i])
+		if !isMarker || isOrdered != ordered || itemDelimiter != delimiter {
+			break
+		}
+
+		content := []string{""}
+		if width < len(lines[i]) {
+			content[0] = lines[i][width:]
+		}
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			if blankLinePattern.MatchString(line) {
+				if i+1 < len(lines) && indentation(lines[i+1]) >= width {
+					content = append(content, "")
+					i++
+					continue
+				}
+				break
+			}
+			if indentation(line) >= width {
+				content = append(content, line[width:])
+			} else if marker, _, _, _, _ := listMarker(line); !marker && !interruptsParagraph(line) {
+				// Lazy continuation of the paragraph of the item
+				content = append(content, strings.TrimLeft(line, " "))
+			} else {
+				break
+			}
+			i++
+		}
+
+		node.Children = append(node.Children, yjs.XmlNode{Name: "listItem", Children: parseBlocks(content)})
+
+		if i < len(lines) && blankLinePattern.MatchString(lines[i]) {
+			next := i
+			for next < len(lines) && blankLinePattern.MatchString(lines[next]) {
+				next++
+			}
+			if next < len(lines) {
+				if marker, isOrdered, _, itemDelimiter, _ := listMarker(lines[next]); marker && isOrdered == ordered && itemDelimiter == delimiter {
+					i = next
+				}
+			}
+		}
+	}
+	return node, i
+}
+
+// listMarker reports whether the line starts a list item and the width of
+// the marker including the spaces that follow it
+func listMarker(line string) (bool, bool, int, string, int) {
+	if rulePattern.MatchString(line) {
+		return false, false, 0, "", 0
+	}
+	if match := bulletPattern.FindStringSubmatch(line); match != nil {
+		return true, false, 0, match[2], markerWidth(len(match[1])+len(match[2]), match[3])
+	}
+	if match := orderedPattern.FindStringSubmatch(line); match != nil {
+		start := 0
+		for _, digit := range match[2] {
+			start = start*10 + int(digit-'0')
+		}
+		return true, true, start, match[3], markerWidth(len(match[1])+len(match[2])+len(match[3]), match[4])
+	}
+	return false, false, 0, "", 0
+}
+
+// markerWidth follows CommonMark, more than four spaces after the marker
+// start indented code so only one of them belongs to the marker
+func markerWidth(marker int, spaces string) int {
+	if len(spaces) == 0 || len(spaces) > 4 {
+		return marker + 1
+	}
+	return marker + len(spaces)
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func parseTable(lines []string, i int) (yjs.XmlNode, int) {
+	table := yjs.XmlNode{Name: "table"}
+	table.Children = append(table.Children, tableRow(lines[i], "tableHeader"))
+	i += 2
+
+	for i < len(lines) && !blankLinePattern.MatchString(lines[i]) && strings.Contains(lines[i], "|") {
+		table.Children = append(table.Children, tableRow(lines[i], "tableCell"))
+		i++
+	}
+	return table, i
+}
+
+func tableRow(line string, cellName string) yjs.XmlNode {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	row := yjs.XmlNode{Name: "tableRow"}
+	cell := strings.Builder{}
+	addCell := func() {
+		row.Children = append(row.Children, yjs.XmlNode{
+			Name: cellName,
+			Children: []yjs.XmlNode{{
+				Name:     "paragraph",
+				Children: parseInline(strings.TrimSpace(cell.String())),
+			}},
+		})
+		cell.Reset()
+	}
+
+	for j := 0; j < len(line); j++ {
+		switch {
+		case line[j] == '\\' && j+1 < len(line) && line[j+1] == '|':
+			cell.WriteByte('|')
+			j++
+		case line[j] == '|':
+			addCell()
+		default:
+			cell.WriteByte(line[j])
+		}
+	}
+	addCell()
+	return row
+}
@@ -0,0 +1,29 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+)
+
+// Enqueue wraps the event in an envelope and stores it in the outbox,
+// pass queries bound to the transaction of the domain change
+func Enqueue(ctx context.Context, q *db.Queries, event events.Event) error {
+	envelope, err := events.New(event)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	return q.CreateOutboxMessage(ctx, db.CreateOutboxMessageParams{
+		Topic: event.Topic(),
+		Key:   []byte(event.Subject()),
+		Value: value,
+	})
+}
@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/eventbus"
+	"github.com/rejdeboer/multiplayer-server/internal/logger"
+)
+
+var log = logger.Get()
+
+// Relay publishes the messages that were written to the outbox table to the event bus.
+// Messages are delivered at least once, in the order they were written.
+type Relay struct {
+	Pool         *pgxpool.Pool
+	Publisher    eventbus.EventPublisher
+	BatchSize    int32
+	PollInterval time.Duration
+	MaxBackoff   time.Duration
+	// The batch stays locked while it is published, the timeout bounds how
+	// long a hanging broker keeps the transaction open
+	PublishTimeout time.Duration
+	// A message that failed this many times is marked failed and no longer
+	// relayed, so a message the broker always rejects does not block the rest
+	MaxAttempts int32
+}
+
+// Validate rejects settings that would make the relay poll in a busy loop
+func (r *Relay) Validate() error {
+	if r.BatchSize <= 0 {
+		return errors.New("outbox batch size must be positive")
+	}
+	if r.PollInterval <= 0 {
+		return errors.New("outbox poll interval must be positive")
+	}
+	if r.MaxBackoff < r.PollInterval {
+		return errors.New("outbox max backoff can not be shorter than the poll interval")
+	}
+	if r.PublishTimeout <= 0 {
+		return errors.New("outbox publish timeout must be positive")
+	}
+	if r.MaxAttempts <= 0 {
+		return errors.New("outbox max attempts must be positive")
+	}
+	return nil
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	log.Info().Msg("starting outbox relay")
+
+	delay := r.PollInterval
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("stopping outbox relay")
+			return
+		case <-time.After(delay):
+		}
+
+		delivered, err := r.relayBatch(ctx)
+		if err != nil {
+			// The delay is 0 after a full batch, back off from the poll interval
+			delay = min(max(delay, r.PollInterval)*2, r.MaxBackoff)
+			log.Error().Err(err).Dur("retry_in", delay).Msg("error relaying outbox messages")
+			continue
+		}
+
+		delay = r.PollInterval
+		if delivered == int(r.BatchSize) {
+			// There are probably more messages waiting, don't wait for the next tick
+			delay = 0
+		}
+	}
+}
+
+// Flush relays pending messages until the outbox is empty
+func (r *Relay) Flush(ctx context.Context) error {
+	for {
+		delivered, err := r.relayBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if delivered < int(r.BatchSize) {
+			return nil
+		}
+	}
+}
+
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(r.Pool).WithTx(tx)
+
+	pending, err := q.GetPendingOutboxMessages(ctx, r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	ids := []int64{}
+	messages := []eventbus.Message{}
+	for _, message := range pending {
+		ids = append(ids, message.ID)
+		messages = append(messages, eventbus.Message{
+			Topic: message.Topic,
+			Key:   message.Key,
+			Value: message.Value,
+		})
+	}
+
+	delivered := 0
+	publishErr := r.publish(ctx, messages)
+	if publishErr == nil {
+		delivered = len(messages)
+	} else if len(messages) > 1 {
+		// Find the message that failed by publishing one at a time, the
+		// messages before it are delivered and only it counts an attempt
+		for delivered < len(messages) {
+			publishErr = r.publish(ctx, messages[delivered:delivered+1])
+			if publishErr != nil {
+				break
+			}
+			delivered++
+		}
+	}
+
+	if delivered > 0 {
+		err = q.MarkOutboxMessagesDelivered(ctx, ids[:delivered])
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if publishErr != nil {
+		lastError := publishErr.Error()
+		failedAt, err := q.MarkOutboxMessageFailed(ctx, db.MarkOutboxMessageFailedParams{
+			LastError:   &lastError,
+			MaxAttempts: r.MaxAttempts,
+			ID:          ids[delivered],
+		})
+		if err != nil {
+			return 0, err
+		}
+		if failedAt.Valid {
+			log.Error().Err(publishErr).Int64("message_id", ids[delivered]).Msg("giving up on outbox message")
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if delivered > 0 {
+		log.Info().Int("messages", delivered).Msg("relayed outbox messages")
+	}
+	return delivered, publishErr
+}
+
+func (r *Relay) publish(ctx context.Context, messages []eventbus.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, r.PublishTimeout)
+	defer cancel()
+	return r.Publisher.Publish(ctx, messages...)
+}
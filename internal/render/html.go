@@ -0,0 +1,226 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+)
+
+// Xml element names written by the editor mapped to the html tags they are
+// rendered as, both the camel and snake case prosemirror names are accepted
+var elementTags = map[string]string{
+	"paragraph":       "p",
+	"blockquote":      "blockquote",
+	"bulletList":      "ul",
+	"bullet_list":     "ul",
+	"orderedList":     "ol",
+	"ordered_list":    "ol",
+	"listItem":        "li",
+	"list_item":       "li",
+	"codeBlock":       "pre",
+	"code_block":      "pre",
+	"horizontalRule":  "hr",
+	"horizontal_rule": "hr",
+	"hardBreak":       "br",
+	"hard_break":      "br",
+	"table":           "table",
+	"tableRow":        "tr",
+	"table_row":       "tr",
+	"tableCell":       "td",
+	"table_cell":      "td",
+	"tableHeader":     "th",
+	"table_header":    "th",
+}
+
+var voidTags = []string{"hr", "br", "img"}
+
+// Text attributes rendered as inline tags, in nesting order
+var markTags = []struct {
+	names []string
+	tag   string
+}{
+	{names: []string{"bold", "strong"}, tag: "strong"},
+	{names: []string{"italic", "em"}, tag: "em"},
+	{names: []string{"underline"}, tag: "u"},
+	{names: []string{"strike", "strikethrough"}, tag: "s"},
+	{names: []string{"code"}, tag: "code"},
+}
+
+// HTML writes the text and xml types of the document to w as an html
+// fragment, every value that comes from the document is escaped
+func HTML(w io.Writer, doc *yjs.Doc) error {
+	builder := bufio.NewWriter(w)
+	for _, name := range doc.Roots() {
+		root := doc.Get(name)
+		switch root.Kind() {
+		case yjs.KindText:
+			writeTextBlocks(builder, root.Delta())
+		case yjs.KindXmlFragment:
+			for _, child := range root.Children() {
+				writeNode(builder, child)
+			}
+		}
+	}
+	// The buffered writer keeps the first write error and returns it here
+	return builder.Flush()
+}
+
+// writeTextBlocks renders a plain text type, every line becomes a paragraph
+func writeTextBlocks(builder *bufio.Writer, delta []yjs.Delta) {
+	for _, line := range splitLines(delta) {
+		builder.WriteString("<p>")
+		writeDelta(builder, line)
+		builder.WriteString("</p>")
+	}
+}
+
+// splitLines splits the delta of a text type on newlines, keeping the
+// attributes of every insert
+func splitLines(delta []yjs.Delta) [][]yjs.Delta {
+	lines := [][]yjs.Delta{}
+	line := []yjs.Delta{}
+	for _, op := range delta {
+		text, ok := op.Insert.(string)
+		if !ok {
+			line = append(line, op)
+			continue
+		}
+
+		parts := strings.Split(text, "\n")
+		for i, part := range parts {
+			if i > 0 {
+				lines = append(lines, line)
+				line = []yjs.Delta{}
+			}
+			if part != "" {
+				line = append(line, yjs.Delta{Insert: part, Attributes: op.Attributes})
+			}
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func writeNode(builder *bufio.Writer, node *yjs.Type) {
+	switch node.Kind() {
+	case yjs.KindXmlText:
+		writeDelta(builder, node.Delta())
+		return
+	case yjs.KindXmlElement:
+	default:
+		return
+	}
+
+	attributes := node.Attributes()
+	tag, ok := elementTags[node.Name()]
+	switch {
+	case node.Name() == "heading":
+		tag = fmt.Sprintf("h%d", headingLevel(attributes["level"]))
+	case node.Name() == "image":
+		src, _ := attributes["src"].(string)
+		alt, _ := attributes["alt"].(string)
+		if safeURL(src) {
+			fmt.Fprintf(builder, `<img src="%s" alt="%s">`, html.EscapeString(src), html.EscapeString(alt))
+		}
+		return
+	case !ok:
+		tag = "div"
+	}
+
+	builder.WriteString("<" + tag + ">")
+	if slices.Contains(voidTags, tag) {
+		return
+	}
+	if tag == "pre" {
+		builder.WriteString("<code>")
+		builder.WriteString(html.EscapeString(node.String()))
+		builder.WriteString("</code>")
+	} else {
+		for _, child := range node.Children() {
+			writeNode(builder, child)
+		}
+	}
+	builder.WriteString("</" + tag + ">")
+}
+
+func writeDelta(builder *bufio.Writer, delta []yjs.Delta) {
+	for _, op := range delta {
+		text, ok := op.Insert.(string)
+		if !ok {
+			continue
+		}
+
+		closing := []string{}
+		href, _ := op.Attributes["link"].(string)
+		if link, ok := op.Attributes["link"].(map[string]any); ok {
+			href, _ = link["href"].(string)
+		}
+		if href != "" && safeURL(href) {
+			fmt.Fprintf(builder, `<a href="%s">`, html.EscapeString(href))
+			closing = append(closing, "</a>")
+		}
+
+		for _, mark := range markTags {
+			if hasAttribute(op.Attributes, mark.names) {
+				builder.WriteString("<" + mark.tag + ">")
+				closing = append(closing, "</"+mark.tag+">")
+			}
+		}
+
+		builder.WriteString(html.EscapeString(text))
+		for i := len(closing) - 1; i >= 0; i-- {
+			builder.WriteString(closing[i])
+		}
+	}
+}
+
+func hasAttribute(attributes map[string]any, names []string) bool {
+	for _, name := range names {
+		if value, ok := attributes[name]; ok && value != false {
+			return true
+		}
+	}
+	return false
+}
+
+func headingLevel(value any) int {
+	return min(max(intAttribute(value, 1), 1), 6)
+}
+
+// intAttribute reads a number attribute, editors store them as numbers or
+// as strings depending on the schema
+func intAttribute(value any, fallback int) int {
+	switch v := value.(type) {
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case string:
+		var number int
+		if _, err := fmt.Sscanf(v, "%d", &number); err == nil {
+			return number
+		}
+	}
+	return fallback
+}
+
+// safeURL only allows links that can not run scripts in the page
+func safeURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https", "mailto", "":
+		return true
+	}
+	return false
+}
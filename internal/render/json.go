@@ -0,0 +1,71 @@
+package render
+
+import (
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+)
+
+type Node struct {
+	Type       string         `json:"type"`
+	Name       string         `json:"name,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Children   []Node         `json:"children,omitempty"`
+	Delta      []yjs.Delta    `json:"delta,omitempty"`
+}
+
+// JSON maps every top level type of the document to a value that can be
+// marshalled, texts become deltas, xml fragments become node trees and maps
+// and arrays are converted like Yjs does
+func JSON(doc *yjs.Doc) map[string]any {
+	content := make(map[string]any)
+	for _, name := range doc.Roots() {
+		root := doc.Get(name)
+		switch root.Kind() {
+		case yjs.KindText:
+			content[name] = textDelta(root)
+		case yjs.KindXmlFragment:
+			content[name] = nodeChildren(root)
+		case yjs.KindMap, yjs.KindArray:
+			content[name] = root.ToJSON()
+		}
+	}
+	return content
+}
+
+func nodeChildren(parent *yjs.Type) []Node {
+	nodes := []Node{}
+	for _, child := range parent.Children() {
+		switch child.Kind() {
+		case yjs.KindXmlText:
+			nodes = append(nodes, Node{Type: "text", Delta: textDelta(child)})
+		case yjs.KindXmlElement:
+			nodes = append(nodes, Node{
+				Type:       "element",
+				Name:       child.Name(),
+				Attributes: scalarAttributes(child.Attributes()),
+				Children:   nodeChildren(child),
+			})
+		}
+	}
+	return nodes
+}
+
+// textDelta drops nested types from the delta, they can not be marshalled
+func textDelta(text *yjs.Type) []yjs.Delta {
+	delta := []yjs.Delta{}
+	for _, op := range text.Delta() {
+		if _, ok := op.Insert.(*yjs.Type); ok {
+			continue
+		}
+		delta = append(delta, op)
+	}
+	return delta
+}
+
+func scalarAttributes(attributes map[string]any) map[string]any {
+	for key, value := range attributes {
+		if _, ok := value.(*yjs.Type); ok {
+			delete(attributes, key)
+		}
+	}
+	return attributes
+}
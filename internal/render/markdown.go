@@ -0,0 +1,332 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+)
+
+// Markdown writes the text and xml types of the document to w as CommonMark,
+// formatting without a markdown equivalent such as underline is dropped
+func Markdown(w io.Writer, doc *yjs.Doc) error {
+	return blockWriter{}.document(w, doc)
+}
+
+// Text writes the text and xml types of the document to w without
+// formatting, blocks are separated by an empty line and list items keep
+// their marker
+func Text(w io.Writer, doc *yjs.Doc) error {
+	return blockWriter{plain: true}.document(w, doc)
+}
+
+// blockWriter renders xml nodes as blocks of lines, plain leaves out all
+// markdown syntax except list markers
+type blockWriter struct {
+	plain bool
+}
+
+// document writes every top level block as soon as it is rendered, so only
+// a single block is held in memory
+func (w blockWriter) document(out io.Writer, doc *yjs.Doc) error {
+	separator := ""
+	write := func(block string) error {
+		if block == "" {
+			return nil
+		}
+		_, err := io.WriteString(out, separator+block)
+		separator = "\n\n"
+		return err
+	}
+
+	for _, name := range doc.Roots() {
+		root := doc.Get(name)
+		switch root.Kind() {
+		case yjs.KindText:
+			if w.plain {
+				if err := write(strings.TrimRight(root.String(), "\n")); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, line := range splitLines(root.Delta()) {
+				if err := write(w.paragraph(w.inline(line))); err != nil {
+					return err
+				}
+			}
+		case yjs.KindXmlFragment:
+			for _, child := range root.Children() {
+				if err := write(w.block(child)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if separator == "" {
+		return nil
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+func (w blockWriter) blocks(nodes []*yjs.Type) []string {
+	blocks := []string{}
+	for _, node := range nodes {
+		blocks = append(blocks, w.block(node))
+	}
+	return nonEmpty(blocks)
+}
+
+func (w blockWriter) block(node *yjs.Type) string {
+	switch node.Kind() {
+	case yjs.KindXmlText:
+		return w.inline(node.Delta())
+	case yjs.KindXmlElement:
+	default:
+		return ""
+	}
+
+	attributes := node.Attributes()
+	switch node.Name() {
+	case "heading":
+		text := w.inlineChildren(node)
+		if w.plain {
+			return text
+		}
+		return strings.Repeat("#", headingLevel(attributes["level"])) + " " + text
+	case "image":
+		return w.image(attributes)
+	}
+
+	switch elementTags[node.Name()] {
+	case "p":
+		return w.paragraph(w.inlineChildren(node))
+	case "blockquote":
+		content := strings.Join(w.blocks(node.Children()), "\n\n")
+		if w.plain {
+			return content
+		}
+		return prefixLines(content, "> ")
+	case "ul", "ol":
+		return w.list(node)
+	case "pre":
+		if w.plain {
+			return node.String()
+		}
+		language, _ := attributes["language"].(string)
+		fence := "```"
+		for strings.Contains(node.String(), fence) {
+			fence += "`"
+		}
+		return fence + language + "\n" + node.String() + "\n" + fence
+	case "hr":
+		if w.plain {
+			return ""
+		}
+		return "---"
+	case "table":
+		return w.table(node)
+	}
+	return strings.Join(w.blocks(node.Children()), "\n\n")
+}
+
+// paragraph escapes text that would otherwise start a heading or quote
+func (w blockWriter) paragraph(text string) string {
+	if !w.plain && (strings.HasPrefix(text, "#") || strings.HasPrefix(text, ">")) {
+		return `\` + text
+	}
+	return text
+}
+
+func (w blockWriter) list(node *yjs.Type) string {
+	ordered := elementTags[node.Name()] == "ol"
+	attributes := node.Attributes()
+	start := intAttribute(attributes["start"], intAttribute(attributes["order"], 1))
+
+	items := []string{}
+	for i, item := range node.Children() {
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", start+i)
+		}
+		content := strings.Join(w.blocks(item.Children()), "\n")
+		items = append(items, marker+indentLines(content, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (w blockWriter) table(node *yjs.Type) string {
+	rows := [][]string{}
+	for _, row := range node.Children() {
+		cells := []string{}
+		for _, cell := range row.Children() {
+			text := strings.ReplaceAll(strings.Join(w.blocks(cell.Children()), " "), "\n", " ")
+			if !w.plain {
+				text = strings.ReplaceAll(text, "|", `\|`)
+			}
+			cells = append(cells, text)
+		}
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+
+	lines := []string{}
+	for i, cells := range rows {
+		if w.plain {
+			lines = append(lines, strings.Join(cells, "\t"))
+			continue
+		}
+
+		for len(cells) < len(rows[0]) {
+			cells = append(cells, "")
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", len(cells)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (w blockWriter) inlineChildren(node *yjs.Type) string {
+	var builder strings.Builder
+	for _, child := range node.Children() {
+		switch {
+		case child.Kind() == yjs.KindXmlText:
+			builder.WriteString(w.inline(child.Delta()))
+		case elementTags[child.Name()] == "br":
+			if w.plain {
+				builder.WriteString("\n")
+			} else {
+				builder.WriteString("\\\n")
+			}
+		case child.Name() == "image":
+			builder.WriteString(w.image(child.Attributes()))
+		default:
+			builder.WriteString(w.inlineChildren(child))
+		}
+	}
+	return builder.String()
+}
+
+func (w blockWriter) inline(delta []yjs.Delta) string {
+	var builder strings.Builder
+	for _, op := range delta {
+		text, ok := op.Insert.(string)
+		if !ok {
+			continue
+		}
+		if w.plain {
+			builder.WriteString(text)
+		} else {
+			builder.WriteString(markdownInline(text, op.Attributes))
+		}
+	}
+	return builder.String()
+}
+
+func (w blockWriter) image(attributes map[string]any) string {
+	src, _ := attributes["src"].(string)
+	alt, _ := attributes["alt"].(string)
+	if w.plain {
+		return alt
+	}
+	if !safeURL(src) {
+		return markdownEscaper.Replace(alt)
+	}
+	return "![" + markdownEscaper.Replace(alt) + "](" + markdownURL(src) + ")"
+}
+
+// markdownInline wraps text in the markers of its attributes, markers can
+// not be next to whitespace so surrounding whitespace is moved outside
+func markdownInline(text string, attributes map[string]any) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+
+	content := markdownEscaper.Replace(trimmed)
+	if hasAttribute(attributes, []string{"code"}) {
+		content = codeSpan(trimmed)
+	}
+	if hasAttribute(attributes, []string{"strike", "strikethrough"}) {
+		content = "~~" + content + "~~"
+	}
+	if hasAttribute(attributes, []string{"italic", "em"}) {
+		content = "*" + content + "*"
+	}
+	if hasAttribute(attributes, []string{"bold", "strong"}) {
+		content = "**" + content + "**"
+	}
+
+	href, _ := attributes["link"].(string)
+	if link, ok := attributes["link"].(map[string]any); ok {
+		href, _ = link["href"].(string)
+	}
+	if href != "" && safeURL(href) {
+		content = "[" + content + "](" + markdownURL(href) + ")"
+	}
+
+	return leading + content + trailing
+}
+
+// codeSpan uses a backtick fence longer than any run of backticks in text
+func codeSpan(text string) string {
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+func markdownURL(raw string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(raw)
+}
+
+func prefixLines(text string, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentLines indents every line except the first, which follows a marker
+func indentLines(text string, indent string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func nonEmpty(blocks []string) []string {
+	result := []string{}
+	for _, block := range blocks {
+		if block != "" {
+			result = append(result, block)
+		}
+	}
+	return result
+}
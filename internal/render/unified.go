@@ -0,0 +1,210 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Number of unchanged lines shown around every change
+const UNIFIED_CONTEXT = 3
+
+// lineOp is a line of the edit script, kind is ' ' for a kept line, '-'
+// for a deleted line and '+' for an inserted line. from and to are the
+// indices of the line in the old and the new text before the op.
+type lineOp struct {
+	kind byte
+	line string
+	from int
+	to   int
+}
+
+// Unified renders the changes between two texts as a unified diff, the
+// format of diff -u. The result is empty when the texts are the same.
+func Unified(fromName string, toName string, from string, to string) string {
+	ops := diffLines(splitAfterLines(from), splitAfterLines(to))
+
+	var builder strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Changes separated by at most twice the context share a hunk
+		last, unchanged := i, 0
+		for j := i; j < len(ops) && unchanged <= 2*UNIFIED_CONTEXT; j++ {
+			if ops[j].kind == ' ' {
+				unchanged++
+			} else {
+				last, unchanged = j, 0
+			}
+		}
+		start := max(i-UNIFIED_CONTEXT, 0)
+		end := min(last+1+UNIFIED_CONTEXT, len(ops))
+
+		if builder.Len() == 0 {
+			fmt.Fprintf(&builder, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&builder, ops[start:end])
+		i = end
+	}
+	return builder.String()
+}
+
+func writeHunk(builder *strings.Builder, ops []lineOp) {
+	fromLines, toLines := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			fromLines++
+		}
+		if op.kind != '-' {
+			toLines++
+		}
+	}
+	fmt.Fprintf(builder, "@@ -%s +%s @@\n", hunkRange(ops[0].from, fromLines), hunkRange(ops[0].to, toLines))
+
+	for _, op := range ops {
+		builder.WriteByte(op.kind)
+		builder.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			builder.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the first line and the number of lines of a hunk, an
+// empty range points at the line before it
+func hunkRange(index int, lines int) string {
+	switch lines {
+	case 0:
+		return fmt.Sprintf("%d,0", index)
+	case 1:
+		return fmt.Sprintf("%d", index+1)
+	default:
+		return fmt.Sprintf("%d,%d", index+1, lines)
+	}
+}
+
+func splitAfterLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script that turns a into b, using the
+// linear space variant of Myers. The middle snake of the script splits it in
+// two halves that are diffed on their own, so only the furthest reaching
+// paths of the current round are kept instead of those of every round.
+func diffLines(a []string, b []string) []lineOp {
+	d := &lineDiff{a: a, b: b, ops: []lineOp{}}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+type lineDiff struct {
+	a   []string
+	b   []string
+	ops []lineOp
+}
+
+// compare appends the edit script that turns a[x0:x1] into b[y0:y1]
+func (d *lineDiff) compare(x0 int, x1 int, y0 int, y1 int) {
+	for x0 < x1 && y0 < y1 && d.a[x0] == d.b[y0] {
+		d.ops = append(d.ops, lineOp{kind: ' ', line: d.a[x0], from: x0, to: y0})
+		x0++
+		y0++
+	}
+	suffixX := x1
+	for x0 < x1 && y0 < y1 && d.a[x1-1] == d.b[y1-1] {
+		x1--
+		y1--
+	}
+
+	switch {
+	case x0 == x1:
+		for y := y0; y < y1; y++ {
+			d.ops = append(d.ops, lineOp{kind: '+', line: d.b[y], from: x0, to: y})
+		}
+	case y0 == y1:
+		for x := x0; x < x1; x++ {
+			d.ops = append(d.ops, lineOp{kind: '-', line: d.a[x], from: x, to: y0})
+		}
+	default:
+		x, y := d.middleSnake(x0, x1, y0, y1)
+		d.compare(x0, x, y0, y)
+		d.compare(x, x1, y, y1)
+	}
+
+	for x1 < suffixX {
+		d.ops = append(d.ops, lineOp{kind: ' ', line: d.a[x1], from: x1, to: y1})
+		x1++
+		y1++
+	}
+}
+
+// middleSnake searches for the shortest edit script from both ends at once
+// and returns the point where the paths meet. Both texts are not empty and
+// differ in their first and last line, so the script has at least two edits
+// and the point is neither the start nor the end.
+func (d *lineDiff) middleSnake(x0 int, x1 int, y0 int, y1 int) (int, int) {
+	n, m := x1-x0, y1-y0
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+
+	// forward holds the furthest x on every diagonal from the start,
+	// backward holds the furthest distance from the end on every diagonal
+	// counted from the end
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for step := 0; step <= maxD; step++ {
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[x0+x] == d.b[y0+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			reverseK := delta - k
+			if odd && reverseK >= -(step-1) && reverseK <= step-1 && x+backward[offset+reverseK] >= n {
+				return x0 + x, y0 + y
+			}
+		}
+
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[x1-1-x] == d.b[y1-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			forwardK := delta - k
+			if !odd && forwardK >= -step && forwardK <= step && forward[offset+forwardK]+x >= n {
+				forwardX := forward[offset+forwardK]
+				return x0 + forwardX, y0 + forwardX - forwardK
+			}
+		}
+	}
+	panic("render: no middle snake found")
+}
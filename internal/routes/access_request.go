@@ -0,0 +1,393 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rejdeboer/multiplayer-server/internal/audit"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	ACCESS_REQUEST_APPROVED = "approved"
+	ACCESS_REQUEST_DENIED   = "denied"
+
+	MAX_ACCESS_REQUEST_MESSAGE_LENGTH = 500
+	// A user whose request was denied can not ask again for this long, so
+	// denying a request is not undone by sending it again
+	ACCESS_REQUEST_DENIED_COOLDOWN = 7 * 24 * time.Hour
+)
+
+type AccessRequestCreate struct {
+	Role    string `json:"role"`
+	Message string `json:"message"`
+}
+
+type AccessRequestResponse struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"userId"`
+	Role      string    `json:"role"`
+	Message   string    `json:"message"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// createAccessRequest lets a user without access ask the owner for it, asking
+// again while a request is pending updates that request. After a denial the
+// user has to wait for ACCESS_REQUEST_DENIED_COOLDOWN before asking again.
+func (env *Env) createAccessRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	var payload AccessRequestCreate
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		httperrors.Write(w, "Invalid access request body", http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid body for create access request")
+		return
+	}
+
+	err = validateAccessRequestCreate(&payload)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid access request")
+		return
+	}
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	document, err := q.GetDocumnetByID(ctx, docID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && document.DeletedAt.Valid) {
+		httperrors.Write(w, "Document not found", http.StatusNotFound)
+		log.Error().Err(err).Msg("document not found")
+		return
+	} else if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching document")
+		return
+	}
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_VIEWER)
+	if err == nil {
+		httperrors.Write(w, "You already have access to this document", http.StatusConflict)
+		log.Error().Msg("user requested access to a document they can access")
+		return
+	}
+
+	denied, err := q.HasDeniedAccessRequestSince(ctx, db.HasDeniedAccessRequestSinceParams{
+		DocumentID:  docID,
+		UserID:      userID,
+		DeniedSince: pgtype.Timestamptz{Time: time.Now().Add(-ACCESS_REQUEST_DENIED_COOLDOWN), Valid: true},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error checking denied access requests")
+		return
+	}
+	if denied {
+		httperrors.Write(w, "Your last request for access was denied, please try again later", http.StatusConflict)
+		log.Error().Msg("user requested access again after a recent denial")
+		return
+	}
+
+	request, err := q.UpsertAccessRequest(ctx, db.UpsertAccessRequestParams{
+		DocumentID: docID,
+		UserID:     userID,
+		Role:       payload.Role,
+		Message:    payload.Message,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error storing access request")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.ACCESS_REQUEST_CREATED,
+		Details:    map[string]any{"requestId": request.ID, "role": request.Role},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	response, err := json.Marshal(accessRequestResponse(request))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Str("request_id", request.ID.String()).Msg("requested access")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(response)
+}
+
+func (env *Env) listAccessRequests(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	q := db.New(env.Pool)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not list access requests")
+		return
+	}
+
+	requests, err := q.ListPendingAccessRequests(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to fetch access requests from db")
+		return
+	}
+
+	result := []AccessRequestResponse{}
+	for _, request := range requests {
+		result = append(result, accessRequestResponse(request))
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Int("requests", len(result)).Msg("sending access requests")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (env *Env) approveAccessRequest(w http.ResponseWriter, r *http.Request) {
+	env.decideAccessRequest(w, r, ACCESS_REQUEST_APPROVED)
+}
+
+func (env *Env) denyAccessRequest(w http.ResponseWriter, r *http.Request) {
+	env.decideAccessRequest(w, r, ACCESS_REQUEST_DENIED)
+}
+
+// decideAccessRequest closes a pending request, approving it adds the
+// requesting user as a contributor with the requested role
+func (env *Env) decideAccessRequest(w http.ResponseWriter, r *http.Request, status string) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	requestID, err := uuid.Parse(r.PathValue("request_id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid request id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid request id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Str("request_id", requestID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not decide on access requests")
+		return
+	}
+
+	request, err := q.GetPendingAccessRequest(ctx, db.GetPendingAccessRequestParams{
+		ID:         requestID,
+		DocumentID: docID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		httperrors.Write(w, "Access request not found", http.StatusNotFound)
+		log.Error().Err(err).Msg("no pending access request")
+		return
+	} else if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching access request")
+		return
+	}
+
+	err = q.DecideAccessRequest(ctx, db.DecideAccessRequestParams{
+		ID:        requestID,
+		Status:    status,
+		DecidedBy: pgtype.UUID{Bytes: userID, Valid: true},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error updating access request")
+		return
+	}
+
+	action := audit.ACCESS_REQUEST_DENIED
+	if status == ACCESS_REQUEST_APPROVED {
+		action = audit.ACCESS_REQUEST_APPROVED
+
+		added, err := q.CreateDocumentContributorIfNotExists(ctx, db.CreateDocumentContributorIfNotExistsParams{
+			DocumentID: docID,
+			UserID:     request.UserID,
+			Role:       request.Role,
+		})
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("error adding contributor")
+			return
+		}
+
+		if added > 0 {
+			err = outbox.Enqueue(ctx, q, events.ContributorAdded{
+				DocumentID: docID,
+				UserID:     request.UserID,
+				AddedBy:    userID,
+			})
+			if err != nil {
+				httperrors.InternalServerError(w)
+				log.Error().Err(err).Msg("failed to write contributor event to outbox")
+				return
+			}
+		}
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     action,
+		Details:    map[string]any{"requestId": request.ID, "userId": request.UserID, "role": request.Role},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	request.Status = status
+	response, err := json.Marshal(accessRequestResponse(request))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Str("status", status).Msg("decided on access request")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func validateAccessRequestCreate(request *AccessRequestCreate) error {
+	if request.Role == "" {
+		request.Role = ROLE_VIEWER
+	}
+	if !isValidRole(request.Role) || request.Role == ROLE_OWNER {
+		return errors.New("role must be one of: editor, commenter, viewer")
+	}
+
+	if utf8.RuneCountInString(request.Message) > MAX_ACCESS_REQUEST_MESSAGE_LENGTH {
+		return fmt.Errorf("message can be at most %d characters", MAX_ACCESS_REQUEST_MESSAGE_LENGTH)
+	}
+
+	return nil
+}
+
+func accessRequestResponse(request db.AccessRequest) AccessRequestResponse {
+	return AccessRequestResponse{
+		ID:        request.ID,
+		UserID:    request.UserID,
+		Role:      request.Role,
+		Message:   request.Message,
+		Status:    request.Status,
+		CreatedAt: request.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+)
+
+const (
+	ROLE_OWNER     = "owner"
+	ROLE_EDITOR    = "editor"
+	ROLE_COMMENTER = "commenter"
+	ROLE_VIEWER    = "viewer"
+)
+
+// Roles ordered from least to most privileged, every role is allowed to do
+// everything the roles before it can do
+var roles = []string{ROLE_VIEWER, ROLE_COMMENTER, ROLE_EDITOR, ROLE_OWNER}
+
+var (
+	errDocumentNotFound = errors.New("document not found or user has no access")
+	errInsufficientRole = errors.New("user role does not allow this action")
+)
+
+func isValidRole(role string) bool {
+	return slices.Contains(roles, role)
+}
+
+// hasRole reports whether role grants at least the permissions of minimumRole
+func hasRole(role string, minimumRole string) bool {
+	return slices.Index(roles, role) >= slices.Index(roles, minimumRole)
+}
+
+// authorizeDocument is the single place where access to a document is checked.
+// It returns the document as seen by the user when they have at least
+// minimumRole on it. Users without any role get errDocumentNotFound so the
+// existence of the document is not leaked.
+func authorizeDocument(
+	ctx context.Context,
+	q *db.Queries,
+	docID uuid.UUID,
+	userID uuid.UUID,
+	minimumRole string,
+) (DocumentResponse, string, error) {
+	document, err := getDocumentAsUser(ctx, docID, userID, q)
+	if err != nil {
+		return DocumentResponse{}, "", errDocumentNotFound
+	}
+
+	role := document.RoleOf(userID)
+	if !hasRole(role, minimumRole) {
+		return DocumentResponse{}, role, errInsufficientRole
+	}
+
+	return document, role, nil
+}
+
+func writeAuthorizationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInsufficientRole) {
+		httperrors.Write(w, "You do not have permission to do this", http.StatusForbidden)
+		return
+	}
+	httperrors.Write(w, "Document not found", http.StatusNotFound)
+}
@@ -1,32 +1,188 @@
 package routes
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
 	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
 	"github.com/rs/zerolog"
 )
 
+const MAX_CONTRIBUTORS_PER_REQUEST = 50
+
+// DocumentContributorCreate identifies the user to add by exactly one of
+// userId, email or username
 type DocumentContributorCreate struct {
+	UserID   uuid.UUID `json:"userId"`
+	Email    string    `json:"email"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+}
+
+type ContributorAddResult struct {
 	UserID uuid.UUID `json:"userId"`
+	Role   string    `json:"role"`
+	Added  bool      `json:"added"`
 }
 
+var errUserNotFound = errors.New("user not found")
+
+// addDocumentContributor accepts either a single contributor or an array of
+// them. A single contributor that already has access results in a 409, in a
+// bulk request existing contributors are skipped and reported with their
+// current role.
 func (env *Env) addDocumentContributor(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := zerolog.Ctx(ctx)
 
-	var contributor DocumentContributorCreate
-	err := json.NewDecoder(r.Body).Decode(&contributor)
+	contributors, isBulk, err := decodeContributors(r.Body)
 	if err != nil {
 		httperrors.Write(w, err.Error(), http.StatusBadRequest)
 		log.Error().Err(err).Msg("invalid body for create contributor")
 		return
 	}
 
-	q := db.New(env.Pool)
+	docID, err := uuid.Parse(r.PathValue("document_id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_EDITOR)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not add contributors")
+		return
+	}
+
+	results := []ContributorAddResult{}
+	for _, contributor := range contributors {
+		user, err := resolveContributor(ctx, q, contributor)
+		if errors.Is(err, errUserNotFound) {
+			httperrors.Write(w, "User not found", http.StatusNotFound)
+			log.Error().Err(err).Msg("contributor does not exist")
+			return
+		} else if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("error looking up contributor")
+			return
+		}
+
+		added, err := q.CreateDocumentContributorIfNotExists(ctx, db.CreateDocumentContributorIfNotExistsParams{
+			DocumentID: docID,
+			UserID:     user.ID,
+			Role:       contributor.Role,
+		})
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("error adding contributor")
+			return
+		}
+
+		if added == 0 {
+			// Existing contributors keep their role, report the stored one instead of the requested one
+			role, err := q.GetDocumentContributorRole(ctx, db.GetDocumentContributorRoleParams{
+				DocumentID: docID,
+				UserID:     user.ID,
+			})
+			if err != nil {
+				httperrors.InternalServerError(w)
+				log.Error().Err(err).Msg("error fetching role of existing contributor")
+				return
+			}
+
+			results = append(results, ContributorAddResult{
+				UserID: user.ID,
+				Role:   role,
+				Added:  false,
+			})
+			log.Info().Str("contributor_id", user.ID.String()).Msg("user is already a contributor")
+			continue
+		}
+
+		results = append(results, ContributorAddResult{
+			UserID: user.ID,
+			Role:   contributor.Role,
+			Added:  true,
+		})
+
+		err = outbox.Enqueue(ctx, q, events.ContributorAdded{
+			DocumentID: docID,
+			UserID:     user.ID,
+			AddedBy:    userID,
+		})
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("failed to write contributor event to outbox")
+			return
+		}
+	}
+
+	if !isBulk && !results[0].Added {
+		httperrors.Write(w, "User is already a contributor", http.StatusConflict)
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	var response []byte
+	if isBulk {
+		response, err = json.Marshal(results)
+	} else {
+		response, err = json.Marshal(results[0])
+	}
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Int("contributors", len(results)).Msg("added contributors")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (env *Env) removeDocumentContributor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
 
 	docID, err := uuid.Parse(r.PathValue("document_id"))
 	if err != nil {
@@ -35,6 +191,13 @@ func (env *Env) addDocumentContributor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	contributorID, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid user id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid contributor id format")
+		return
+	}
+
 	userID, err := uuid.Parse(ctx.Value("user_id").(string))
 	if err != nil {
 		httperrors.InternalServerError(w)
@@ -45,25 +208,219 @@ func (env *Env) addDocumentContributor(w http.ResponseWriter, r *http.Request) {
 	*log = log.With().
 		Str("document_id", docID.String()).
 		Str("user_id", userID.String()).
-		Str("contributor_id", contributor.UserID.String()).
+		Str("contributor_id", contributorID.String()).
 		Logger()
 
-	_, err = getDocumentAsUser(ctx, docID, userID, q)
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	document, _, err := authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
 	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not remove contributors")
+		return
+	}
+
+	if contributorID == document.OwnerID {
+		httperrors.Write(w, "The owner can not be removed from a document", http.StatusBadRequest)
+		log.Error().Msg("user tried to remove the owner")
+		return
+	}
+
+	if document.RoleOf(contributorID) == "" {
+		httperrors.Write(w, "Contributor not found", http.StatusNotFound)
+		log.Error().Msg("user is not a contributor of document")
+		return
+	}
+
+	err = removeContributor(ctx, q, docID, contributorID, userID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error removing contributor")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	log.Info().Msg("removed contributor")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (env *Env) leaveDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, role, err := authorizeDocument(ctx, q, docID, userID, ROLE_VIEWER)
+	if err != nil {
+		writeAuthorizationError(w, err)
 		log.Error().Err(err).Msg("error fetching document")
-		httperrors.Write(w, "Document not found", http.StatusNotFound)
 		return
 	}
 
-	err = q.CreateDocumentContributor(ctx, db.CreateDocumentContributorParams{
-		DocumentID: docID,
-		UserID:     contributor.UserID,
-	})
+	if role == ROLE_OWNER {
+		httperrors.Write(w, "The owner can not leave a document, transfer ownership first", http.StatusBadRequest)
+		log.Error().Msg("owner tried to leave document")
+		return
+	}
+
+	err = removeContributor(ctx, q, docID, userID, userID)
 	if err != nil {
 		httperrors.InternalServerError(w)
-		log.Error().Err(err).Msg("error adding contributor")
+		log.Error().Err(err).Msg("error leaving document")
+		return
 	}
 
-	log.Info().Msg("added contributor")
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	log.Info().Msg("user left document")
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// removeContributor revokes access and publishes a contributor.removed event
+// so live sessions of the removed user can be closed
+func removeContributor(
+	ctx context.Context,
+	q *db.Queries,
+	docID uuid.UUID,
+	contributorID uuid.UUID,
+	removedBy uuid.UUID,
+) error {
+	_, err := q.DeleteDocumentContributor(ctx, db.DeleteDocumentContributorParams{
+		DocumentID: docID,
+		UserID:     contributorID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return outbox.Enqueue(ctx, q, events.ContributorRemoved{
+		DocumentID: docID,
+		UserID:     contributorID,
+		RemovedBy:  removedBy,
+	})
+}
+
+func decodeContributors(body io.Reader) ([]DocumentContributorCreate, bool, error) {
+	bytes, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var contributors []DocumentContributorCreate
+	isBulk := len(bytes) > 0 && strings.HasPrefix(strings.TrimSpace(string(bytes)), "[")
+	if isBulk {
+		err = json.Unmarshal(bytes, &contributors)
+	} else {
+		var contributor DocumentContributorCreate
+		err = json.Unmarshal(bytes, &contributor)
+		contributors = append(contributors, contributor)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(contributors) == 0 {
+		return nil, false, errors.New("at least one contributor must be provided")
+	}
+	if len(contributors) > MAX_CONTRIBUTORS_PER_REQUEST {
+		return nil, false, fmt.Errorf("at most %d contributors can be added at once", MAX_CONTRIBUTORS_PER_REQUEST)
+	}
+
+	for i := range contributors {
+		err = validateContributorCreate(&contributors[i])
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return contributors, isBulk, nil
+}
+
+func validateContributorCreate(contributor *DocumentContributorCreate) error {
+	identifiers := 0
+	if contributor.UserID != uuid.Nil {
+		identifiers++
+	}
+	if contributor.Email != "" {
+		identifiers++
+	}
+	if contributor.Username != "" {
+		identifiers++
+	}
+	if identifiers != 1 {
+		return errors.New("provide exactly one of userId, email or username")
+	}
+
+	if contributor.Role == "" {
+		contributor.Role = ROLE_EDITOR
+	}
+	if !isValidRole(contributor.Role) || contributor.Role == ROLE_OWNER {
+		return errors.New("role must be one of: editor, commenter, viewer")
+	}
+
+	return nil
+}
+
+func resolveContributor(ctx context.Context, q *db.Queries, contributor DocumentContributorCreate) (db.User, error) {
+	var user db.User
+	var err error
+	switch {
+	case contributor.Email != "":
+		user, err = q.GetUserByEmail(ctx, contributor.Email)
+	case contributor.Username != "":
+		user, err = q.GetUserByUsername(ctx, contributor.Username)
+	default:
+		user, err = q.GetUserByID(ctx, contributor.UserID)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.User{}, errUserNotFound
+	}
+	return user, err
+}
@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/render"
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+// DiffChangeResponse is a run of text that was kept, inserted or deleted.
+// Author is who inserted or deleted the text and is null for kept text.
+type DiffChangeResponse struct {
+	Kind   yjs.ChangeKind `json:"kind"`
+	Text   string         `json:"text"`
+	Author *uuid.UUID     `json:"author"`
+}
+
+// DiffResponse describes the changes from the document as of From to the
+// document as of To, Insertions and Deletions count characters
+type DiffResponse struct {
+	From       int32                `json:"from"`
+	To         int32                `json:"to"`
+	Insertions int                  `json:"insertions"`
+	Deletions  int                  `json:"deletions"`
+	Changes    []DiffChangeResponse `json:"changes"`
+	Unified    string               `json:"unified"`
+}
+
+// diffVersions compares two versions of the document. The text diff follows
+// the document and attributes changes to the authors of the updates, the
+// unified diff compares the plain text export of both versions by line.
+func (env *Env) diffVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	q := db.New(env.Pool)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	from, err := parseClock(r.URL.Query().Get("from"))
+	if err != nil {
+		httperrors.Write(w, "from: "+err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid from clock")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Int32("from", from).
+		Logger()
+
+	document, _, err := authorizeDocument(ctx, q, docID, userID, ROLE_VIEWER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not view document history")
+		return
+	}
+
+	// The latest version is compared when to is left out
+	var to int32
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		to, err = parseClock(raw)
+		if err != nil {
+			httperrors.Write(w, "to: "+err.Error(), http.StatusBadRequest)
+			log.Error().Err(err).Msg("user used invalid to clock")
+			return
+		}
+	} else {
+		to, err = q.GetLatestDocumentClock(ctx, docID)
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("error fetching latest clock")
+			return
+		}
+	}
+
+	if from > to {
+		httperrors.Write(w, "from can not be after to", http.StatusBadRequest)
+		log.Error().Int32("to", to).Msg("user used from after to")
+		return
+	}
+
+	updates, err := q.ListDocumentUpdatesUntil(ctx, db.ListDocumentUpdatesUntilParams{
+		DocumentID: docID,
+		Clock:      to,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching document updates")
+		return
+	}
+	if len(updates) == 0 || updates[len(updates)-1].Clock != to {
+		httperrors.Write(w, "Version not found", http.StatusNotFound)
+		log.Error().Int32("to", to).Msg("no update with clock")
+		return
+	}
+
+	fromUpdates := [][]byte{}
+	toUpdates := [][]byte{}
+	authors := yjs.NewAuthors()
+	authorIDs := map[string]*uuid.UUID{}
+	for _, update := range updates {
+		toUpdates = append(toUpdates, update.Value)
+		if update.Clock <= from {
+			fromUpdates = append(fromUpdates, update.Value)
+			continue
+		}
+
+		author := ""
+		if authorID := nullableUUID(update.AuthorID); authorID != nil {
+			author = authorID.String()
+			authorIDs[author] = authorID
+		}
+		err = authors.Add(update.Value, author)
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Int32("clock", update.Clock).Msg("failed to decode document update")
+			return
+		}
+	}
+
+	fromContent, err := yjs.MergeUpdates(fromUpdates)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to load document version")
+		return
+	}
+	toContent, err := yjs.MergeUpdates(toUpdates)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to load document version")
+		return
+	}
+
+	var fromText, toText strings.Builder
+	err = render.Text(&fromText, fromContent)
+	if err == nil {
+		err = render.Text(&toText, toContent)
+	}
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to render document version")
+		return
+	}
+
+	result := DiffResponse{
+		From:    from,
+		To:      to,
+		Changes: []DiffChangeResponse{},
+		Unified: render.Unified(
+			fmt.Sprintf("%s@%d", document.Name, from),
+			fmt.Sprintf("%s@%d", document.Name, to),
+			fromText.String(),
+			toText.String(),
+		),
+	}
+	for _, change := range yjs.DiffText(fromContent, toContent, authors) {
+		switch change.Kind {
+		case yjs.ChangeInsert:
+			result.Insertions += utf8.RuneCountInString(change.Text)
+		case yjs.ChangeDelete:
+			result.Deletions += utf8.RuneCountInString(change.Text)
+		}
+		result.Changes = append(result.Changes, DiffChangeResponse{
+			Kind:   change.Kind,
+			Text:   change.Text,
+			Author: authorIDs[change.Author],
+		})
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Int32("to", to).Int("changes", len(result.Changes)).Msg("sending document diff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
@@ -4,11 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
-	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
 	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
 	"github.com/rs/zerolog"
 )
@@ -17,16 +24,35 @@ type DocumentCreate struct {
 	Name string `json:"name"`
 }
 
-type DocumentResponse struct {
-	ID           uuid.UUID   `json:"id"`
-	Name         string      `json:"name"`
-	OwnerID      uuid.UUID   `json:"ownerId"`
-	Contributors []uuid.UUID `json:"contributors"`
+const (
+	MAX_DOCUMENT_NAME_LENGTH        = 255
+	MAX_DOCUMENT_DESCRIPTION_LENGTH = 2000
+	MAX_DOCUMENT_ICON_LENGTH        = 8
+)
+
+// DocumentPatch only updates the fields that are present, an empty icon
+// removes the current one
+type DocumentPatch struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+}
+
+type ContributorResponse struct {
+	UserID uuid.UUID `json:"userId"`
+	Role   string    `json:"role"`
 }
 
-type DocumentListItem struct {
-	ID   uuid.UUID `json:"id"`
-	Name string    `json:"name"`
+type DocumentResponse struct {
+	ID           uuid.UUID             `json:"id"`
+	Name         string                `json:"name"`
+	Description  string                `json:"description"`
+	Icon         *string               `json:"icon"`
+	OwnerID      uuid.UUID             `json:"ownerId"`
+	Contributors []ContributorResponse `json:"contributors"`
+	CreatedAt    time.Time             `json:"createdAt"`
+	UpdatedAt    time.Time             `json:"updatedAt"`
+	LastEditedBy *uuid.UUID            `json:"lastEditedBy"`
 }
 
 func (env *Env) createDocument(w http.ResponseWriter, r *http.Request) {
@@ -41,7 +67,12 @@ func (env *Env) createDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	q := db.New(env.Pool)
+	err = validateDocumentName(document.Name)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid document name")
+		return
+	}
 
 	userID, err := uuid.Parse(ctx.Value("user_id").(string))
 	if err != nil {
@@ -50,47 +81,93 @@ func (env *Env) createDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdDocument, err := q.CreateDocument(ctx, db.CreateDocumentParams{
-		Name:    document.Name,
-		OwnerID: userID,
-	})
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	createdDocument, err := insertDocument(ctx, q, document.Name, userID)
 	if err != nil {
 		httperrors.InternalServerError(w)
-		log.Error().Err(err).Msg("failed to push document to db")
+		log.Error().Err(err).Msg("failed to create document")
 		return
 	}
 	*log = log.With().
 		Str("document_id", createdDocument.ID.String()).
 		Str("user_id", userID.String()).
 		Logger()
-	log.Info().Msg("created new document")
 
-	err = q.CreateDocumentContributor(ctx, db.CreateDocumentContributorParams{
-		DocumentID: createdDocument.ID,
-		UserID:     userID,
-	})
+	err = tx.Commit(ctx)
 	if err != nil {
 		httperrors.InternalServerError(w)
-		log.Error().Err(err).Msg("error adding owner as contributor")
+		log.Error().Err(err).Msg("failed to commit transaction")
 		return
 	}
-	log.Info().Msg("added owner as contributor")
 
-	response, err := json.Marshal(DocumentResponse{
-		ID:      createdDocument.ID,
-		Name:    createdDocument.Name,
-		OwnerID: createdDocument.OwnerID,
-	})
+	response, err := json.Marshal(createdDocumentResponse(createdDocument))
 	if err != nil {
 		httperrors.InternalServerError(w)
 		log.Error().Err(err).Msg("error marshalling response")
 		return
 	}
 
+	log.Info().Msg("created new document")
 	w.WriteHeader(http.StatusOK)
 	w.Write(response)
 }
 
+// insertDocument is the single path through which documents are created, it
+// adds the owner as contributor and announces the document on the outbox
+func insertDocument(ctx context.Context, q *db.Queries, name string, ownerID uuid.UUID) (db.Document, error) {
+	document, err := q.CreateDocument(ctx, db.CreateDocumentParams{
+		Name:    name,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		return db.Document{}, fmt.Errorf("failed to push document to db: %w", err)
+	}
+
+	err = q.CreateDocumentContributor(ctx, db.CreateDocumentContributorParams{
+		DocumentID: document.ID,
+		UserID:     ownerID,
+		Role:       ROLE_OWNER,
+	})
+	if err != nil {
+		return db.Document{}, fmt.Errorf("error adding owner as contributor: %w", err)
+	}
+
+	err = outbox.Enqueue(ctx, q, events.DocumentCreated{
+		ID:      document.ID,
+		Name:    document.Name,
+		OwnerID: document.OwnerID,
+	})
+	if err != nil {
+		return db.Document{}, fmt.Errorf("failed to write document event to outbox: %w", err)
+	}
+
+	return document, nil
+}
+
+func createdDocumentResponse(document db.Document) DocumentResponse {
+	return DocumentResponse{
+		ID:          document.ID,
+		Name:        document.Name,
+		Description: document.Description,
+		Icon:        document.Icon,
+		OwnerID:     document.OwnerID,
+		Contributors: []ContributorResponse{
+			{UserID: document.OwnerID, Role: ROLE_OWNER},
+		},
+		CreatedAt: document.CreatedAt.Time,
+		UpdatedAt: document.UpdatedAt.Time,
+	}
+}
+
 func (env *Env) deleteDocument(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := zerolog.Ctx(ctx)
@@ -114,38 +191,67 @@ func (env *Env) deleteDocument(w http.ResponseWriter, r *http.Request) {
 		Str("user_id", userID.String()).
 		Logger()
 
-	q := db.New(env.Pool)
-
-	document, err := q.GetDocumnetByID(ctx, docID)
+	tx, err := env.Pool.Begin(ctx)
 	if err != nil {
-		httperrors.Write(w, "Document not found", http.StatusNotFound)
-		log.Error().Err(err).Msg("document not found")
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	if document.OwnerID != userID {
-		httperrors.Write(w, "Document not found", http.StatusNotFound)
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
+	if err != nil {
+		writeAuthorizationError(w, err)
 		log.Error().Err(err).Msg("user has no right to delete document")
 		return
 	}
 
-	err = q.DeleteDocument(ctx, docID)
+	err = q.SoftDeleteDocument(ctx, db.SoftDeleteDocumentParams{
+		DeletedBy: pgtype.UUID{Bytes: userID, Valid: true},
+		ID:        docID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to move document to trash")
+		return
+	}
+
+	err = outbox.Enqueue(ctx, q, events.DocumentDeleted{
+		ID:        docID,
+		DeletedBy: userID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write document event to outbox")
+		return
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
-		httperrors.Write(w, "document not found", http.StatusNotFound)
-		log.Error().Err(err).Msg("failed to delete document")
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
 		return
 	}
-	log.Info().Msg("deleted document")
+	log.Info().Msg("moved document to trash")
 
 	w.WriteHeader(http.StatusAccepted)
 }
 
-func (env *Env) listDocuments(w http.ResponseWriter, r *http.Request) {
+func (env *Env) getDocument(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := zerolog.Ctx(ctx)
 
 	q := db.New(env.Pool)
 
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
 	userID, err := uuid.Parse(ctx.Value("user_id").(string))
 	if err != nil {
 		httperrors.InternalServerError(w)
@@ -153,39 +259,37 @@ func (env *Env) listDocuments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbDocuments, err := q.GetDocumentsByOwnerID(ctx, userID)
+	document, _, err := authorizeDocument(ctx, q, docID, userID, ROLE_VIEWER)
 	if err != nil {
-		httperrors.InternalServerError(w)
-		log.Error().Err(err).Msg("failed to fetch documents from db")
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("error fetching document")
 		return
 	}
 
-	documents := []DocumentListItem{}
-	for _, document := range dbDocuments {
-		documents = append(documents, DocumentListItem{
-			ID:   document.ID,
-			Name: document.Name,
-		})
+	err = q.UpdateDocumentLastOpened(ctx, db.UpdateDocumentLastOpenedParams{
+		DocumentID: docID,
+		UserID:     userID,
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to record document as opened")
 	}
 
-	response, err := json.Marshal(documents)
+	response, err := json.Marshal(document)
 	if err != nil {
 		httperrors.InternalServerError(w)
 		log.Error().Err(err).Msg("error marshalling response")
 		return
 	}
 
-	log.Info().Int("items", len(documents)).Msg("sending document list")
+	log.Info().Str("document_id", docID.String()).Msg("sending document")
 	w.WriteHeader(http.StatusOK)
 	w.Write(response)
 }
 
-func (env *Env) getDocument(w http.ResponseWriter, r *http.Request) {
+func (env *Env) updateDocument(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := zerolog.Ctx(ctx)
 
-	q := db.New(env.Pool)
-
 	docID, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
 		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
@@ -193,6 +297,21 @@ func (env *Env) getDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var patch DocumentPatch
+	err = json.NewDecoder(r.Body).Decode(&patch)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid body for update document")
+		return
+	}
+
+	err = validateDocumentPatch(patch)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid document update")
+		return
+	}
+
 	userID, err := uuid.Parse(ctx.Value("user_id").(string))
 	if err != nil {
 		httperrors.InternalServerError(w)
@@ -200,13 +319,69 @@ func (env *Env) getDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	document, err := getDocumentAsUser(ctx, docID, userID, q)
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	document, _, err := authorizeDocument(ctx, q, docID, userID, ROLE_EDITOR)
 	if err != nil {
-		log.Error().Err(err).Msg("error fetching document")
-		httperrors.Write(w, "Document not found", http.StatusNotFound)
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not update document")
 		return
 	}
 
+	updatedDocument, err := q.UpdateDocumentMetadata(ctx, db.UpdateDocumentMetadataParams{
+		Name:        patch.Name,
+		Description: patch.Description,
+		Icon:        patch.Icon,
+		EditedBy:    pgtype.UUID{Bytes: userID, Valid: true},
+		ID:          docID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to update document")
+		return
+	}
+
+	if updatedDocument.Name != document.Name {
+		err = outbox.Enqueue(ctx, q, events.DocumentRenamed{
+			ID:           docID,
+			Name:         updatedDocument.Name,
+			PreviousName: document.Name,
+			RenamedBy:    userID,
+		})
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("failed to write document event to outbox")
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+	log.Info().Msg("updated document")
+
+	document.Name = updatedDocument.Name
+	document.Description = updatedDocument.Description
+	document.Icon = updatedDocument.Icon
+	document.UpdatedAt = updatedDocument.UpdatedAt.Time
+	document.LastEditedBy = nullableUUID(updatedDocument.LastEditedBy)
+
 	response, err := json.Marshal(document)
 	if err != nil {
 		httperrors.InternalServerError(w)
@@ -214,7 +389,6 @@ func (env *Env) getDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Info().Str("document_id", docID.String()).Msg("sending document")
 	w.WriteHeader(http.StatusOK)
 	w.Write(response)
 }
@@ -230,19 +404,102 @@ func getDocumentAsUser(
 		return DocumentResponse{}, err
 	}
 
-	var contributors []uuid.UUID
+	contributors := []ContributorResponse{}
 	for _, row := range rows {
-		contributors = append(contributors, row.ContributorID)
+		contributors = append(contributors, ContributorResponse{
+			UserID: row.ContributorID,
+			Role:   row.ContributorRole,
+		})
 	}
 
-	if !slices.Contains(contributors, userID) {
+	document := DocumentResponse{Contributors: contributors}
+	if document.RoleOf(userID) == "" {
 		return DocumentResponse{}, errors.New("user does not have access rights")
 	}
 
-	return DocumentResponse{
-		ID:           rows[0].ID,
-		OwnerID:      rows[0].OwnerID,
-		Name:         rows[0].Name,
-		Contributors: contributors,
-	}, nil
+	document.ID = rows[0].ID
+	document.OwnerID = rows[0].OwnerID
+	document.Name = rows[0].Name
+	document.Description = rows[0].Description
+	document.Icon = rows[0].Icon
+	document.CreatedAt = rows[0].CreatedAt.Time
+	document.UpdatedAt = rows[0].UpdatedAt.Time
+	document.LastEditedBy = nullableUUID(rows[0].LastEditedBy)
+	return document, nil
+}
+
+// RoleOf returns the role the user has on the document, or an empty string
+// when they are not a contributor
+func (d DocumentResponse) RoleOf(userID uuid.UUID) string {
+	for _, contributor := range d.Contributors {
+		if contributor.UserID == userID {
+			return contributor.Role
+		}
+	}
+	return ""
+}
+
+func nullableUUID(value pgtype.UUID) *uuid.UUID {
+	if !value.Valid {
+		return nil
+	}
+	id := uuid.UUID(value.Bytes)
+	return &id
+}
+
+func nullableTime(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid || value.InfinityModifier != pgtype.Finite {
+		return nil
+	}
+	return &value.Time
+}
+
+func validateDocumentPatch(patch DocumentPatch) error {
+	if patch.Name == nil && patch.Description == nil && patch.Icon == nil {
+		return errors.New("at least one of name, description or icon must be provided")
+	}
+
+	if patch.Name != nil {
+		err := validateDocumentName(*patch.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	if patch.Description != nil && utf8.RuneCountInString(*patch.Description) > MAX_DOCUMENT_DESCRIPTION_LENGTH {
+		return fmt.Errorf("description can not be longer than %d characters", MAX_DOCUMENT_DESCRIPTION_LENGTH)
+	}
+
+	if patch.Icon != nil {
+		return validateDocumentIcon(*patch.Icon)
+	}
+
+	return nil
+}
+
+func validateDocumentName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("document name can not be empty")
+	}
+	if utf8.RuneCountInString(name) > MAX_DOCUMENT_NAME_LENGTH {
+		return fmt.Errorf("document name can not be longer than %d characters", MAX_DOCUMENT_NAME_LENGTH)
+	}
+	return nil
+}
+
+// validateDocumentIcon only accepts emoji, which may be made up of several
+// code points joined together. An empty icon is valid and clears it.
+func validateDocumentIcon(icon string) error {
+	if utf8.RuneCountInString(icon) > MAX_DOCUMENT_ICON_LENGTH {
+		return errors.New("icon must be a single emoji")
+	}
+
+	for _, char := range icon {
+		switch {
+		case unicode.IsLetter(char) || unicode.IsDigit(char) || unicode.IsSpace(char) || unicode.IsControl(char):
+			return errors.New("icon must be a single emoji")
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,286 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+type DocumentListItem struct {
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	Icon         *string    `json:"icon"`
+	OwnerID      uuid.UUID  `json:"ownerId"`
+	IsOwner      bool       `json:"isOwner"`
+	Role         string     `json:"role"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	LastEditedBy *uuid.UUID `json:"lastEditedBy"`
+	LastOpenedAt *time.Time `json:"lastOpenedAt"`
+}
+
+const (
+	SORT_NAME        = "name"
+	SORT_CREATED     = "created"
+	SORT_UPDATED     = "updated"
+	SORT_LAST_OPENED = "lastOpened"
+)
+
+var ownerFilters = []string{"me", "others", "any"}
+
+var documentSorts = []string{SORT_NAME, SORT_CREATED, SORT_UPDATED, SORT_LAST_OPENED}
+
+// Documents that were never opened by the user sort after all opened ones
+const NEVER_OPENED = "-infinity"
+
+type documentListQuery struct {
+	UserID      uuid.UUID
+	OwnerFilter string
+	NamePrefix  string
+	Sort        string
+	Cursor      *Cursor
+	Limit       int
+}
+
+func (env *Env) listDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	q := db.New(env.Pool)
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	query := r.URL.Query()
+
+	ownerFilter := query.Get("owner")
+	if ownerFilter == "" {
+		ownerFilter = "any"
+	}
+	if !slices.Contains(ownerFilters, ownerFilter) {
+		httperrors.Write(w, "owner must be one of: me, others, any", http.StatusBadRequest)
+		log.Error().Str("owner", ownerFilter).Msg("invalid owner filter")
+		return
+	}
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = SORT_NAME
+	}
+	if !slices.Contains(documentSorts, sort) {
+		httperrors.Write(w, "sort must be one of: name, created, updated, lastOpened", http.StatusBadRequest)
+		log.Error().Str("sort", sort).Msg("invalid document sort")
+		return
+	}
+
+	limit, err := parsePageSize(query)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid page size")
+		return
+	}
+
+	cursor, err := decodeCursor(query.Get("cursor"), sort)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid document cursor")
+		return
+	}
+
+	// One extra row is fetched to find out whether there is a next page
+	rows, err := listDocumentRows(ctx, q, documentListQuery{
+		UserID:      userID,
+		OwnerFilter: ownerFilter,
+		NamePrefix:  query.Get("name"),
+		Sort:        sort,
+		Cursor:      cursor,
+		Limit:       limit + 1,
+	})
+	if errors.Is(err, errInvalidCursor) {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid document cursor")
+		return
+	} else if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to fetch documents from db")
+		return
+	}
+
+	page := Page[DocumentListItem]{Items: []DocumentListItem{}}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		page.NextCursor = documentCursor(sort, rows[len(rows)-1]).Encode()
+	}
+
+	for _, document := range rows {
+		isOwner := document.OwnerID == userID
+		role := ROLE_OWNER
+		if !isOwner && document.Role != nil {
+			role = *document.Role
+		}
+		page.Items = append(page.Items, DocumentListItem{
+			ID:           document.ID,
+			Name:         document.Name,
+			Icon:         document.Icon,
+			OwnerID:      document.OwnerID,
+			IsOwner:      isOwner,
+			Role:         role,
+			CreatedAt:    document.CreatedAt.Time,
+			UpdatedAt:    document.UpdatedAt.Time,
+			LastEditedBy: nullableUUID(document.LastEditedBy),
+			LastOpenedAt: nullableTime(document.LastOpenedAt),
+		})
+	}
+
+	response, err := json.Marshal(page)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Int("items", len(page.Items)).Str("sort", sort).Msg("sending document list")
+	writePageLink(w, r, page.NextCursor)
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// listDocumentRows runs the keyset query matching the requested sort. When no
+// cursor is given the keyset starts at a bound that sorts before every row.
+func listDocumentRows(
+	ctx context.Context,
+	q *db.Queries,
+	params documentListQuery,
+) ([]db.ListDocumentsByNameRow, error) {
+	afterID := uuid.Max
+	afterTime := pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}
+	if params.Cursor != nil {
+		afterID = params.Cursor.ID
+	}
+
+	if params.Sort == SORT_NAME {
+		afterName := ""
+		if params.Cursor != nil {
+			afterName = params.Cursor.Value
+		} else {
+			afterID = uuid.Nil
+		}
+		return q.ListDocumentsByName(ctx, db.ListDocumentsByNameParams{
+			UserID:      params.UserID,
+			OwnerFilter: params.OwnerFilter,
+			NamePrefix:  params.NamePrefix,
+			AfterName:   afterName,
+			AfterID:     afterID,
+			PageSize:    int32(params.Limit),
+		})
+	}
+
+	if params.Cursor != nil {
+		var err error
+		afterTime, err = parseCursorTime(params.Cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []db.ListDocumentsByNameRow
+	switch params.Sort {
+	case SORT_CREATED:
+		result, err := q.ListDocumentsByCreatedAt(ctx, db.ListDocumentsByCreatedAtParams{
+			UserID:         params.UserID,
+			OwnerFilter:    params.OwnerFilter,
+			NamePrefix:     params.NamePrefix,
+			AfterCreatedAt: afterTime,
+			AfterID:        afterID,
+			PageSize:       int32(params.Limit),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range result {
+			rows = append(rows, db.ListDocumentsByNameRow(row))
+		}
+	case SORT_UPDATED:
+		result, err := q.ListDocumentsByUpdatedAt(ctx, db.ListDocumentsByUpdatedAtParams{
+			UserID:         params.UserID,
+			OwnerFilter:    params.OwnerFilter,
+			NamePrefix:     params.NamePrefix,
+			AfterUpdatedAt: afterTime,
+			AfterID:        afterID,
+			PageSize:       int32(params.Limit),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range result {
+			rows = append(rows, db.ListDocumentsByNameRow(row))
+		}
+	case SORT_LAST_OPENED:
+		result, err := q.ListDocumentsByLastOpenedAt(ctx, db.ListDocumentsByLastOpenedAtParams{
+			UserID:            params.UserID,
+			OwnerFilter:       params.OwnerFilter,
+			NamePrefix:        params.NamePrefix,
+			AfterLastOpenedAt: afterTime,
+			AfterID:           afterID,
+			PageSize:          int32(params.Limit),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range result {
+			rows = append(rows, db.ListDocumentsByNameRow(row))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sort: %s", params.Sort)
+	}
+
+	return rows, nil
+}
+
+func documentCursor(sort string, row db.ListDocumentsByNameRow) Cursor {
+	cursor := Cursor{Sort: sort, ID: row.ID}
+	switch sort {
+	case SORT_NAME:
+		cursor.Value = row.Name
+	case SORT_CREATED:
+		cursor.Value = formatCursorTime(row.CreatedAt)
+	case SORT_UPDATED:
+		cursor.Value = formatCursorTime(row.UpdatedAt)
+	case SORT_LAST_OPENED:
+		cursor.Value = formatCursorTime(row.LastOpenedAt)
+	}
+	return cursor
+}
+
+func formatCursorTime(value pgtype.Timestamptz) string {
+	if !value.Valid || value.InfinityModifier == pgtype.NegativeInfinity {
+		return NEVER_OPENED
+	}
+	return value.Time.UTC().Format(time.RFC3339Nano)
+}
+
+func parseCursorTime(value string) (pgtype.Timestamptz, error) {
+	if value == NEVER_OPENED {
+		return pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return pgtype.Timestamptz{}, errInvalidCursor
+	}
+	return pgtype.Timestamptz{Time: parsed, Valid: true}, nil
+}
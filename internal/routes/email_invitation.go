@@ -0,0 +1,403 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/audit"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/mailer"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+type EmailInvitationCreate struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type EmailInvitationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy uuid.UUID `json:"invitedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Sent is false when the invitation was stored but the mail could not be
+	// delivered, inviting the same address again retries sending it
+	Sent bool `json:"sent"`
+}
+
+// createEmailInvitation invites someone without an account to a document.
+// The invitation turns into a contributor once a user registers with the
+// invited address and the token from the mail, which proves they own it.
+func (env *Env) createEmailInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	var payload EmailInvitationCreate
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		httperrors.Write(w, "Invalid invitation body", http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid body for create email invitation")
+		return
+	}
+
+	err = validateEmailInvitationCreate(&payload)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid email invitation")
+		return
+	}
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	document, _, err := authorizeDocument(ctx, q, docID, userID, ROLE_EDITOR)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not invite to document")
+		return
+	}
+
+	exists, err := q.UserEmailExists(ctx, payload.Email)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error looking up user by email")
+		return
+	}
+	if exists {
+		httperrors.Write(w, "A user with that email already exists, add them as a contributor instead", http.StatusConflict)
+		log.Error().Msg("invited email already has an account")
+		return
+	}
+
+	inviter, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching inviting user")
+		return
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to generate invitation token")
+		return
+	}
+
+	// Inviting the same address again keeps the token of the first mail
+	invitation, err := q.UpsertEmailInvitation(ctx, db.UpsertEmailInvitationParams{
+		DocumentID: docID,
+		Email:      payload.Email,
+		Role:       payload.Role,
+		InvitedBy:  userID,
+		Token:      token,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error storing email invitation")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.EMAIL_INVITATION_CREATED,
+		Details:    map[string]any{"invitationId": invitation.ID, "role": invitation.Role},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	// The mail is sent after committing so a slow or failing relay never
+	// rolls back the invitation itself
+	result := emailInvitationResponse(invitation)
+	err = env.Mailer.Send(ctx, invitationMessage(env.SignupUrl, invitation, inviter.Username, document.Name))
+	if err != nil {
+		log.Error().Err(err).Msg("failed to send invitation mail")
+	} else {
+		result.Sent = true
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Str("invitation_id", invitation.ID.String()).Bool("sent", result.Sent).Msg("invited user by email")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (env *Env) listEmailInvitations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	q := db.New(env.Pool)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_EDITOR)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not list email invitations")
+		return
+	}
+
+	invitations, err := q.ListEmailInvitations(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to fetch email invitations from db")
+		return
+	}
+
+	result := []EmailInvitationResponse{}
+	for _, invitation := range invitations {
+		result = append(result, emailInvitationResponse(invitation))
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Int("invitations", len(result)).Msg("sending email invitations")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (env *Env) cancelEmailInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	invitationID, err := uuid.Parse(r.PathValue("invitation_id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid invitation id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid invitation id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Str("invitation_id", invitationID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_EDITOR)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not cancel email invitations")
+		return
+	}
+
+	deleted, err := q.DeleteEmailInvitation(ctx, db.DeleteEmailInvitationParams{
+		ID:         invitationID,
+		DocumentID: docID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error deleting email invitation")
+		return
+	}
+	if deleted == 0 {
+		httperrors.Write(w, "Invitation not found", http.StatusNotFound)
+		log.Error().Msg("email invitation not found")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.EMAIL_INVITATION_CANCELLED,
+		Details:    map[string]any{"invitationId": invitationID},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	log.Info().Msg("cancelled email invitation")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// claimEmailInvitations turns the pending invitations for the email of a
+// newly registered user into contributor rows, it returns how many documents
+// the user was added to. The token of one of the invitation mails proves the
+// user owns the address, without it the invitations stay pending.
+func claimEmailInvitations(ctx context.Context, q *db.Queries, user db.User, token string) (int, error) {
+	invitations, err := q.ClaimEmailInvitations(ctx, db.ClaimEmailInvitationsParams{
+		Email: user.Email,
+		Token: token,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, invitation := range invitations {
+		_, err = q.CreateDocumentContributorIfNotExists(ctx, db.CreateDocumentContributorIfNotExistsParams{
+			DocumentID: invitation.DocumentID,
+			UserID:     user.ID,
+			Role:       invitation.Role,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		err = audit.Record(ctx, q, audit.Entry{
+			DocumentID: invitation.DocumentID,
+			ActorID:    user.ID,
+			Action:     audit.EMAIL_INVITATION_ACCEPTED,
+			Details:    map[string]any{"invitationId": invitation.ID, "role": invitation.Role},
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		err = outbox.Enqueue(ctx, q, events.ContributorAdded{
+			DocumentID: invitation.DocumentID,
+			UserID:     user.ID,
+			AddedBy:    invitation.InvitedBy,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(invitations), nil
+}
+
+func validateEmailInvitationCreate(invitation *EmailInvitationCreate) error {
+	address, err := mail.ParseAddress(invitation.Email)
+	if err != nil || address.Address != invitation.Email {
+		return errors.New("invalid email address")
+	}
+	invitation.Email = strings.ToLower(invitation.Email)
+
+	if invitation.Role == "" {
+		invitation.Role = ROLE_EDITOR
+	}
+	if !isValidRole(invitation.Role) || invitation.Role == ROLE_OWNER {
+		return errors.New("role must be one of: editor, commenter, viewer")
+	}
+
+	return nil
+}
+
+func invitationMessage(signupUrl string, invitation db.EmailInvitation, inviter string, documentName string) mailer.Message {
+	link := signupUrl + "?" + url.Values{"email": {invitation.Email}, "token": {invitation.Token}}.Encode()
+
+	return mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("%s invited you to %s", inviter, documentName),
+		Body: fmt.Sprintf(
+			"%s invited you to collaborate on %s as %s.\n\nCreate an account with this email address to get access:\n%s\n",
+			inviter,
+			documentName,
+			invitation.Role,
+			link,
+		),
+	}
+}
+
+func emailInvitationResponse(invitation db.EmailInvitation) EmailInvitationResponse {
+	return EmailInvitationResponse{
+		ID:        invitation.ID,
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		InvitedBy: invitation.InvitedBy,
+		CreatedAt: invitation.CreatedAt.Time,
+	}
+}
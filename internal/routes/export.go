@@ -0,0 +1,143 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/render"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	EXPORT_MARKDOWN = "md"
+	EXPORT_HTML     = "html"
+	EXPORT_TEXT     = "txt"
+	EXPORT_JSON     = "json"
+)
+
+var exportFormats = []string{EXPORT_MARKDOWN, EXPORT_HTML, EXPORT_TEXT, EXPORT_JSON}
+
+var exportContentTypes = map[string]string{
+	EXPORT_MARKDOWN: "text/markdown; charset=utf-8",
+	EXPORT_HTML:     "text/html; charset=utf-8",
+	EXPORT_TEXT:     "text/plain; charset=utf-8",
+	EXPORT_JSON:     "application/json",
+}
+
+type DocumentExport struct {
+	ID          uuid.UUID      `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Icon        *string        `json:"icon"`
+	Content     map[string]any `json:"content"`
+}
+
+// exportDocument rebuilds the document from its persisted updates and sends
+// it as a download in the requested format, markdown by default
+func (env *Env) exportDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	q := db.New(env.Pool)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = EXPORT_MARKDOWN
+	}
+	if !slices.Contains(exportFormats, format) {
+		httperrors.Write(w, "format must be one of: "+strings.Join(exportFormats, ", "), http.StatusBadRequest)
+		log.Error().Str("format", format).Msg("user requested invalid export format")
+		return
+	}
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Str("format", format).
+		Logger()
+
+	document, _, err := authorizeDocument(ctx, q, docID, userID, ROLE_VIEWER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not export document")
+		return
+	}
+
+	content, err := loadDocumentContent(ctx, q, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to load document content")
+		return
+	}
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": exportFilename(document.Name, format),
+	}))
+	w.WriteHeader(http.StatusOK)
+
+	switch format {
+	case EXPORT_MARKDOWN:
+		err = render.Markdown(w, content)
+	case EXPORT_TEXT:
+		err = render.Text(w, content)
+	case EXPORT_HTML:
+		err = writeDocumentPage(w, document.Name, document.Icon, func(w io.Writer) error {
+			return render.HTML(w, content)
+		})
+	case EXPORT_JSON:
+		err = json.NewEncoder(w).Encode(DocumentExport{
+			ID:          document.ID,
+			Name:        document.Name,
+			Description: document.Description,
+			Icon:        document.Icon,
+			Content:     render.JSON(content),
+		})
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("error writing export")
+		return
+	}
+
+	log.Info().Msg("exported document")
+}
+
+// exportFilename removes characters that are not allowed in file names on
+// common platforms, mime encodes names that are not ascii
+func exportFilename(name string, format string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	name = strings.Trim(name, " .")
+	if name == "" {
+		name = "document"
+	}
+	return fmt.Sprintf("%s.%s", name, format)
+}
@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/markdown"
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+// The xml fragment the editor binds to, imported content is written there
+const IMPORT_ROOT = "default"
+
+// Parsers for the file extensions that can be imported
+var importParsers = map[string]func(string) []yjs.XmlNode{
+	"md":       markdown.Parse,
+	"markdown": markdown.Parse,
+	"txt":      markdown.ParseText,
+}
+
+// importDocument creates a document from an uploaded markdown or text file,
+// the content is stored as the first update of the document so the
+// websocket server serves it to the first client that connects
+func (env *Env) importDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("user_id", userID.String()).
+		Logger()
+
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_UPLOAD_SIZE)
+	if err := r.ParseMultipartForm(MAX_UPLOAD_SIZE); err != nil {
+		httperrors.Write(w, "The uploaded file is too big. Please choose a file that's less than 10MB in size", http.StatusBadRequest)
+		log.Error().Err(err).Msg("error parsing form")
+		return
+	}
+
+	file, fh, err := r.FormFile("file")
+	if err != nil {
+		httperrors.Write(w, "Please provide a file to import", http.StatusBadRequest)
+		log.Error().Err(err).Msg("error reading input file")
+		return
+	}
+	defer file.Close()
+
+	fileExtension := strings.ToLower(strings.TrimPrefix(filepath.Ext(fh.Filename), "."))
+	parse, ok := importParsers[fileExtension]
+	if !ok {
+		httperrors.Write(w, "Please provide a md, markdown or txt file", http.StatusBadRequest)
+		log.Error().Str("file_type", fileExtension).Msg("invalid file type")
+		return
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		name = strings.TrimSpace(strings.TrimSuffix(filepath.Base(fh.Filename), filepath.Ext(fh.Filename)))
+	}
+	err = validateDocumentName(name)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid document name")
+		return
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error getting file bytes")
+		return
+	}
+	if !utf8.Valid(content) {
+		httperrors.Write(w, "The file is not valid UTF-8 text", http.StatusBadRequest)
+		log.Error().Msg("imported file is not utf-8")
+		return
+	}
+
+	nodes := parse(strings.TrimPrefix(string(content), "\ufeff"))
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	createdDocument, err := insertDocument(ctx, q, name, userID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to create document")
+		return
+	}
+	*log = log.With().
+		Str("document_id", createdDocument.ID.String()).
+		Logger()
+
+	if len(nodes) > 0 {
+		client, err := randomClientID()
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("failed to generate client id")
+			return
+		}
+
+		update := yjs.EncodeXmlFragment(client, IMPORT_ROOT, nodes)
+		_, err = appendDocumentUpdate(ctx, q, createdDocument.ID, userID, update)
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("failed to write imported content")
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	response, err := json.Marshal(createdDocumentResponse(createdDocument))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Str("file_type", fileExtension).Int("blocks", len(nodes)).Msg("imported document")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// randomClientID picks a client id like Yjs does, a random uint32
+func randomClientID() (uint64, error) {
+	bytes := make([]byte, 4)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(binary.LittleEndian.Uint32(bytes)), nil
+}
@@ -0,0 +1,487 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rejdeboer/multiplayer-server/internal/audit"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+const INVITE_TOKEN_BYTES = 24
+
+// InviteCreate describes a new invite link, leaving expiresAt or maxUses
+// empty creates a link that never expires or can be used any number of times
+type InviteCreate struct {
+	Role      string     `json:"role"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	MaxUses   *int32     `json:"maxUses"`
+}
+
+type InviteResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Token     string     `json:"token"`
+	Role      string     `json:"role"`
+	CreatedBy uuid.UUID  `json:"createdBy"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	MaxUses   *int32     `json:"maxUses"`
+	Uses      int32      `json:"uses"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+var (
+	errInviteNotFound = errors.New("invite not found")
+	errInviteExpired  = errors.New("invite has expired or has no uses left")
+)
+
+func (env *Env) createInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	var payload InviteCreate
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		httperrors.Write(w, "Invalid invite body", http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid body for create invite")
+		return
+	}
+
+	err = validateInviteCreate(&payload, time.Now())
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid invite")
+		return
+	}
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	token, err := generateInviteToken()
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to generate invite token")
+		return
+	}
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not create invites")
+		return
+	}
+
+	expiresAt := pgtype.Timestamptz{}
+	if payload.ExpiresAt != nil {
+		expiresAt = pgtype.Timestamptz{Time: *payload.ExpiresAt, Valid: true}
+	}
+
+	invite, err := q.CreateDocumentInvite(ctx, db.CreateDocumentInviteParams{
+		DocumentID: docID,
+		Token:      token,
+		Role:       payload.Role,
+		CreatedBy:  userID,
+		ExpiresAt:  expiresAt,
+		MaxUses:    payload.MaxUses,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error creating invite")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.INVITE_CREATED,
+		Details:    map[string]any{"inviteId": invite.ID, "role": invite.Role},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	response, err := json.Marshal(inviteResponse(invite))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Str("invite_id", invite.ID.String()).Msg("created invite")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (env *Env) listInvites(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	q := db.New(env.Pool)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not list invites")
+		return
+	}
+
+	invites, err := q.ListDocumentInvites(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to fetch invites from db")
+		return
+	}
+
+	result := []InviteResponse{}
+	for _, invite := range invites {
+		result = append(result, inviteResponse(invite))
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Int("invites", len(result)).Msg("sending invites")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (env *Env) revokeInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	inviteID, err := uuid.Parse(r.PathValue("invite_id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid invite id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid invite id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Str("invite_id", inviteID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not revoke invites")
+		return
+	}
+
+	revoked, err := q.RevokeDocumentInvite(ctx, db.RevokeDocumentInviteParams{
+		ID:         inviteID,
+		DocumentID: docID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error revoking invite")
+		return
+	}
+	if revoked == 0 {
+		httperrors.Write(w, "Invite not found", http.StatusNotFound)
+		log.Error().Msg("invite not found")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.INVITE_REVOKED,
+		Details:    map[string]any{"inviteId": inviteID},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	log.Info().Msg("revoked invite")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// acceptInvite adds the caller to the document of the invite with the role of
+// the invite. Users that already have access keep their role and do not use
+// up the invite.
+func (env *Env) acceptInvite(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	token := r.PathValue("token")
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	invite, err := getUsableInvite(ctx, q, token, time.Now())
+	if errors.Is(err, errInviteNotFound) {
+		httperrors.Write(w, "Invite not found", http.StatusNotFound)
+		log.Error().Err(err).Msg("invite not found")
+		return
+	} else if errors.Is(err, errInviteExpired) {
+		httperrors.Write(w, "Invite has expired", http.StatusGone)
+		log.Error().Err(err).Msg("invite can not be used")
+		return
+	} else if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching invite")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", invite.DocumentID.String()).
+		Str("invite_id", invite.ID.String()).
+		Logger()
+
+	added, err := q.CreateDocumentContributorIfNotExists(ctx, db.CreateDocumentContributorIfNotExistsParams{
+		DocumentID: invite.DocumentID,
+		UserID:     userID,
+		Role:       invite.Role,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error adding contributor")
+		return
+	}
+
+	if added > 0 {
+		err = q.IncrementDocumentInviteUses(ctx, invite.ID)
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("error updating invite uses")
+			return
+		}
+
+		err = audit.Record(ctx, q, audit.Entry{
+			DocumentID: invite.DocumentID,
+			ActorID:    userID,
+			Action:     audit.INVITE_ACCEPTED,
+			Details:    map[string]any{"inviteId": invite.ID, "role": invite.Role},
+		})
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("failed to write audit log entry")
+			return
+		}
+
+		err = outbox.Enqueue(ctx, q, events.ContributorAdded{
+			DocumentID: invite.DocumentID,
+			UserID:     userID,
+			AddedBy:    invite.CreatedBy,
+		})
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("failed to write contributor event to outbox")
+			return
+		}
+	}
+
+	document, err := getDocumentAsUser(ctx, invite.DocumentID, userID, q)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching document")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	response, err := json.Marshal(document)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Bool("added", added > 0).Msg("accepted invite")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// getUsableInvite locks the invite so concurrent accepts can not exceed the
+// maximum number of uses
+func getUsableInvite(ctx context.Context, q *db.Queries, token string, now time.Time) (db.DocumentInvite, error) {
+	invite, err := q.GetDocumentInviteByToken(ctx, token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.DocumentInvite{}, errInviteNotFound
+	} else if err != nil {
+		return db.DocumentInvite{}, err
+	}
+
+	if invite.RevokedAt.Valid {
+		return db.DocumentInvite{}, errInviteNotFound
+	}
+
+	document, err := q.GetDocumnetByID(ctx, invite.DocumentID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && document.DeletedAt.Valid) {
+		return db.DocumentInvite{}, errInviteNotFound
+	} else if err != nil {
+		return db.DocumentInvite{}, err
+	}
+
+	if invite.ExpiresAt.Valid && !invite.ExpiresAt.Time.After(now) {
+		return db.DocumentInvite{}, errInviteExpired
+	}
+	if invite.MaxUses != nil && invite.Uses >= *invite.MaxUses {
+		return db.DocumentInvite{}, errInviteExpired
+	}
+
+	return invite, nil
+}
+
+func validateInviteCreate(invite *InviteCreate, now time.Time) error {
+	if invite.Role == "" {
+		invite.Role = ROLE_EDITOR
+	}
+	if !isValidRole(invite.Role) || invite.Role == ROLE_OWNER {
+		return errors.New("role must be one of: editor, commenter, viewer")
+	}
+
+	if invite.ExpiresAt != nil && !invite.ExpiresAt.After(now) {
+		return errors.New("expiresAt must be in the future")
+	}
+
+	if invite.MaxUses != nil && *invite.MaxUses < 1 {
+		return errors.New("maxUses must be at least 1")
+	}
+
+	return nil
+}
+
+func generateInviteToken() (string, error) {
+	bytes := make([]byte, INVITE_TOKEN_BYTES)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func inviteResponse(invite db.DocumentInvite) InviteResponse {
+	return InviteResponse{
+		ID:        invite.ID,
+		Token:     invite.Token,
+		Role:      invite.Role,
+		CreatedBy: invite.CreatedBy,
+		ExpiresAt: nullableTime(invite.ExpiresAt),
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		CreatedAt: invite.CreatedAt.Time,
+	}
+}
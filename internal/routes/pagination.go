@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DEFAULT_PAGE_SIZE = 50
+	MAX_PAGE_SIZE     = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Page is the response shape shared by all paginated list endpoints
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Cursor points at the last item of a page. It is handed to clients as an
+// opaque base64 string, the sort is included so a cursor can not be replayed
+// against a differently ordered listing.
+type Cursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	bytes, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func decodeCursor(raw string, sort string) (*Cursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	bytes, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var cursor Cursor
+	err = json.Unmarshal(bytes, &cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	if cursor.Sort != sort {
+		return nil, errors.New("cursor does not match requested sort")
+	}
+
+	return &cursor, nil
+}
+
+func parsePageSize(query url.Values) (int, error) {
+	raw := query.Get("limit")
+	if raw == "" {
+		return DEFAULT_PAGE_SIZE, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > MAX_PAGE_SIZE {
+		return 0, fmt.Errorf("limit must be between 1 and %d", MAX_PAGE_SIZE)
+	}
+
+	return limit, nil
+}
+
+// writePageLink sets a Link header pointing at the next page, keeping all
+// other query parameters of the current request intact
+func writePageLink(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+
+	query := r.URL.Query()
+	query.Set("cursor", nextCursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.String()))
+}
@@ -0,0 +1,401 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rejdeboer/multiplayer-server/internal/audit"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/render"
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	MAX_SLUG_NAME_LENGTH = 48
+
+	PUBLIC_CACHE_CONTROL = "public, max-age=60"
+)
+
+type PublicationResponse struct {
+	Slug        string    `json:"slug"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+type PublicDocumentResponse struct {
+	ID          uuid.UUID      `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Icon        *string        `json:"icon"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	PublishedAt time.Time      `json:"publishedAt"`
+	Content     map[string]any `json:"content"`
+}
+
+// documentPage wraps a rendered document, the content is written between the
+// header and footer so it can be streamed
+var documentPage = template.Must(template.New("document").Parse(`{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+</head>
+<body>
+<article>
+<h1>{{if .Icon}}{{.Icon}} {{end}}{{.Name}}</h1>
+{{end}}{{define "footer"}}
+</article>
+</body>
+</html>
+{{end}}`))
+
+// publishDocument makes the document readable without authentication,
+// publishing again after unpublishing keeps the slug so old links work again
+func (env *Env) publishDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	document, _, err := authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not publish document")
+		return
+	}
+
+	slug, err := generateSlug(document.Name)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to generate slug")
+		return
+	}
+
+	publication, err := q.PublishDocument(ctx, db.PublishDocumentParams{
+		DocumentID:  docID,
+		Slug:        slug,
+		PublishedBy: pgtype.UUID{Bytes: userID, Valid: true},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error publishing document")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.DOCUMENT_PUBLISHED,
+		Details:    map[string]any{"slug": publication.Slug},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	response, err := json.Marshal(PublicationResponse{
+		Slug:        publication.Slug,
+		PublishedAt: publication.PublishedAt.Time,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Str("slug", publication.Slug).Msg("published document")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (env *Env) unpublishDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not unpublish document")
+		return
+	}
+
+	unpublished, err := q.UnpublishDocument(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error unpublishing document")
+		return
+	}
+	if unpublished == 0 {
+		httperrors.Write(w, "Document is not published", http.StatusNotFound)
+		log.Error().Msg("document is not published")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.DOCUMENT_UNPUBLISHED,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	log.Info().Msg("unpublished document")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// getPublicDocument serves a published document without authentication, as
+// json when asked for and as a read-only html page otherwise
+func (env *Env) getPublicDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	slug := r.PathValue("slug")
+	*log = log.With().Str("slug", slug).Logger()
+
+	q := db.New(env.Pool)
+
+	document, err := q.GetPublishedDocumentBySlug(ctx, slug)
+	if errors.Is(err, pgx.ErrNoRows) {
+		httperrors.Write(w, "Document not found", http.StatusNotFound)
+		log.Error().Err(err).Msg("no published document with slug")
+		return
+	} else if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching published document")
+		return
+	}
+
+	content, err := env.loadPublicContent(ctx, q, document.ID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to load document content")
+		return
+	}
+
+	w.Header().Set("Cache-Control", PUBLIC_CACHE_CONTROL)
+	// The same url serves html or json, shared caches must key on Accept
+	w.Header().Set("Vary", "Accept")
+
+	if wantsJSON(r) {
+		response, err := json.Marshal(PublicDocumentResponse{
+			ID:          document.ID,
+			Name:        document.Name,
+			Description: document.Description,
+			Icon:        document.Icon,
+			UpdatedAt:   document.UpdatedAt.Time,
+			PublishedAt: document.PublishedAt.Time,
+			Content:     content.json,
+		})
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("error marshalling response")
+			return
+		}
+
+		log.Info().Msg("sending public document")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(response)
+		return
+	}
+
+	log.Info().Msg("rendering public document")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	err = writeDocumentPage(w, document.Name, document.Icon, func(w io.Writer) error {
+		_, err := io.WriteString(w, content.html)
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("error rendering public document")
+	}
+}
+
+func writeDocumentPage(w io.Writer, name string, icon *string, content func(io.Writer) error) error {
+	data := map[string]any{
+		"Name": name,
+		"Icon": icon,
+	}
+	err := documentPage.ExecuteTemplate(w, "header", data)
+	if err != nil {
+		return err
+	}
+	err = content(w)
+	if err != nil {
+		return err
+	}
+	return documentPage.ExecuteTemplate(w, "footer", data)
+}
+
+// loadPublicContent renders the published document at its latest clock, the
+// rendered content is cached until a new update is stored
+func (env *Env) loadPublicContent(ctx context.Context, q *db.Queries, docID uuid.UUID) (publicContent, error) {
+	clock, err := q.GetLatestDocumentClock(ctx, docID)
+	if err != nil {
+		return publicContent{}, err
+	}
+
+	if content, ok := env.publicContent.get(docID, clock); ok {
+		return content, nil
+	}
+
+	// Updates stored after reading the clock may be rendered too, the entry
+	// is replaced on the next request because the clock no longer matches
+	doc, err := loadDocumentContent(ctx, q, docID)
+	if err != nil {
+		return publicContent{}, err
+	}
+
+	var html strings.Builder
+	err = render.HTML(&html, doc)
+	if err != nil {
+		return publicContent{}, err
+	}
+
+	content := publicContent{
+		clock: clock,
+		html:  html.String(),
+		json:  render.JSON(doc),
+	}
+	env.publicContent.put(docID, content)
+	return content, nil
+}
+
+// loadDocumentContent replays the persisted updates of the document, this is
+// the state the websocket server hands to clients when they connect
+func loadDocumentContent(ctx context.Context, q *db.Queries, docID uuid.UUID) (*yjs.Doc, error) {
+	updates, err := q.ListDocumentUpdateValues(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	return yjs.MergeUpdates(updates)
+}
+
+func wantsJSON(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "json"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// generateSlug builds a readable slug from the document name, the random
+// suffix keeps slugs unique and hard to guess
+func generateSlug(name string) (string, error) {
+	bytes := make([]byte, 5)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	suffix := hex.EncodeToString(bytes)
+
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if builder.Len() >= MAX_SLUG_NAME_LENGTH {
+			break
+		}
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			builder.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	if builder.Len() == 0 {
+		return suffix, nil
+	}
+	return builder.String() + "-" + suffix, nil
+}
@@ -0,0 +1,57 @@
+package routes
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const PUBLIC_CONTENT_CACHE_SIZE = 256
+
+// publicContent is a published document rendered at a clock
+type publicContent struct {
+	clock int32
+	html  string
+	json  map[string]any
+}
+
+// publicContentCache keeps the rendered content of published documents so
+// anonymous readers don't replay the whole update history on every request.
+// An entry is only served while its clock is the latest clock of the document.
+type publicContentCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[uuid.UUID]publicContent
+}
+
+func newPublicContentCache(size int) *publicContentCache {
+	return &publicContentCache{
+		size:    size,
+		entries: make(map[uuid.UUID]publicContent, size),
+	}
+}
+
+func (c *publicContentCache) get(docID uuid.UUID, clock int32) (publicContent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	content, ok := c.entries[docID]
+	if !ok || content.clock != clock {
+		return publicContent{}, false
+	}
+	return content, true
+}
+
+func (c *publicContentCache) put(docID uuid.UUID, content publicContent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[docID]; !ok && len(c.entries) >= c.size {
+		// Evict an arbitrary entry, a miss only costs a replay
+		for id := range c.entries {
+			delete(c.entries, id)
+			break
+		}
+	}
+	c.entries[docID] = content
+}
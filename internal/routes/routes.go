@@ -2,26 +2,32 @@ package routes
 
 import (
 	"net/http"
+	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rejdeboer/multiplayer-server/internal/configuration"
+	"github.com/rejdeboer/multiplayer-server/internal/mailer"
 	"github.com/rejdeboer/multiplayer-server/internal/middleware"
+	"github.com/rejdeboer/multiplayer-server/internal/storage"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog"
-	"github.com/segmentio/kafka-go"
 )
 
 type Env struct {
-	Pool         *pgxpool.Pool
-	Producer     *kafka.Writer
-	Blob         *azblob.Client
-	SearchClient *elasticsearch.TypedClient
+	Pool           *pgxpool.Pool
+	Storage        storage.ObjectStore
+	SearchClient   *elasticsearch.TypedClient
+	TrashRetention time.Duration
+	Mailer         mailer.Mailer
+	SignupUrl      string
+
+	publicContent *publicContentCache
 }
 
 func CreateHandler(settings configuration.Settings, env *Env) http.Handler {
 	authorized := middleware.WithAuth(settings.Application.SigningKey)
+	env.publicContent = newPublicContentCache(PUBLIC_CONTENT_CACHE_SIZE)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -31,23 +37,61 @@ func CreateHandler(settings configuration.Settings, env *Env) http.Handler {
 	})
 
 	mux.HandleFunc("GET /document", authorized(env.listDocuments))
+	mux.HandleFunc("GET /document/trash", authorized(env.listTrash))
 	mux.HandleFunc("GET /document/{id}", authorized(env.getDocument))
+	mux.HandleFunc("PATCH /document/{id}", authorized(env.updateDocument))
 	mux.HandleFunc("DELETE /document/{id}", authorized(env.deleteDocument))
 	mux.HandleFunc("POST /document", authorized(env.createDocument))
+	mux.HandleFunc("POST /document/import", authorized(env.importDocument))
+	mux.HandleFunc("POST /document/{id}/restore", authorized(env.restoreDocument))
+	mux.HandleFunc("GET /document/{id}/export", authorized(env.exportDocument))
+	mux.HandleFunc("GET /document/{id}/diff", authorized(env.diffVersions))
+	mux.HandleFunc("GET /document/{id}/versions", authorized(env.listVersions))
+	mux.HandleFunc("GET /document/{id}/versions/{clock}", authorized(env.getVersion))
+	mux.HandleFunc("POST /document/{id}/versions/{clock}/restore", authorized(env.restoreVersion))
+	mux.HandleFunc("POST /document/{id}/snapshots", authorized(env.createSnapshot))
+
+	mux.HandleFunc("POST /document/{id}/leave", authorized(env.leaveDocument))
+	mux.HandleFunc("POST /document/{id}/transfer", authorized(env.requestDocumentTransfer))
+	mux.HandleFunc("POST /document/{id}/transfer/accept", authorized(env.acceptDocumentTransfer))
+	mux.HandleFunc("DELETE /document/{id}/transfer", authorized(env.cancelDocumentTransfer))
+
+	mux.HandleFunc("GET /document/{id}/invite", authorized(env.listInvites))
+	mux.HandleFunc("POST /document/{id}/invite", authorized(env.createInvite))
+	mux.HandleFunc("DELETE /document/{id}/invite/{invite_id}", authorized(env.revokeInvite))
+	mux.HandleFunc("GET /document/{id}/invitation", authorized(env.listEmailInvitations))
+	mux.HandleFunc("POST /document/{id}/invitation", authorized(env.createEmailInvitation))
+	mux.HandleFunc("DELETE /document/{id}/invitation/{invitation_id}", authorized(env.cancelEmailInvitation))
+	mux.HandleFunc("GET /document/{id}/access-request", authorized(env.listAccessRequests))
+	mux.HandleFunc("POST /document/{id}/access-request", authorized(env.createAccessRequest))
+	mux.HandleFunc("POST /document/{id}/access-request/{request_id}/approve", authorized(env.approveAccessRequest))
+	mux.HandleFunc("POST /document/{id}/access-request/{request_id}/deny", authorized(env.denyAccessRequest))
+	mux.HandleFunc("POST /invite/{token}/accept", authorized(env.acceptInvite))
+
+	mux.HandleFunc("POST /document/{id}/publish", authorized(env.publishDocument))
+	mux.HandleFunc("DELETE /document/{id}/publish", authorized(env.unpublishDocument))
+	mux.HandleFunc("GET /public/{slug}", env.getPublicDocument)
 
 	mux.HandleFunc("POST /document/{document_id}/contributor", authorized(env.addDocumentContributor))
+	mux.HandleFunc("DELETE /document/{document_id}/contributor/{user_id}", authorized(env.removeDocumentContributor))
 
 	mux.HandleFunc("POST /user", env.createUser)
 	mux.HandleFunc("POST /user/image", authorized(env.updateUserImage))
 	mux.HandleFunc("GET /user/search", env.searchUsers)
 	mux.HandleFunc("POST /token", env.getToken(settings.Application.SigningKey, settings.Application.TokenExpirationSeconds))
 
+	if localStore, ok := env.Storage.(*storage.LocalStore); ok {
+		mountPath := localStore.MountPath()
+		mux.Handle("GET "+mountPath+"/", http.StripPrefix(mountPath, localStore.Handler()))
+	}
+
 	handler := middleware.WithLogging(mux)
 
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000"},
 		AllowedHeaders:   []string{"*"},
-		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions},
+		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete, http.MethodOptions},
 		AllowCredentials: true,
 	})
 
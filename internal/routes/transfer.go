@@ -0,0 +1,368 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rejdeboer/multiplayer-server/internal/audit"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+type DocumentTransferCreate struct {
+	UserID uuid.UUID `json:"userId"`
+}
+
+type DocumentTransferResponse struct {
+	DocumentID uuid.UUID `json:"documentId"`
+	FromUserID uuid.UUID `json:"fromUserId"`
+	ToUserID   uuid.UUID `json:"toUserId"`
+}
+
+// requestDocumentTransfer lets the owner offer ownership to an existing
+// contributor. Ownership only changes once the recipient accepts, a new
+// request replaces the pending one.
+func (env *Env) requestDocumentTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	var payload DocumentTransferCreate
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil || payload.UserID == uuid.Nil {
+		httperrors.Write(w, "Please provide the userId of the new owner", http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid body for transfer request")
+		return
+	}
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Str("recipient_id", payload.UserID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	document, _, err := authorizeDocument(ctx, q, docID, userID, ROLE_OWNER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not transfer document")
+		return
+	}
+
+	if payload.UserID == document.OwnerID {
+		httperrors.Write(w, "The user already owns this document", http.StatusBadRequest)
+		log.Error().Msg("owner tried to transfer document to themselves")
+		return
+	}
+
+	if document.RoleOf(payload.UserID) == "" {
+		httperrors.Write(w, "Ownership can only be transferred to a contributor", http.StatusBadRequest)
+		log.Error().Msg("recipient is not a contributor of document")
+		return
+	}
+
+	err = q.UpsertDocumentTransfer(ctx, db.UpsertDocumentTransferParams{
+		DocumentID: docID,
+		FromUserID: userID,
+		ToUserID:   payload.UserID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error storing transfer request")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.OWNERSHIP_TRANSFER_REQUESTED,
+		Details:    map[string]uuid.UUID{"toUserId": payload.UserID},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	response, err := json.Marshal(DocumentTransferResponse{
+		DocumentID: docID,
+		FromUserID: userID,
+		ToUserID:   payload.UserID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Msg("requested ownership transfer")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(response)
+}
+
+// acceptDocumentTransfer makes the recipient of a pending transfer the owner,
+// the previous owner keeps access as an editor
+func (env *Env) acceptDocumentTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	document, _, err := authorizeDocument(ctx, q, docID, userID, ROLE_VIEWER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("error fetching document")
+		return
+	}
+
+	transfer, err := q.GetDocumentTransfer(ctx, docID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && transfer.ToUserID != userID) {
+		httperrors.Write(w, "Transfer not found", http.StatusNotFound)
+		log.Error().Err(err).Msg("no pending transfer for user")
+		return
+	} else if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching transfer")
+		return
+	}
+
+	// The owner may have changed since the transfer was requested
+	if transfer.FromUserID != document.OwnerID {
+		httperrors.Write(w, "Transfer is no longer valid", http.StatusConflict)
+		log.Error().Msg("transfer was requested by a previous owner")
+		return
+	}
+
+	err = q.UpdateDocumentOwner(ctx, db.UpdateDocumentOwnerParams{
+		ID:      docID,
+		OwnerID: userID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error updating document owner")
+		return
+	}
+
+	err = q.UpdateDocumentContributorRole(ctx, db.UpdateDocumentContributorRoleParams{
+		DocumentID: docID,
+		UserID:     userID,
+		Role:       ROLE_OWNER,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error updating role of new owner")
+		return
+	}
+
+	err = q.UpdateDocumentContributorRole(ctx, db.UpdateDocumentContributorRoleParams{
+		DocumentID: docID,
+		UserID:     transfer.FromUserID,
+		Role:       ROLE_EDITOR,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error updating role of previous owner")
+		return
+	}
+
+	err = q.DeleteDocumentTransfer(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error deleting transfer")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.OWNERSHIP_TRANSFER_ACCEPTED,
+		Details:    map[string]uuid.UUID{"fromUserId": transfer.FromUserID},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = outbox.Enqueue(ctx, q, events.DocumentOwnershipTransferred{
+		ID:              docID,
+		PreviousOwnerID: transfer.FromUserID,
+		OwnerID:         userID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write document event to outbox")
+		return
+	}
+
+	transferred, err := getDocumentAsUser(ctx, docID, userID, q)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching transferred document")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	response, err := json.Marshal(transferred)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Str("previous_owner_id", transfer.FromUserID.String()).Msg("accepted ownership transfer")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// cancelDocumentTransfer lets the owner withdraw a pending transfer or the
+// recipient decline it
+func (env *Env) cancelDocumentTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_VIEWER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("error fetching document")
+		return
+	}
+
+	transfer, err := q.GetDocumentTransfer(ctx, docID)
+	if errors.Is(err, pgx.ErrNoRows) ||
+		(err == nil && transfer.FromUserID != userID && transfer.ToUserID != userID) {
+		httperrors.Write(w, "Transfer not found", http.StatusNotFound)
+		log.Error().Err(err).Msg("no pending transfer for user")
+		return
+	} else if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching transfer")
+		return
+	}
+
+	err = q.DeleteDocumentTransfer(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error deleting transfer")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.OWNERSHIP_TRANSFER_CANCELLED,
+		Details:    map[string]uuid.UUID{"toUserId": transfer.ToUserID},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	log.Info().Msg("cancelled ownership transfer")
+	w.WriteHeader(http.StatusAccepted)
+}
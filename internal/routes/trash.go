@@ -0,0 +1,203 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+const SORT_DELETED = "deleted"
+
+type TrashItem struct {
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	Icon      *string    `json:"icon"`
+	DeletedAt time.Time  `json:"deletedAt"`
+	DeletedBy *uuid.UUID `json:"deletedBy"`
+	PurgeAt   time.Time  `json:"purgeAt"`
+}
+
+func (env *Env) listTrash(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	q := db.New(env.Pool)
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit, err := parsePageSize(query)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid page size")
+		return
+	}
+
+	cursor, err := decodeCursor(query.Get("cursor"), SORT_DELETED)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid trash cursor")
+		return
+	}
+
+	afterDeletedAt := pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}
+	afterID := uuid.Max
+	if cursor != nil {
+		afterDeletedAt, err = parseCursorTime(cursor.Value)
+		if err != nil {
+			httperrors.Write(w, err.Error(), http.StatusBadRequest)
+			log.Error().Err(err).Msg("invalid trash cursor")
+			return
+		}
+		afterID = cursor.ID
+	}
+
+	rows, err := q.ListDeletedDocuments(ctx, db.ListDeletedDocumentsParams{
+		OwnerID:        userID,
+		AfterDeletedAt: afterDeletedAt,
+		AfterID:        afterID,
+		PageSize:       int32(limit + 1),
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to fetch trash from db")
+		return
+	}
+
+	page := Page[TrashItem]{Items: []TrashItem{}}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = Cursor{
+			Sort:  SORT_DELETED,
+			Value: formatCursorTime(last.DeletedAt),
+			ID:    last.ID,
+		}.Encode()
+	}
+
+	for _, document := range rows {
+		page.Items = append(page.Items, TrashItem{
+			ID:        document.ID,
+			Name:      document.Name,
+			Icon:      document.Icon,
+			DeletedAt: document.DeletedAt.Time,
+			DeletedBy: nullableUUID(document.DeletedBy),
+			PurgeAt:   document.DeletedAt.Time.Add(env.TrashRetention),
+		})
+	}
+
+	response, err := json.Marshal(page)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Int("items", len(page.Items)).Msg("sending trash")
+	writePageLink(w, r, page.NextCursor)
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (env *Env) restoreDocument(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	document, err := q.GetDocumnetByID(ctx, docID)
+	if err != nil {
+		httperrors.Write(w, "Document not found", http.StatusNotFound)
+		log.Error().Err(err).Msg("document not found")
+		return
+	}
+
+	if document.OwnerID != userID || !document.DeletedAt.Valid {
+		httperrors.Write(w, "Document not found", http.StatusNotFound)
+		log.Error().Msg("document is not in the trash of the user")
+		return
+	}
+
+	err = q.RestoreDocument(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to restore document")
+		return
+	}
+
+	err = outbox.Enqueue(ctx, q, events.DocumentRestored{
+		ID:         docID,
+		RestoredBy: userID,
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write document event to outbox")
+		return
+	}
+
+	restored, err := getDocumentAsUser(ctx, docID, userID, q)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching restored document")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+	log.Info().Msg("restored document from trash")
+
+	response, err := json.Marshal(restored)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
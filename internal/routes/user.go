@@ -1,38 +1,41 @@
 package routes
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/mail"
 	"slices"
 	"strings"
 	"unicode"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
 	"github.com/google/uuid"
 	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/indexing"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
 	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
 	"github.com/rs/zerolog"
-	"github.com/segmentio/kafka-go"
 	"golang.org/x/crypto/bcrypt"
 )
 
 const (
 	MAX_UPLOAD_SIZE       = 10 * 1024 * 1024 // 10MB
 	USER_IMAGES_CONTAINER = "user-images"
-	USERS_TOPIC           = "users"
 )
 
 type UserCreate struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// InvitationToken is the token from an email invitation link, it claims
+	// the pending invitations for the email
+	InvitationToken string `json:"invitationToken"`
 }
 
 type UserResponse struct {
@@ -75,7 +78,15 @@ func (env *Env) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	q := db.New(env.Pool)
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
 
 	createdUser, err := q.CreateUser(ctx, db.CreateUserParams{
 		Email:    user.Email,
@@ -98,7 +109,6 @@ func (env *Env) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	userID := createdUser.ID.String()
-	log.Info().Str("user_id", userID).Msg("created new user")
 
 	body, err := json.Marshal(UserResponse{
 		ID:       createdUser.ID,
@@ -111,12 +121,34 @@ func (env *Env) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	topic := USERS_TOPIC
-	env.Producer.WriteMessages(context.Background(), kafka.Message{
-		Topic: topic,
-		Key:   []byte(createdUser.ID.String()),
-		Value: body,
+	err = outbox.Enqueue(ctx, q, events.UserCreated{
+		ID:       createdUser.ID,
+		Email:    createdUser.Email,
+		Username: createdUser.Username,
 	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write user event to outbox")
+		return
+	}
+
+	claimed := 0
+	if user.InvitationToken != "" {
+		claimed, err = claimEmailInvitations(ctx, q, createdUser, user.InvitationToken)
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("failed to claim email invitations")
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+	log.Info().Str("user_id", userID).Int("claimed_invitations", claimed).Msg("created new user")
 
 	w.WriteHeader(http.StatusOK)
 	w.Write(body)
@@ -129,7 +161,7 @@ func (env *Env) searchUsers(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("query")
 
 	result, err := env.SearchClient.Search().
-		Index("users").
+		Index(indexing.USERS_INDEX).
 		Request(&search.Request{
 			Query: &types.Query{MultiMatch: &types.MultiMatchQuery{
 				Query: query,
@@ -210,20 +242,21 @@ func (env *Env) updateUserImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = env.Blob.UploadBuffer(
+	imageKey := userID.String() + "." + fileExtension
+	err = env.Storage.Put(
 		ctx,
 		USER_IMAGES_CONTAINER,
-		userID.String()+"."+fileExtension,
+		imageKey,
 		imageBytes,
-		&blockblob.UploadFileOptions{},
+		mime.TypeByExtension("."+fileExtension),
 	)
 	if err != nil {
 		httperrors.InternalServerError(w)
-		log.Error().Err(err).Msg("error storing user image in blob storage")
+		log.Error().Err(err).Msg("error storing user image in object storage")
 		return
 	}
 
-	imageUrl := env.Blob.URL() + "/" + userID.String()
+	imageUrl := env.Storage.URL(USER_IMAGES_CONTAINER, imageKey)
 	q := db.New(env.Pool)
 	err = q.UpdateUserImage(ctx, db.UpdateUserImageParams{
 		ID:       userID,
@@ -0,0 +1,607 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rejdeboer/multiplayer-server/internal/audit"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/render"
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Updates within this interval of the first update of a version are
+	// grouped into that version
+	VERSION_INTERVAL = 10 * time.Minute
+
+	MAX_SNAPSHOT_NAME_LENGTH = 100
+)
+
+var errInvalidClock = errors.New("clock must be a non-negative integer")
+
+type SnapshotCreate struct {
+	Name  string `json:"name"`
+	Clock *int32 `json:"clock"`
+}
+
+type SnapshotResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	Clock     int32      `json:"clock"`
+	CreatedBy *uuid.UUID `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// VersionResponse groups the updates from FromClock up to and including
+// Clock, the content of the version is the document as of Clock
+type VersionResponse struct {
+	Clock     int32              `json:"clock"`
+	FromClock int32              `json:"fromClock"`
+	StartedAt time.Time          `json:"startedAt"`
+	EndedAt   time.Time          `json:"endedAt"`
+	Updates   int                `json:"updates"`
+	Authors   []uuid.UUID        `json:"authors"`
+	Snapshots []SnapshotResponse `json:"snapshots"`
+}
+
+type VersionRestoreResponse struct {
+	RestoredClock int32 `json:"restoredClock"`
+	Clock         int32 `json:"clock"`
+}
+
+type VersionContentResponse struct {
+	Clock     int32          `json:"clock"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Content   map[string]any `json:"content"`
+}
+
+// listVersions returns the history of the document newest first, a named
+// snapshot always ends the version it points into
+func (env *Env) listVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	q := db.New(env.Pool)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_VIEWER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not view document history")
+		return
+	}
+
+	updates, err := q.ListDocumentUpdateHistory(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to fetch document updates from db")
+		return
+	}
+
+	snapshots, err := q.ListDocumentSnapshots(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to fetch document snapshots from db")
+		return
+	}
+
+	result := groupVersions(updates, snapshots)
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Int("versions", len(result)).Msg("sending document versions")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// getVersion returns the content of the document as of the given clock
+func (env *Env) getVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	q := db.New(env.Pool)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	clock, err := parseClock(r.PathValue("clock"))
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid clock")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Int32("clock", clock).
+		Logger()
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_VIEWER)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not view document history")
+		return
+	}
+
+	update, err := q.GetDocumentUpdateInfo(ctx, db.GetDocumentUpdateInfoParams{
+		DocumentID: docID,
+		Clock:      clock,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		httperrors.Write(w, "Version not found", http.StatusNotFound)
+		log.Error().Err(err).Msg("no update with clock")
+		return
+	} else if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching document update")
+		return
+	}
+
+	content, err := loadDocumentContentAt(ctx, q, docID, clock)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to load document content")
+		return
+	}
+
+	response, err := json.Marshal(VersionContentResponse{
+		Clock:     update.Clock,
+		CreatedAt: update.CreatedAt.Time,
+		Content:   render.JSON(content),
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Msg("sending document version")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// createSnapshot names a point in the history of the document, without a
+// clock the snapshot points at the latest update
+func (env *Env) createSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	var payload SnapshotCreate
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		httperrors.Write(w, "Invalid snapshot body", http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid body for create snapshot")
+		return
+	}
+
+	err = validateSnapshotCreate(&payload)
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("invalid snapshot")
+		return
+	}
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_EDITOR)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not create snapshot")
+		return
+	}
+
+	latestClock, err := q.GetLatestDocumentClock(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching latest clock")
+		return
+	}
+	if latestClock < 0 {
+		httperrors.Write(w, "Document has no changes to snapshot yet", http.StatusBadRequest)
+		log.Error().Msg("document has no updates")
+		return
+	}
+
+	clock := latestClock
+	if payload.Clock != nil {
+		clock = *payload.Clock
+	}
+	if clock > latestClock {
+		httperrors.Write(w, "Clock is ahead of the latest change of the document", http.StatusBadRequest)
+		log.Error().Int32("clock", clock).Msg("snapshot clock is ahead of document")
+		return
+	}
+
+	snapshot, err := q.CreateDocumentSnapshot(ctx, db.CreateDocumentSnapshotParams{
+		DocumentID: docID,
+		Clock:      clock,
+		Name:       payload.Name,
+		CreatedBy:  pgtype.UUID{Bytes: userID, Valid: true},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error creating snapshot")
+		return
+	}
+
+	err = audit.Record(ctx, q, audit.Entry{
+		DocumentID: docID,
+		ActorID:    userID,
+		Action:     audit.SNAPSHOT_CREATED,
+		Details:    map[string]any{"snapshotId": snapshot.ID, "name": snapshot.Name, "clock": snapshot.Clock},
+	})
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to write audit log entry")
+		return
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	response, err := json.Marshal(snapshotResponse(snapshot))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Str("snapshot_id", snapshot.ID.String()).Int32("clock", clock).Msg("created snapshot")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(response)
+}
+
+// restoreVersion brings the content back to how it was at the given clock.
+// History is not rewritten, the inverse of the changes made since is
+// appended as a new update that connected clients receive like any other.
+func (env *Env) restoreVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := zerolog.Ctx(ctx)
+
+	docID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		httperrors.Write(w, "Invalid document id, please use uuid format", http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid document id format")
+		return
+	}
+
+	clock, err := parseClock(r.PathValue("clock"))
+	if err != nil {
+		httperrors.Write(w, err.Error(), http.StatusBadRequest)
+		log.Error().Err(err).Msg("user used invalid clock")
+		return
+	}
+
+	userID, err := uuid.Parse(ctx.Value("user_id").(string))
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to parse uuid")
+		return
+	}
+
+	*log = log.With().
+		Str("document_id", docID.String()).
+		Str("user_id", userID.String()).
+		Int32("clock", clock).
+		Logger()
+
+	tx, err := env.Pool.Begin(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to begin transaction")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(env.Pool).WithTx(tx)
+
+	_, _, err = authorizeDocument(ctx, q, docID, userID, ROLE_EDITOR)
+	if err != nil {
+		writeAuthorizationError(w, err)
+		log.Error().Err(err).Msg("user can not restore document")
+		return
+	}
+
+	_, err = q.GetDocumentUpdateInfo(ctx, db.GetDocumentUpdateInfoParams{
+		DocumentID: docID,
+		Clock:      clock,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		httperrors.Write(w, "Version not found", http.StatusNotFound)
+		log.Error().Err(err).Msg("no update with clock")
+		return
+	} else if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error fetching document update")
+		return
+	}
+
+	// Lock before loading the content so no update is appended between
+	// computing the restore and appending it
+	_, err = q.GetDocumentForUpdate(ctx, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to lock document")
+		return
+	}
+
+	current, err := loadDocumentContent(ctx, q, docID)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to load document content")
+		return
+	}
+
+	target, err := loadDocumentContentAt(ctx, q, docID, clock)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to load document version")
+		return
+	}
+
+	client, err := randomClientID()
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to generate client id")
+		return
+	}
+
+	result := VersionRestoreResponse{RestoredClock: clock}
+	update := yjs.EncodeRestore(current, target, client)
+	if update == nil {
+		result.Clock, err = q.GetLatestDocumentClock(ctx, docID)
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("error fetching latest clock")
+			return
+		}
+		log.Info().Msg("document already has the content of the version")
+	} else {
+		result.Clock, err = appendDocumentUpdate(ctx, q, docID, userID, update)
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("failed to append restore update")
+			return
+		}
+
+		err = audit.Record(ctx, q, audit.Entry{
+			DocumentID: docID,
+			ActorID:    userID,
+			Action:     audit.VERSION_RESTORED,
+			Details:    map[string]any{"restoredClock": clock, "clock": result.Clock},
+		})
+		if err != nil {
+			httperrors.InternalServerError(w)
+			log.Error().Err(err).Msg("failed to write audit log entry")
+			return
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("failed to commit transaction")
+		return
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		httperrors.InternalServerError(w)
+		log.Error().Err(err).Msg("error marshalling response")
+		return
+	}
+
+	log.Info().Int32("update_clock", result.Clock).Msg("restored document version")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// groupVersions buckets the updates, ordered by clock, into versions and
+// returns them newest first
+func groupVersions(updates []db.ListDocumentUpdateHistoryRow, snapshots []db.DocumentSnapshot) []VersionResponse {
+	snapshotsByClock := map[int32][]SnapshotResponse{}
+	for _, snapshot := range snapshots {
+		snapshotsByClock[snapshot.Clock] = append(snapshotsByClock[snapshot.Clock], snapshotResponse(snapshot))
+	}
+
+	versions := []VersionResponse{}
+	var current *VersionResponse
+	for _, update := range updates {
+		if current == nil || update.CreatedAt.Time.Sub(current.StartedAt) >= VERSION_INTERVAL {
+			versions = append(versions, VersionResponse{
+				FromClock: update.Clock,
+				StartedAt: update.CreatedAt.Time,
+				Authors:   []uuid.UUID{},
+				Snapshots: []SnapshotResponse{},
+			})
+			current = &versions[len(versions)-1]
+		}
+
+		current.Clock = update.Clock
+		current.EndedAt = update.CreatedAt.Time
+		current.Updates++
+		if author := nullableUUID(update.AuthorID); author != nil && !slices.Contains(current.Authors, *author) {
+			current.Authors = append(current.Authors, *author)
+		}
+
+		if named, ok := snapshotsByClock[update.Clock]; ok {
+			current.Snapshots = append(current.Snapshots, named...)
+			current = nil
+		}
+	}
+
+	slices.Reverse(versions)
+	return versions
+}
+
+// loadDocumentContentAt replays the updates of the document up to and
+// including clock
+func loadDocumentContentAt(ctx context.Context, q *db.Queries, docID uuid.UUID, clock int32) (*yjs.Doc, error) {
+	updates, err := q.ListDocumentUpdateValuesUntil(ctx, db.ListDocumentUpdateValuesUntilParams{
+		DocumentID: docID,
+		Clock:      clock,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return yjs.MergeUpdates(updates)
+}
+
+// appendDocumentUpdate stores an update made by the server the way the
+// websocket server stores the updates of clients. The websocket server is
+// notified on commit so it can forward the update to connected clients.
+func appendDocumentUpdate(ctx context.Context, q *db.Queries, docID uuid.UUID, authorID uuid.UUID, update []byte) (int32, error) {
+	// The row lock serialises writers, the websocket server takes it too
+	// before it picks the next clock and merges the state vector
+	document, err := q.GetDocumentForUpdate(ctx, docID)
+	if err != nil {
+		return 0, err
+	}
+
+	stateVector := map[uint64]uint64{}
+	if len(document.StateVector) > 0 {
+		stateVector, err = yjs.DecodeStateVector(document.StateVector)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode state vector: %w", err)
+		}
+	}
+	updateStateVector, err := yjs.UpdateStateVector(update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode update: %w", err)
+	}
+	for client, clock := range updateStateVector {
+		stateVector[client] = max(stateVector[client], clock)
+	}
+
+	clock, err := q.AppendDocumentUpdate(ctx, db.AppendDocumentUpdateParams{
+		DocumentID: docID,
+		Value:      update,
+		AuthorID:   pgtype.UUID{Bytes: authorID, Valid: true},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = q.UpdateDocumentStateVector(ctx, db.UpdateDocumentStateVectorParams{
+		ID:          docID,
+		StateVector: yjs.EncodeStateVector(stateVector),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return clock, q.NotifyDocumentUpdate(ctx, fmt.Sprintf("%s:%d", docID, clock))
+}
+
+func parseClock(raw string) (int32, error) {
+	clock, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil || clock < 0 {
+		return 0, errInvalidClock
+	}
+	return int32(clock), nil
+}
+
+func validateSnapshotCreate(payload *SnapshotCreate) error {
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" {
+		return errors.New("snapshot name can not be empty")
+	}
+	if utf8.RuneCountInString(payload.Name) > MAX_SNAPSHOT_NAME_LENGTH {
+		return fmt.Errorf("snapshot name can not be longer than %d characters", MAX_SNAPSHOT_NAME_LENGTH)
+	}
+	if payload.Clock != nil && *payload.Clock < 0 {
+		return errInvalidClock
+	}
+	return nil
+}
+
+func snapshotResponse(snapshot db.DocumentSnapshot) SnapshotResponse {
+	return SnapshotResponse{
+		ID:        snapshot.ID,
+		Name:      snapshot.Name,
+		Clock:     snapshot.Clock,
+		CreatedBy: nullableUUID(snapshot.CreatedBy),
+		CreatedAt: snapshot.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+)
+
+type AzureStore struct {
+	client *azblob.Client
+}
+
+func NewAzureStore(client *azblob.Client) *AzureStore {
+	return &AzureStore{client: client}
+}
+
+func (s *AzureStore) EnsureContainer(ctx context.Context, container string) error {
+	_, err := s.client.CreateContainer(ctx, container, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		return err
+	}
+	return nil
+}
+
+func (s *AzureStore) Put(ctx context.Context, container string, key string, body []byte, contentType string) error {
+	_, err := s.client.UploadBuffer(ctx, container, key, body, &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	})
+	return err
+}
+
+func (s *AzureStore) Get(ctx context.Context, container string, key string) (io.ReadCloser, error) {
+	res, err := s.client.DownloadStream(ctx, container, key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *AzureStore) Delete(ctx context.Context, container string, key string) error {
+	_, err := s.client.DeleteBlob(ctx, container, key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *AzureStore) List(ctx context.Context, container string, prefix string) ([]ObjectInfo, error) {
+	pager := s.client.NewListBlobsFlatPager(container, &azblob.ListBlobsFlatOptions{
+		Prefix: &prefix,
+	})
+
+	objects := []ObjectInfo{}
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Segment.BlobItems {
+			object := ObjectInfo{Key: *item.Name}
+			if item.Properties != nil {
+				if item.Properties.ContentLength != nil {
+					object.Size = *item.Properties.ContentLength
+				}
+				if item.Properties.ContentType != nil {
+					object.ContentType = *item.Properties.ContentType
+				}
+				if item.Properties.LastModified != nil {
+					object.LastModified = *item.Properties.LastModified
+				}
+			}
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+func (s *AzureStore) URL(container string, key string) string {
+	return s.blobClient(container, key).URL()
+}
+
+func (s *AzureStore) SignedURL(ctx context.Context, container string, key string, expiry time.Duration) (string, error) {
+	blobClient := s.blobClient(container, key)
+	expiresAt := time.Now().UTC().Add(expiry)
+	permissions := sas.BlobPermissions{Read: true}
+
+	signedURL, err := blobClient.GetSASURL(permissions, expiresAt, nil)
+	if !errors.Is(err, bloberror.MissingSharedKeyCredential) {
+		return signedURL, err
+	}
+
+	// Clients authenticated with Azure AD have no account key, sign with a user delegation key instead
+	startsAt := time.Now().UTC().Add(-5 * time.Minute)
+	start := startsAt.Format(sas.TimeFormat)
+	end := expiresAt.Format(sas.TimeFormat)
+	credential, err := s.client.ServiceClient().GetUserDelegationCredential(ctx, service.KeyInfo{
+		Start:  &start,
+		Expiry: &end,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	params, err := sas.BlobSignatureValues{
+		Protocol:      sas.ProtocolHTTPS,
+		StartTime:     startsAt,
+		ExpiryTime:    expiresAt,
+		Permissions:   permissions.String(),
+		ContainerName: container,
+		BlobName:      key,
+	}.SignWithUserDelegation(credential)
+	if err != nil {
+		return "", err
+	}
+
+	return blobClient.URL() + "?" + params.Encode(), nil
+}
+
+func (s *AzureStore) blobClient(container string, key string) *blob.Client {
+	return s.client.ServiceClient().NewContainerClient(container).NewBlobClient(key)
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
+)
+
+// LocalStore keeps objects in a directory on disk, every container is a subdirectory.
+// Objects are served by Handler, which should be mounted on the path of baseURL.
+// The content type of an object is derived from the extension of its key.
+type LocalStore struct {
+	root             string
+	baseURL          string
+	mountPath        string
+	signingKey       []byte
+	publicContainers []string
+}
+
+func NewLocalStore(root string, baseURL string, signingKey string, publicContainers []string) (*LocalStore, error) {
+	err := os.MkdirAll(root, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+
+	// The files are served under the path of the base url, without one the
+	// file handler would take every GET request the api does not handle
+	mountPath := strings.TrimSuffix(parsed.Path, "/")
+	if mountPath == "" {
+		return nil, errors.New("base url needs a path to serve files under")
+	}
+
+	return &LocalStore{
+		root:             root,
+		baseURL:          strings.TrimSuffix(baseURL, "/"),
+		mountPath:        mountPath,
+		signingKey:       []byte(signingKey),
+		publicContainers: publicContainers,
+	}, nil
+}
+
+// MountPath is the path of baseURL, Handler expects requests with this prefix stripped
+func (s *LocalStore) MountPath() string {
+	return s.mountPath
+}
+
+func (s *LocalStore) EnsureContainer(ctx context.Context, container string) error {
+	dir, err := s.path(container, "")
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(dir, 0755)
+}
+
+func (s *LocalStore) Put(ctx context.Context, container string, key string, body []byte, contentType string) error {
+	objectPath, err := s.path(container, key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(objectPath), 0755)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partially written object
+	tmp, err := os.CreateTemp(filepath.Dir(objectPath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(body)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), objectPath)
+}
+
+func (s *LocalStore) Get(ctx context.Context, container string, key string) (io.ReadCloser, error) {
+	objectPath, err := s.path(container, key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, container string, key string) error {
+	objectPath, err := s.path(container, key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *LocalStore) List(ctx context.Context, container string, prefix string) ([]ObjectInfo, error) {
+	dir, err := s.path(container, "")
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []ObjectInfo{}
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			ContentType:  mime.TypeByExtension(path.Ext(key)),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return objects, nil
+	}
+	return objects, err
+}
+
+func (s *LocalStore) URL(container string, key string) string {
+	return s.baseURL + "/" + container + "/" + key
+}
+
+func (s *LocalStore) SignedURL(ctx context.Context, container string, key string, expiry time.Duration) (string, error) {
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	return fmt.Sprintf("%s?expires=%s&signature=%s", s.URL(container, key), expires, s.sign(container, key, expires)), nil
+}
+
+// Handler serves objects on /{container}/{key}, objects in private containers require a signed URL
+func (s *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		container, key, found := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if !found || key == "" {
+			httperrors.Write(w, "object not found", http.StatusNotFound)
+			return
+		}
+
+		if !slices.Contains(s.publicContainers, container) && !s.verify(container, key, r) {
+			httperrors.Write(w, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+
+		object, err := s.Get(r.Context(), container, key)
+		if err != nil {
+			httperrors.Write(w, "object not found", http.StatusNotFound)
+			return
+		}
+		defer object.Close()
+
+		if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(http.StatusOK)
+		io.Copy(w, object)
+	})
+}
+
+func (s *LocalStore) verify(container string, key string, r *http.Request) bool {
+	expires := r.URL.Query().Get("expires")
+	signature := r.URL.Query().Get("signature")
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(s.sign(container, key, expires)))
+}
+
+func (s *LocalStore) sign(container string, key string, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(container + "/" + key + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path resolves the location of an object and rejects keys that would escape the root directory
+func (s *LocalStore) path(container string, key string) (string, error) {
+	if container == "" || strings.ContainsAny(container, `/\`) || container == "." || container == ".." {
+		return "", fmt.Errorf("invalid container name: %q", container)
+	}
+
+	dir := filepath.Join(s.root, container)
+	if key == "" {
+		return dir, nil
+	}
+
+	objectPath := filepath.Join(dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(objectPath, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid object key: %q", key)
+	}
+	return objectPath, nil
+}
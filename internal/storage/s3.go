@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Store works with AWS S3 and S3 compatible services such as MinIO, containers map to buckets
+type S3Store struct {
+	client *minio.Client
+}
+
+func NewS3Store(endpoint string, region string, accessKey string, secretKey string, useSsl bool) (*S3Store, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Region: region,
+		Secure: useSsl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{client: client}, nil
+}
+
+func (s *S3Store) EnsureContainer(ctx context.Context, container string) error {
+	exists, err := s.client.BucketExists(ctx, container)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return s.client.MakeBucket(ctx, container, minio.MakeBucketOptions{})
+}
+
+func (s *S3Store) Put(ctx context.Context, container string, key string, body []byte, contentType string) error {
+	_, err := s.client.PutObject(
+		ctx,
+		container,
+		key,
+		bytes.NewReader(body),
+		int64(len(body)),
+		minio.PutObjectOptions{ContentType: contentType},
+	)
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, container string, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, container, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	// GetObject is lazy, stat the object so a missing key surfaces here instead of on the first read
+	_, err = object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, mapS3Error(err)
+	}
+	return object, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, container string, key string) error {
+	// RemoveObject succeeds for missing keys, stat first to report them like the other stores
+	_, err := s.client.StatObject(ctx, container, key, minio.StatObjectOptions{})
+	if err != nil {
+		return mapS3Error(err)
+	}
+	return mapS3Error(s.client.RemoveObject(ctx, container, key, minio.RemoveObjectOptions{}))
+}
+
+func (s *S3Store) List(ctx context.Context, container string, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+	for object := range s.client.ListObjects(ctx, container, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return nil, mapS3Error(object.Err)
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          object.Key,
+			Size:         object.Size,
+			ContentType:  object.ContentType,
+			LastModified: object.LastModified,
+		})
+	}
+	return objects, nil
+}
+
+func (s *S3Store) URL(container string, key string) string {
+	return s.client.EndpointURL().JoinPath(container, key).String()
+}
+
+func (s *S3Store) SignedURL(ctx context.Context, container string, key string, expiry time.Duration) (string, error) {
+	signedURL, err := s.client.PresignedGetObject(ctx, container, key, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return signedURL.String(), nil
+}
+
+func mapS3Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NoSuchBucket":
+		return ErrNotFound
+	}
+	return err
+}
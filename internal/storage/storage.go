@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	AZURE = "azure"
+	LOCAL = "local"
+	S3    = "s3"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// ObjectStore stores blobs in containers, which map to Azure containers, S3 buckets or local directories
+type ObjectStore interface {
+	EnsureContainer(ctx context.Context, container string) error
+	Put(ctx context.Context, container string, key string, body []byte, contentType string) error
+	// Get returns ErrNotFound when the object does not exist, the caller closes the reader
+	Get(ctx context.Context, container string, key string) (io.ReadCloser, error)
+	// Delete returns ErrNotFound when the object does not exist
+	Delete(ctx context.Context, container string, key string) error
+	List(ctx context.Context, container string, prefix string) ([]ObjectInfo, error)
+	// URL is the unsigned location of an object, readable when the container allows public access
+	URL(container string, key string) string
+	SignedURL(ctx context.Context, container string, key string, expiry time.Duration) (string, error)
+}
@@ -0,0 +1,112 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/logger"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
+)
+
+var log = logger.Get()
+
+// Purger permanently removes documents that have been in the trash for
+// longer than the retention period, together with their update history.
+type Purger struct {
+	Pool      *pgxpool.Pool
+	Retention time.Duration
+	Interval  time.Duration
+	BatchSize int32
+}
+
+// Validate rejects settings that would make the purger loop without pausing
+func (p *Purger) Validate() error {
+	if p.BatchSize <= 0 {
+		return errors.New("trash batch size must be positive")
+	}
+	if p.Interval <= 0 {
+		return errors.New("trash purge interval must be positive")
+	}
+	return nil
+}
+
+func (p *Purger) Run(ctx context.Context) {
+	log.Info().Dur("retention", p.Retention).Msg("starting trash purger")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("stopping trash purger")
+			return
+		case <-time.After(p.Interval):
+		}
+
+		_, err := p.Purge(ctx, time.Now())
+		if err != nil {
+			log.Error().Err(err).Msg("error purging trash")
+		}
+	}
+}
+
+// Purge removes every document that was deleted before now minus the
+// retention period and returns how many were removed
+func (p *Purger) Purge(ctx context.Context, now time.Time) (int, error) {
+	deletedBefore := pgtype.Timestamptz{Time: now.Add(-p.Retention), Valid: true}
+
+	total := 0
+	for {
+		purged, err := p.purgeBatch(ctx, deletedBefore)
+		if err != nil {
+			return total, err
+		}
+		total += purged
+		if purged < int(p.BatchSize) {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Info().Int("documents", total).Msg("purged documents from trash")
+	}
+	return total, nil
+}
+
+func (p *Purger) purgeBatch(ctx context.Context, deletedBefore pgtype.Timestamptz) (int, error) {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	q := db.New(p.Pool).WithTx(tx)
+
+	purged, err := q.PurgeDeletedDocuments(ctx, db.PurgeDeletedDocumentsParams{
+		DeletedBefore: deletedBefore,
+		BatchSize:     p.BatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, document := range purged {
+		err = outbox.Enqueue(ctx, q, events.DocumentPurged{
+			ID:      document.ID,
+			OwnerID: document.OwnerID,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(purged), nil
+}
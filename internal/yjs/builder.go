@@ -0,0 +1,206 @@
+package yjs
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"unicode/utf16"
+)
+
+// XmlNode is a node of an xml fragment. Nodes without a name are xml texts,
+// their content is the delta, elements have attributes and children.
+type XmlNode struct {
+	Name       string
+	Attributes map[string]any
+	Children   []XmlNode
+	Delta      []Delta
+}
+
+// EncodeXmlFragment encodes an update that inserts nodes into the top level
+// xml fragment root of an empty document, as a single transaction of client
+func EncodeXmlFragment(client uint64, root string, nodes []XmlNode) []byte {
+	w := &structWriter{client: client}
+	w.nodes(parent{key: root}, nodes)
+	return w.update(nil)
+}
+
+// EncodeStateVector encodes a state vector the way encodeStateVector does,
+// the format of documents.state_vector
+func EncodeStateVector(vector map[uint64]uint64) []byte {
+	clients := []uint64{}
+	for client := range vector {
+		clients = append(clients, client)
+	}
+	slices.Sort(clients)
+	slices.Reverse(clients)
+
+	e := &encoder{}
+	e.writeVarUint(uint64(len(clients)))
+	for _, client := range clients {
+		e.writeVarUint(client)
+		e.writeVarUint(vector[client])
+	}
+	return e.buf
+}
+
+// parent is either a top level type or the item of a nested type
+type parent struct {
+	key string
+	id  *ID
+}
+
+// structWriter writes the structs of a single client with increasing clocks
+type structWriter struct {
+	client  uint64
+	clock   uint64
+	count   uint64
+	structs encoder
+}
+
+// item writes a struct and returns the id of its last clock tick. Structs
+// with an origin inherit the parent of their origin, so it is only written
+// for the first struct of a type.
+func (w *structWriter) item(origin *ID, p parent, sub string, ref byte, length uint64, content func(e *encoder)) ID {
+	return w.itemBetween(origin, nil, p, sub, ref, length, content)
+}
+
+// itemBetween writes a struct that is inserted between origin and
+// rightOrigin, the parent is only written when both are nil
+func (w *structWriter) itemBetween(origin *ID, rightOrigin *ID, p parent, sub string, ref byte, length uint64, content func(e *encoder)) ID {
+	info := ref
+	if origin != nil {
+		info |= infoOrigin
+	}
+	if rightOrigin != nil {
+		info |= infoRightOrigin
+	}
+	if sub != "" {
+		info |= infoParentSub
+	}
+
+	e := &w.structs
+	e.writeUint8(info)
+	if origin != nil {
+		e.writeID(*origin)
+	}
+	if rightOrigin != nil {
+		e.writeID(*rightOrigin)
+	}
+	if origin == nil && rightOrigin == nil {
+		if p.id == nil {
+			e.writeVarUint(1)
+			e.writeVarString(p.key)
+		} else {
+			e.writeVarUint(0)
+			e.writeID(*p.id)
+		}
+		if sub != "" {
+			e.writeVarString(sub)
+		}
+	}
+	content(e)
+
+	w.clock += length
+	w.count++
+	return ID{Client: w.client, Clock: w.clock - 1}
+}
+
+// update encodes the written structs as a single group followed by the
+// delete set
+func (w *structWriter) update(deletes []deleteRange) []byte {
+	e := &encoder{}
+	if w.count == 0 {
+		e.writeVarUint(0)
+	} else {
+		e.writeVarUint(1)
+		e.writeVarUint(w.count)
+		e.writeVarUint(w.client)
+		e.writeVarUint(0)
+		e.buf = append(e.buf, w.structs.buf...)
+	}
+	e.writeDeleteSet(deletes)
+	return e.buf
+}
+
+func (w *structWriter) nodes(p parent, nodes []XmlNode) {
+	var origin *ID
+	for _, node := range nodes {
+		last := w.node(origin, p, node)
+		origin = &last
+	}
+}
+
+func (w *structWriter) node(origin *ID, p parent, node XmlNode) ID {
+	if node.Name == "" {
+		id := w.item(origin, p, "", refType, 1, func(e *encoder) {
+			e.writeVarUint(typeXmlText)
+		})
+		w.text(parent{id: &id}, node.Delta)
+		return id
+	}
+
+	id := w.item(origin, p, "", refType, 1, func(e *encoder) {
+		e.writeVarUint(typeXmlElement)
+		e.writeVarString(node.Name)
+	})
+	for _, key := range sortedKeys(node.Attributes) {
+		value := node.Attributes[key]
+		w.item(nil, parent{id: &id}, key, refAny, 1, func(e *encoder) {
+			e.writeVarUint(1)
+			e.writeAny(value)
+		})
+	}
+	w.nodes(parent{id: &id}, node.Children)
+	return id
+}
+
+// text writes the delta the way Yjs inserts formatted text, a format struct
+// starts every attribute change and open attributes are closed at the end
+func (w *structWriter) text(p parent, delta []Delta) {
+	var origin *ID
+	write := func(ref byte, length uint64, content func(e *encoder)) {
+		last := w.item(origin, p, "", ref, length, content)
+		origin = &last
+	}
+	format := func(key string, value any) {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			raw = []byte("null")
+		}
+		write(refFormat, 1, func(e *encoder) {
+			e.writeVarString(key)
+			e.writeVarString(string(raw))
+		})
+	}
+
+	current := map[string]any{}
+	for _, op := range delta {
+		text, ok := op.Insert.(string)
+		if !ok || text == "" {
+			continue
+		}
+
+		for _, key := range sortedKeys(current) {
+			if _, ok := op.Attributes[key]; !ok {
+				format(key, nil)
+				delete(current, key)
+			}
+		}
+		for _, key := range sortedKeys(op.Attributes) {
+			value := op.Attributes[key]
+			if existing, ok := current[key]; !ok || !reflect.DeepEqual(existing, value) {
+				format(key, value)
+				current[key] = value
+			}
+		}
+
+		length := uint64(len(utf16.Encode([]rune(text))))
+		write(refString, length, func(e *encoder) {
+			e.writeVarString(text)
+		})
+	}
+
+	for _, key := range sortedKeys(current) {
+		format(key, nil)
+	}
+}
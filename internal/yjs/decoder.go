@@ -0,0 +1,173 @@
+package yjs
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var (
+	ErrUnexpectedEOF = errors.New("yjs: unexpected end of update")
+	ErrInvalidUpdate = errors.New("yjs: invalid update")
+)
+
+// decoder reads the lib0 primitives the v1 update encoding is built from
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *decoder) readUint8() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, ErrUnexpectedEOF
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *decoder) readBytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.buf)-d.pos) {
+		return nil, ErrUnexpectedEOF
+	}
+	b := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func (d *decoder) readVarUint() (uint64, error) {
+	var num uint64
+	var shift uint
+	for {
+		b, err := d.readUint8()
+		if err != nil {
+			return 0, err
+		}
+		if shift > 63 {
+			return 0, ErrInvalidUpdate
+		}
+		num |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return num, nil
+		}
+		shift += 7
+	}
+}
+
+// readVarInt reads a signed integer, the first byte holds a continuation
+// bit, a sign bit and six bits of the value
+func (d *decoder) readVarInt() (int64, error) {
+	b, err := d.readUint8()
+	if err != nil {
+		return 0, err
+	}
+	num := int64(b & 0x3f)
+	negative := b&0x40 > 0
+	shift := uint(6)
+	for b&0x80 > 0 {
+		b, err = d.readUint8()
+		if err != nil {
+			return 0, err
+		}
+		if shift > 63 {
+			return 0, ErrInvalidUpdate
+		}
+		num |= int64(b&0x7f) << shift
+		shift += 7
+	}
+	if negative {
+		return -num, nil
+	}
+	return num, nil
+}
+
+func (d *decoder) readVarBytes() ([]byte, error) {
+	n, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	return d.readBytes(n)
+}
+
+func (d *decoder) readVarString() (string, error) {
+	b, err := d.readVarBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readAny reads a value written with lib0 writeAny, undefined and null are
+// both returned as nil
+func (d *decoder) readAny() (any, error) {
+	tag, err := d.readUint8()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case 127, 126:
+		return nil, nil
+	case 125:
+		return d.readVarInt()
+	case 124:
+		b, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 123:
+		b, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 122:
+		b, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return int64(binary.BigEndian.Uint64(b)), nil
+	case 121:
+		return false, nil
+	case 120:
+		return true, nil
+	case 119:
+		return d.readVarString()
+	case 118:
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		object := make(map[string]any)
+		for i := uint64(0); i < n; i++ {
+			key, err := d.readVarString()
+			if err != nil {
+				return nil, err
+			}
+			object[key], err = d.readAny()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return object, nil
+	case 117:
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		array := []any{}
+		for i := uint64(0); i < n; i++ {
+			value, err := d.readAny()
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case 116:
+		return d.readVarBytes()
+	default:
+		return nil, ErrInvalidUpdate
+	}
+}
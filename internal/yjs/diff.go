@@ -0,0 +1,202 @@
+package yjs
+
+import (
+	"slices"
+	"sort"
+	"unicode/utf16"
+)
+
+type ChangeKind string
+
+const (
+	ChangeEqual  ChangeKind = "equal"
+	ChangeInsert ChangeKind = "insert"
+	ChangeDelete ChangeKind = "delete"
+)
+
+// TextChange is a run of text that was kept, inserted or deleted between two
+// states of a document. Author is whoever inserted or deleted the text, it
+// is empty for kept text and for changes no update was attributed to.
+type TextChange struct {
+	Kind   ChangeKind
+	Text   string
+	Author string
+}
+
+// Authors attributes structs and deletions to the author of the update that
+// contains them. The first update that inserted or deleted a struct wins,
+// the way it would be when the updates are applied in order.
+type Authors struct {
+	inserted map[uint64][]authorRange
+	deleted  map[uint64][]authorRange
+}
+
+type authorRange struct {
+	clock  uint64
+	length uint64
+	author string
+}
+
+func NewAuthors() *Authors {
+	return &Authors{
+		inserted: make(map[uint64][]authorRange),
+		deleted:  make(map[uint64][]authorRange),
+	}
+}
+
+// Add attributes the structs and the delete set of the update to author
+func (a *Authors) Add(buf []byte, author string) error {
+	u, err := decodeUpdate(buf)
+	if err != nil {
+		return err
+	}
+
+	for _, it := range u.items {
+		if !it.gc {
+			addAuthorRange(a.inserted, it.id.Client, authorRange{clock: it.id.Clock, length: it.length, author: author})
+		}
+	}
+	for _, r := range u.deletes {
+		addAuthorRange(a.deleted, r.client, authorRange{clock: r.clock, length: r.length, author: author})
+	}
+	return nil
+}
+
+// addAuthorRange attributes the clocks of r that have no author yet. The
+// ranges of a client stay sorted by clock and never overlap, so the author of
+// a clock is found with a binary search. Updates mostly continue the last
+// range, only an update that reaches back into it has to be merged.
+func addAuthorRange(ranges map[uint64][]authorRange, client uint64, r authorRange) {
+	existing := ranges[client]
+	n := len(existing)
+	if n == 0 || existing[n-1].clock+existing[n-1].length <= r.clock {
+		ranges[client] = appendAuthorRange(existing, r)
+		return
+	}
+
+	// Only the gaps between the ranges that r overlaps are new
+	i := sort.Search(n, func(i int) bool {
+		return existing[i].clock+existing[i].length > r.clock
+	})
+	merged := slices.Clone(existing[:i])
+	clock, end := r.clock, r.clock+r.length
+	for ; i < n && existing[i].clock < end; i++ {
+		if clock < existing[i].clock {
+			merged = appendAuthorRange(merged, authorRange{clock: clock, length: existing[i].clock - clock, author: r.author})
+		}
+		merged = appendAuthorRange(merged, existing[i])
+		clock = max(clock, existing[i].clock+existing[i].length)
+	}
+	if clock < end {
+		merged = appendAuthorRange(merged, authorRange{clock: clock, length: end - clock, author: r.author})
+	}
+	ranges[client] = append(merged, existing[i:]...)
+}
+
+// appendAuthorRange extends the last range when r continues it with the
+// same author, items are decoded per clock tick so most ranges do
+func appendAuthorRange(ranges []authorRange, r authorRange) []authorRange {
+	if n := len(ranges); n > 0 {
+		last := &ranges[n-1]
+		if last.author == r.author && last.clock+last.length == r.clock {
+			last.length += r.length
+			return ranges
+		}
+	}
+	return append(ranges, r)
+}
+
+func findAuthor(ranges map[uint64][]authorRange, id ID) string {
+	client := ranges[id.Client]
+	i := sort.Search(len(client), func(i int) bool {
+		return client[i].clock+client[i].length > id.Clock
+	})
+	if i < len(client) && client[i].clock <= id.Clock {
+		return client[i].author
+	}
+	return ""
+}
+
+// DiffText compares the text of from with the text of to, a later state of
+// the same document. The changes follow the document order of to, text and
+// xml text are included and every xml text ends with a newline.
+func DiffText(from *Doc, to *Doc, authors *Authors) []TextChange {
+	d := &differ{from: from, authors: authors}
+	for _, name := range to.Roots() {
+		root := to.Get(name)
+		switch root.Kind() {
+		case KindText, KindXmlFragment:
+			d.sequence(root, true, true)
+		}
+	}
+	d.flush()
+	return d.changes
+}
+
+type differ struct {
+	from    *Doc
+	authors *Authors
+	changes []TextChange
+
+	kind   ChangeKind
+	author string
+	units  []uint16
+}
+
+// sequence walks the items of a type, before and after tell whether the
+// type itself is visible in from and in to
+func (d *differ) sequence(t *Type, before bool, after bool) {
+	for it := t.start; it != nil; it = it.right {
+		itemBefore := before && d.visibleBefore(it)
+		itemAfter := after && !it.deleted
+		if !itemBefore && !itemAfter {
+			continue
+		}
+
+		switch it.content.ref {
+		case refString:
+			d.add(it, itemBefore, itemAfter, it.content.unit)
+		case refType:
+			switch it.content.typ.kind {
+			case KindXmlElement:
+				d.sequence(it.content.typ, itemBefore, itemAfter)
+			case KindXmlText:
+				d.sequence(it.content.typ, itemBefore, itemAfter)
+				d.add(it, itemBefore, itemAfter, '\n')
+			}
+		}
+	}
+}
+
+func (d *differ) visibleBefore(it *item) bool {
+	previous := d.from.find(it.id)
+	return previous != nil && !previous.gc && !previous.deleted
+}
+
+func (d *differ) add(it *item, before bool, after bool, unit uint16) {
+	kind, author := ChangeEqual, ""
+	switch {
+	case !before:
+		kind, author = ChangeInsert, findAuthor(d.authors.inserted, it.id)
+	case !after:
+		kind, author = ChangeDelete, findAuthor(d.authors.deleted, it.id)
+	}
+
+	if kind != d.kind || author != d.author {
+		d.flush()
+		d.kind, d.author = kind, author
+	}
+	d.units = append(d.units, unit)
+}
+
+func (d *differ) flush() {
+	if len(d.units) == 0 {
+		return
+	}
+	d.changes = append(d.changes, TextChange{
+		Kind:   d.kind,
+		Text:   string(utf16.Decode(d.units)),
+		Author: d.author,
+	})
+	d.units = nil
+}
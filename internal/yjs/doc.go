@@ -0,0 +1,438 @@
+package yjs
+
+import (
+	"slices"
+	"sort"
+)
+
+// Doc is a read-only replica of a Yjs document. Updates are integrated with
+// the same conflict resolution as Yjs and yrs, so applying the updates that
+// were persisted for a document reproduces the content its clients see.
+type Doc struct {
+	roots   map[string]*Type
+	structs map[uint64][]*item
+	pending map[uint64]map[uint64]*item
+	deletes []deleteRange
+}
+
+func NewDoc() *Doc {
+	return &Doc{
+		roots:   make(map[string]*Type),
+		structs: make(map[uint64][]*item),
+		pending: make(map[uint64]map[uint64]*item),
+	}
+}
+
+// MergeUpdates applies updates in the order they were persisted, which is
+// the order of the clock column of document_updates
+func MergeUpdates(updates [][]byte) (*Doc, error) {
+	doc := NewDoc()
+	for _, update := range updates {
+		err := doc.ApplyUpdate(update)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// ApplyUpdate integrates an update encoded with encodeStateAsUpdate or
+// received from a provider, both use the v1 encoding. Structs whose
+// dependencies are missing are kept until a later update provides them.
+func (d *Doc) ApplyUpdate(buf []byte) error {
+	u, err := decodeUpdate(buf)
+	if err != nil {
+		return err
+	}
+
+	for _, it := range u.items {
+		if it.id.Clock+it.length <= d.state(it.id.Client) {
+			continue
+		}
+		if d.pending[it.id.Client] == nil {
+			d.pending[it.id.Client] = make(map[uint64]*item)
+		}
+		// A longer run starting at the same clock covers the shorter one
+		if existing, ok := d.pending[it.id.Client][it.id.Clock]; !ok || existing.length < it.length {
+			d.pending[it.id.Client][it.id.Clock] = it
+		}
+	}
+	d.deletes = append(d.deletes, u.deletes...)
+
+	d.integratePending()
+	d.applyDeletes()
+	return nil
+}
+
+// Roots returns the names of the top level types in the document
+func (d *Doc) Roots() []string {
+	names := []string{}
+	for name := range d.roots {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Get returns the top level type with the given name or nil when the
+// document does not contain it
+func (d *Doc) Get(name string) *Type {
+	root, ok := d.roots[name]
+	if !ok {
+		return nil
+	}
+	root.inferKind()
+	return root
+}
+
+// ToJSON returns the content of every top level type, see Type.ToJSON
+func (d *Doc) ToJSON() map[string]any {
+	content := make(map[string]any)
+	for _, name := range d.Roots() {
+		content[name] = d.Get(name).ToJSON()
+	}
+	return content
+}
+
+// StateVector returns the next expected clock of every client that has
+// integrated structs, structs that are still pending are not included
+func (d *Doc) StateVector() map[uint64]uint64 {
+	vector := make(map[uint64]uint64)
+	for client := range d.structs {
+		vector[client] = d.state(client)
+	}
+	return vector
+}
+
+func (d *Doc) state(client uint64) uint64 {
+	structs := d.structs[client]
+	if len(structs) == 0 {
+		return 0
+	}
+	last := structs[len(structs)-1]
+	return last.id.Clock + last.length
+}
+
+// find returns the struct that contains the clock tick of id, structs of a
+// client are sorted by clock and cover every tick from zero to the state
+func (d *Doc) find(id ID) *item {
+	structs := d.structs[id.Client]
+	i := d.index(id)
+	if i == len(structs) {
+		return nil
+	}
+	return structs[i]
+}
+
+func (d *Doc) index(id ID) int {
+	structs := d.structs[id.Client]
+	return sort.Search(len(structs), func(i int) bool {
+		return structs[i].id.Clock+structs[i].length > id.Clock
+	})
+}
+
+// findEndingAt returns the struct whose last tick is id, a deleted run that
+// continues after id is split like Yjs splits structs an item is inserted in
+func (d *Doc) findEndingAt(id ID) *item {
+	it := d.find(id)
+	if it != nil && !it.gc && it.id.Clock+it.length-1 > id.Clock {
+		d.split(it, id.Clock-it.id.Clock+1)
+	}
+	return it
+}
+
+// findStartingAt returns the struct whose first tick is id, splitting a
+// deleted run that starts before id
+func (d *Doc) findStartingAt(id ID) *item {
+	it := d.find(id)
+	if it != nil && !it.gc && it.id.Clock < id.Clock {
+		return d.split(it, id.Clock-it.id.Clock)
+	}
+	return it
+}
+
+// split cuts a run at offset and returns the part after it, which takes the
+// place of the run on the right
+func (d *Doc) split(it *item, offset uint64) *item {
+	right := *it
+	right.id.Clock = it.id.Clock + offset
+	right.length = it.length - offset
+	right.origin = &ID{Client: it.id.Client, Clock: right.id.Clock - 1}
+	right.left = it
+	it.length = offset
+	it.right = &right
+
+	if right.right != nil {
+		right.right.left = &right
+	} else if right.hasSub && right.parent != nil && right.parent.entries[right.parentSub] == it {
+		right.parent.entries[right.parentSub] = &right
+	}
+
+	i := d.index(it.id) + 1
+	d.structs[it.id.Client] = slices.Insert(d.structs[it.id.Client], i, &right)
+	return &right
+}
+
+// nextPending returns the pending struct that continues the state of the
+// client. A pending run that overlaps the state is trimmed to start at it.
+func (d *Doc) nextPending(client uint64) *item {
+	state := d.state(client)
+	pending := d.pending[client]
+	if it, ok := pending[state]; ok {
+		return it
+	}
+
+	for clock, it := range pending {
+		if clock >= state {
+			continue
+		}
+		delete(pending, clock)
+		if clock+it.length > state {
+			offset := state - clock
+			it.id.Clock += offset
+			it.length -= offset
+			if !it.gc {
+				it.origin = &ID{Client: client, Clock: state - 1}
+			}
+			pending[state] = it
+			return it
+		}
+	}
+	return nil
+}
+
+func (d *Doc) root(name string) *Type {
+	root, ok := d.roots[name]
+	if !ok {
+		root = &Type{entries: make(map[string]*item)}
+		d.roots[name] = root
+	}
+	return root
+}
+
+// integratePending integrates every pending struct whose dependencies are
+// known, following dependencies to other clients first like Yjs does
+func (d *Doc) integratePending() {
+	for d.integrateRound() {
+	}
+
+	for client, pending := range d.pending {
+		if len(pending) == 0 {
+			delete(d.pending, client)
+		}
+	}
+}
+
+// integrateRound reports whether any struct was integrated, a client that
+// was blocked may be unblocked by structs integrated later in the round
+func (d *Doc) integrateRound() bool {
+	clients := []uint64{}
+	for client := range d.pending {
+		clients = append(clients, client)
+	}
+	slices.Sort(clients)
+
+	progress := false
+	blocked := make(map[uint64]bool)
+	for _, client := range clients {
+		if blocked[client] {
+			continue
+		}
+
+		stack := []uint64{client}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			it := d.nextPending(current)
+			if it == nil {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+
+			dependency, missing := d.missingDependency(it)
+			if missing {
+				available := d.nextPending(dependency) != nil
+				if available && !blocked[dependency] && !slices.Contains(stack, dependency) {
+					stack = append(stack, dependency)
+					continue
+				}
+				blocked[current] = true
+				stack = stack[:len(stack)-1]
+				continue
+			}
+
+			delete(d.pending[current], it.id.Clock)
+			d.integrate(it)
+			progress = true
+		}
+	}
+	return progress
+}
+
+func (d *Doc) missingDependency(it *item) (uint64, bool) {
+	if it.gc {
+		return 0, false
+	}
+	for _, id := range []*ID{it.origin, it.rightOrigin, it.parentID} {
+		if id != nil && id.Clock >= d.state(id.Client) {
+			return id.Client, true
+		}
+	}
+	return 0, false
+}
+
+func (d *Doc) integrate(it *item) {
+	d.structs[it.id.Client] = append(d.structs[it.id.Client], it)
+	if it.gc {
+		return
+	}
+
+	if it.origin != nil {
+		it.left = d.findEndingAt(*it.origin)
+	}
+	if it.rightOrigin != nil {
+		it.right = d.findStartingAt(*it.rightOrigin)
+	}
+
+	switch {
+	case (it.left != nil && it.left.gc) || (it.right != nil && it.right.gc):
+		it.parent = nil
+	case it.hasKey:
+		it.parent = d.root(it.parentKey)
+	case it.parentID != nil:
+		parent := d.find(*it.parentID)
+		if parent != nil && !parent.gc && parent.content.ref == refType {
+			it.parent = parent.content.typ
+		}
+	default:
+		if it.left != nil {
+			it.parent = it.left.parent
+			it.parentSub, it.hasSub = it.left.parentSub, it.left.hasSub
+		}
+		if it.right != nil {
+			it.parent = it.right.parent
+			it.parentSub, it.hasSub = it.right.parentSub, it.right.hasSub
+		}
+	}
+
+	// Structs without a parent belong to a garbage collected type
+	if it.parent == nil {
+		it.gc = true
+		it.left, it.right = nil, nil
+		return
+	}
+
+	d.resolveConflicts(it)
+
+	if it.left != nil {
+		it.right = it.left.right
+		it.left.right = it
+	} else {
+		var right *item
+		if it.hasSub {
+			right = it.parent.entries[it.parentSub]
+			for right != nil && right.left != nil {
+				right = right.left
+			}
+		} else {
+			right = it.parent.start
+			it.parent.start = it
+		}
+		it.right = right
+	}
+
+	if it.right != nil {
+		it.right.left = it
+	} else if it.hasSub {
+		it.parent.entries[it.parentSub] = it
+		if it.left != nil {
+			it.left.deleted = true
+		}
+	}
+
+	if it.content.ref == refType {
+		it.content.typ.item = it
+	}
+	if it.content.ref == refDeleted ||
+		(it.parent.item != nil && it.parent.item.deleted) ||
+		(it.hasSub && it.right != nil) {
+		it.deleted = true
+	}
+}
+
+// resolveConflicts finds the left neighbour of an item that was inserted
+// concurrently with other items between the same origins (YATA)
+func (d *Doc) resolveConflicts(it *item) {
+	if !((it.left == nil && (it.right == nil || it.right.left != nil)) ||
+		(it.left != nil && it.left.right != it.right)) {
+		return
+	}
+
+	left := it.left
+	var o *item
+	if left != nil {
+		o = left.right
+	} else if it.hasSub {
+		o = it.parent.entries[it.parentSub]
+		for o != nil && o.left != nil {
+			o = o.left
+		}
+	} else {
+		o = it.parent.start
+	}
+
+	conflicting := make(map[*item]bool)
+	beforeOrigin := make(map[*item]bool)
+	for o != nil && o != it.right {
+		beforeOrigin[o] = true
+		conflicting[o] = true
+
+		if sameID(it.origin, o.origin) {
+			if o.id.Client < it.id.Client {
+				left = o
+				clear(conflicting)
+			} else if sameID(it.rightOrigin, o.rightOrigin) {
+				break
+			}
+		} else if origin := d.originItem(o); origin != nil && beforeOrigin[origin] {
+			if !conflicting[origin] {
+				left = o
+				clear(conflicting)
+			}
+		} else {
+			break
+		}
+		o = o.right
+	}
+	it.left = left
+}
+
+func (d *Doc) originItem(it *item) *item {
+	if it.origin == nil {
+		return nil
+	}
+	return d.find(*it.origin)
+}
+
+func (d *Doc) applyDeletes() {
+	remaining := []deleteRange{}
+	for _, r := range d.deletes {
+		state := d.state(r.client)
+		end := r.clock + r.length
+		structs := d.structs[r.client]
+		for i := d.index(ID{Client: r.client, Clock: r.clock}); i < len(structs) && structs[i].id.Clock < end; i++ {
+			structs[i].deleted = true
+		}
+		if end > state {
+			start := max(r.clock, state)
+			remaining = append(remaining, deleteRange{client: r.client, clock: start, length: end - start})
+		}
+	}
+	d.deletes = remaining
+}
+
+func sameID(a *ID, b *ID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
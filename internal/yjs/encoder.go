@@ -0,0 +1,155 @@
+package yjs
+
+import (
+	"cmp"
+	"encoding/binary"
+	"math"
+	"slices"
+)
+
+// encoder writes the lib0 primitives, the counterpart of decoder
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) writeUint8(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) writeVarUint(num uint64) {
+	for num > 0x7f {
+		e.buf = append(e.buf, byte(num&0x7f)|0x80)
+		num >>= 7
+	}
+	e.buf = append(e.buf, byte(num))
+}
+
+func (e *encoder) writeVarInt(num int64) {
+	b := byte(0)
+	if num < 0 {
+		num = -num
+		b = 0x40
+	}
+	b |= byte(num & 0x3f)
+	num >>= 6
+	if num > 0 {
+		b |= 0x80
+	}
+	e.buf = append(e.buf, b)
+
+	for num > 0 {
+		b = byte(num & 0x7f)
+		num >>= 7
+		if num > 0 {
+			b |= 0x80
+		}
+		e.buf = append(e.buf, b)
+	}
+}
+
+func (e *encoder) writeVarBytes(b []byte) {
+	e.writeVarUint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeVarString(s string) {
+	e.writeVarBytes([]byte(s))
+}
+
+func (e *encoder) writeID(id ID) {
+	e.writeVarUint(id.Client)
+	e.writeVarUint(id.Clock)
+}
+
+// writeDeleteSet writes the ranges grouped by client, ranges of a client
+// are sorted and adjacent ranges are merged like Yjs does
+func (e *encoder) writeDeleteSet(deletes []deleteRange) {
+	byClient := map[uint64][]deleteRange{}
+	for _, r := range deletes {
+		byClient[r.client] = append(byClient[r.client], r)
+	}
+	clients := []uint64{}
+	for client := range byClient {
+		clients = append(clients, client)
+	}
+	slices.Sort(clients)
+	slices.Reverse(clients)
+
+	e.writeVarUint(uint64(len(clients)))
+	for _, client := range clients {
+		ranges := byClient[client]
+		slices.SortFunc(ranges, func(a, b deleteRange) int {
+			return cmp.Compare(a.clock, b.clock)
+		})
+		merged := []deleteRange{ranges[0]}
+		for _, r := range ranges[1:] {
+			last := &merged[len(merged)-1]
+			if r.clock <= last.clock+last.length {
+				last.length = max(last.length, r.clock+r.length-last.clock)
+			} else {
+				merged = append(merged, r)
+			}
+		}
+
+		e.writeVarUint(client)
+		e.writeVarUint(uint64(len(merged)))
+		for _, r := range merged {
+			e.writeVarUint(r.clock)
+			e.writeVarUint(r.length)
+		}
+	}
+}
+
+// writeAny writes a value the way lib0 writeAny does, integers that fit in
+// 31 bits are written as var ints like numbers in javascript
+func (e *encoder) writeAny(value any) {
+	switch v := value.(type) {
+	case nil:
+		e.writeUint8(126)
+	case bool:
+		if v {
+			e.writeUint8(120)
+		} else {
+			e.writeUint8(121)
+		}
+	case int:
+		e.writeNumber(float64(v))
+	case int32:
+		e.writeNumber(float64(v))
+	case int64:
+		e.writeNumber(float64(v))
+	case float64:
+		e.writeNumber(v)
+	case string:
+		e.writeUint8(119)
+		e.writeVarString(v)
+	case map[string]any:
+		e.writeUint8(118)
+		e.writeVarUint(uint64(len(v)))
+		for _, key := range sortedKeys(v) {
+			e.writeVarString(key)
+			e.writeAny(v[key])
+		}
+	case []any:
+		e.writeUint8(117)
+		e.writeVarUint(uint64(len(v)))
+		for _, item := range v {
+			e.writeAny(item)
+		}
+	case []byte:
+		e.writeUint8(116)
+		e.writeVarBytes(v)
+	default:
+		e.writeUint8(127)
+	}
+}
+
+func (e *encoder) writeNumber(num float64) {
+	if num == math.Trunc(num) && math.Abs(num) <= math.MaxInt32 {
+		e.writeUint8(125)
+		e.writeVarInt(int64(num))
+		return
+	}
+	e.writeUint8(123)
+	e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(num))
+}
@@ -0,0 +1,234 @@
+package yjs
+
+import (
+	"encoding/json"
+	"unicode/utf16"
+)
+
+// EncodeRestore encodes an update of client that turns the content of
+// current into the content of target, an earlier state of the same document.
+// History is kept the way the undo manager of Yjs keeps it: content added
+// since target is deleted and content removed since target is inserted again
+// as new structs. Nil is returned when the content is already the same.
+func EncodeRestore(current *Doc, target *Doc, client uint64) []byte {
+	r := &restorer{current: current, target: target, w: &structWriter{client: client}}
+	for _, name := range current.Roots() {
+		r.diff(current.roots[name], target.roots[name], parent{key: name})
+	}
+
+	if r.w.count == 0 && len(r.deletes) == 0 {
+		return nil
+	}
+	return r.w.update(r.deletes)
+}
+
+type restorer struct {
+	current *Doc
+	target  *Doc
+	w       *structWriter
+	deletes []deleteRange
+}
+
+// diff restores the content of the current type to that of the same type in
+// the target, which is nil when the type did not exist yet
+func (r *restorer) diff(current *Type, target *Type, p parent) {
+	var left *item
+	run := []*item{}
+	for it := current.start; it != nil; it = it.right {
+		inTarget := r.inTarget(it)
+		if it.deleted && inTarget {
+			run = append(run, it)
+			continue
+		}
+
+		r.insertAgain(p, left, run, it)
+		run = run[:0]
+
+		switch {
+		case !it.deleted && !inTarget:
+			r.delete(it)
+		case !it.deleted && it.content.ref == refType:
+			r.diff(it.content.typ, r.target.find(it.id).content.typ, parent{id: &it.id})
+		}
+		left = it
+	}
+	r.insertAgain(p, left, run, nil)
+
+	keys := sortedKeys(current.entries)
+	for _, key := range keys {
+		it := current.entries[key]
+		var previous *item
+		if target != nil {
+			previous = target.entries[key]
+		}
+
+		switch {
+		case previous != nil && !previous.deleted && previous.id == it.id && !it.deleted:
+			if it.content.ref == refType {
+				r.diff(it.content.typ, previous.content.typ, parent{id: &it.id})
+			}
+		case previous != nil && !previous.deleted:
+			// Setting the key again overwrites the current value
+			r.copy(&it.id, nil, p, key, []*item{previous})
+		case !it.deleted:
+			r.delete(it)
+		}
+	}
+}
+
+// inTarget reports whether the item is part of the content of the target
+func (r *restorer) inTarget(it *item) bool {
+	previous := r.target.find(it.id)
+	return previous != nil && !previous.gc && !previous.deleted
+}
+
+// insertAgain inserts copies of the deleted items of a run between the
+// items around the run, so they end up where they were in the target
+func (r *restorer) insertAgain(p parent, left *item, run []*item, right *item) {
+	if len(run) == 0 {
+		return
+	}
+
+	var origin, rightOrigin *ID
+	if left != nil {
+		origin = &left.id
+	}
+	if right != nil {
+		rightOrigin = &right.id
+	}
+
+	items := make([]*item, 0, len(run))
+	for _, it := range run {
+		items = append(items, r.target.find(it.id))
+	}
+	r.copy(origin, rightOrigin, p, "", items)
+}
+
+// copy writes the content of target items as new structs, consecutive
+// string items are written as one struct. Nested types are copied with
+// their content in the target.
+func (r *restorer) copy(origin *ID, rightOrigin *ID, p parent, sub string, items []*item) {
+	for i := 0; i < len(items); i++ {
+		it := items[i]
+
+		switch it.content.ref {
+		case refString:
+			units := []uint16{it.content.unit}
+			for i+1 < len(items) && items[i+1].content.ref == refString {
+				i++
+				units = append(units, items[i].content.unit)
+			}
+			text := string(utf16.Decode(units))
+			last := r.w.itemBetween(origin, rightOrigin, p, sub, refString, uint64(len(utf16.Encode([]rune(text)))), func(e *encoder) {
+				e.writeVarString(text)
+			})
+			origin = &last
+		case refType:
+			id := r.w.itemBetween(origin, rightOrigin, p, sub, refType, 1, func(e *encoder) {
+				writeTypeRef(e, it.content.typ)
+			})
+			r.copyType(it.content.typ, parent{id: &id})
+			origin = &id
+		default:
+			last := r.w.itemBetween(origin, rightOrigin, p, sub, it.content.ref, 1, func(e *encoder) {
+				writeContent(e, it.content)
+			})
+			origin = &last
+		}
+	}
+}
+
+// copyType writes the content a target type has in the target into the new
+// type with the given parent
+func (r *restorer) copyType(typ *Type, p parent) {
+	for _, key := range sortedKeys(typ.entries) {
+		if it := typ.entries[key]; !it.deleted {
+			r.copy(nil, nil, p, key, []*item{it})
+		}
+	}
+
+	items := []*item{}
+	for it := typ.start; it != nil; it = it.right {
+		if !it.deleted {
+			items = append(items, it)
+		}
+	}
+	r.copy(nil, nil, p, "", items)
+}
+
+// delete deletes the item together with the content of a nested type, the
+// same structs Yjs adds to the delete set when a type is deleted
+func (r *restorer) delete(it *item) {
+	r.deletes = append(r.deletes, deleteRange{client: it.id.Client, clock: it.id.Clock, length: 1})
+	if it.content.ref != refType {
+		return
+	}
+
+	typ := it.content.typ
+	for child := typ.start; child != nil; child = child.right {
+		if !child.deleted {
+			r.delete(child)
+		}
+	}
+	for _, entry := range typ.entries {
+		for ; entry != nil; entry = entry.left {
+			if !entry.deleted {
+				r.delete(entry)
+			}
+		}
+	}
+}
+
+func writeTypeRef(e *encoder, typ *Type) {
+	switch typ.kind {
+	case KindArray:
+		e.writeVarUint(typeArray)
+	case KindMap:
+		e.writeVarUint(typeMap)
+	case KindText:
+		e.writeVarUint(typeText)
+	case KindXmlElement:
+		e.writeVarUint(typeXmlElement)
+		e.writeVarString(typ.name)
+	case KindXmlFragment:
+		e.writeVarUint(typeXmlFragment)
+	case KindXmlHook:
+		e.writeVarUint(typeXmlHook)
+		e.writeVarString(typ.name)
+	case KindXmlText:
+		e.writeVarUint(typeXmlText)
+	}
+}
+
+// writeContent writes content of a single clock tick, the counterpart of
+// decodeContent for everything except strings and types
+func writeContent(e *encoder, c content) {
+	switch c.ref {
+	case refJSON:
+		e.writeVarUint(1)
+		e.writeVarString(marshalJSON(c.value))
+	case refBinary:
+		e.writeVarBytes(c.value.([]byte))
+	case refEmbed:
+		e.writeVarString(marshalJSON(c.value))
+	case refFormat:
+		e.writeVarString(c.key)
+		e.writeVarString(marshalJSON(c.value))
+	case refAny:
+		e.writeVarUint(1)
+		e.writeAny(c.value)
+	case refDoc:
+		e.writeVarString(c.value.(string))
+		e.writeAny(map[string]any{})
+	case refDeleted:
+		e.writeVarUint(1)
+	}
+}
+
+func marshalJSON(value any) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "null"
+	}
+	return string(raw)
+}
@@ -0,0 +1,279 @@
+package yjs
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
+
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindArray
+	KindMap
+	KindText
+	KindXmlElement
+	KindXmlFragment
+	KindXmlHook
+	KindXmlText
+)
+
+// Type is a shared type of the document. Top level types carry no type
+// information in updates, their kind is inferred from their content.
+type Type struct {
+	kind    Kind
+	name    string
+	item    *item
+	start   *item
+	entries map[string]*item
+}
+
+// Delta is a single insert of a text, the same format Y.Text.toDelta
+// returns. Insert is a string, an embedded value or a nested *Type.
+type Delta struct {
+	Insert     any            `json:"insert"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+func (t *Type) Kind() Kind {
+	return t.kind
+}
+
+// Name is the node name of an xml element or the name of an xml hook
+func (t *Type) Name() string {
+	return t.name
+}
+
+func (t *Type) inferKind() {
+	if t.kind != KindUnknown {
+		return
+	}
+
+	for it := t.start; it != nil; it = it.right {
+		switch it.content.ref {
+		case refString, refFormat, refEmbed:
+			t.kind = KindText
+			return
+		case refType:
+			switch it.content.typ.kind {
+			case KindXmlElement, KindXmlText:
+				t.kind = KindXmlFragment
+				return
+			}
+		}
+	}
+
+	if t.start != nil {
+		t.kind = KindArray
+	} else if len(t.entries) > 0 {
+		t.kind = KindMap
+	}
+}
+
+// Delta returns the formatted content of a text or xml text
+func (t *Type) Delta() []Delta {
+	ops := []Delta{}
+	attributes := map[string]any{}
+
+	var text []uint16
+	flush := func() {
+		if len(text) == 0 {
+			return
+		}
+		ops = append(ops, Delta{Insert: string(utf16.Decode(text)), Attributes: copyAttributes(attributes)})
+		text = nil
+	}
+
+	for it := t.start; it != nil; it = it.right {
+		if it.deleted {
+			continue
+		}
+
+		switch it.content.ref {
+		case refString:
+			text = append(text, it.content.unit)
+		case refEmbed:
+			flush()
+			ops = append(ops, Delta{Insert: it.content.value, Attributes: copyAttributes(attributes)})
+		case refType:
+			flush()
+			ops = append(ops, Delta{Insert: it.content.typ, Attributes: copyAttributes(attributes)})
+		case refFormat:
+			flush()
+			if it.content.value == nil {
+				delete(attributes, it.content.key)
+			} else {
+				attributes[it.content.key] = it.content.value
+			}
+		}
+	}
+	flush()
+
+	return ops
+}
+
+// String returns the unformatted text of a text type, for xml types it is
+// the text of all descendants
+func (t *Type) String() string {
+	switch t.kind {
+	case KindXmlElement, KindXmlFragment:
+		var builder strings.Builder
+		for _, child := range t.Children() {
+			builder.WriteString(child.String())
+		}
+		return builder.String()
+	}
+
+	var text []uint16
+	for it := t.start; it != nil; it = it.right {
+		if !it.deleted && it.content.ref == refString {
+			text = append(text, it.content.unit)
+		}
+	}
+	return string(utf16.Decode(text))
+}
+
+// Children returns the nodes of an xml fragment or element
+func (t *Type) Children() []*Type {
+	children := []*Type{}
+	for it := t.start; it != nil; it = it.right {
+		if !it.deleted && it.content.ref == refType {
+			children = append(children, it.content.typ)
+		}
+	}
+	return children
+}
+
+// Values returns the content of an array, nested types are returned as *Type
+func (t *Type) Values() []any {
+	values := []any{}
+	for it := t.start; it != nil; it = it.right {
+		if it.deleted {
+			continue
+		}
+		switch it.content.ref {
+		case refJSON, refAny, refBinary, refEmbed, refDoc:
+			values = append(values, it.content.value)
+		case refType:
+			values = append(values, it.content.typ)
+		}
+	}
+	return values
+}
+
+// Entries returns the content of a map, nested types are returned as *Type
+func (t *Type) Entries() map[string]any {
+	entries := map[string]any{}
+	for key, it := range t.entries {
+		if it.deleted {
+			continue
+		}
+		entries[key] = it.content.value
+		if it.content.ref == refType {
+			entries[key] = it.content.typ
+		}
+	}
+	return entries
+}
+
+// Attributes returns the attributes of an xml element or xml text
+func (t *Type) Attributes() map[string]any {
+	return t.Entries()
+}
+
+// ToJSON returns the content the same way toJSON does in Yjs: texts become
+// strings, arrays and maps are converted recursively and xml types become
+// their xml string
+func (t *Type) ToJSON() any {
+	switch t.kind {
+	case KindText:
+		return t.String()
+	case KindArray:
+		values := t.Values()
+		for i, value := range values {
+			values[i] = toJSON(value)
+		}
+		return values
+	case KindMap, KindXmlHook:
+		entries := t.Entries()
+		for key, value := range entries {
+			entries[key] = toJSON(value)
+		}
+		return entries
+	case KindXmlElement, KindXmlFragment, KindXmlText:
+		var builder strings.Builder
+		t.writeXML(&builder)
+		return builder.String()
+	}
+	return nil
+}
+
+func toJSON(value any) any {
+	if typ, ok := value.(*Type); ok {
+		return typ.ToJSON()
+	}
+	return value
+}
+
+// writeXML writes the xml string Yjs returns from toString, formatting of
+// xml text is written as nested elements named after the attribute
+func (t *Type) writeXML(builder *strings.Builder) {
+	switch t.kind {
+	case KindXmlText:
+		for _, op := range t.Delta() {
+			names := sortedKeys(op.Attributes)
+			for _, name := range names {
+				builder.WriteString("<" + name)
+				if attributes, ok := op.Attributes[name].(map[string]any); ok {
+					for _, key := range sortedKeys(attributes) {
+						fmt.Fprintf(builder, ` %s="%v"`, key, attributes[key])
+					}
+				}
+				builder.WriteString(">")
+			}
+			if text, ok := op.Insert.(string); ok {
+				builder.WriteString(text)
+			} else if typ, ok := op.Insert.(*Type); ok {
+				typ.writeXML(builder)
+			}
+			for i := len(names) - 1; i >= 0; i-- {
+				builder.WriteString("</" + names[i] + ">")
+			}
+		}
+	case KindXmlElement:
+		name := strings.ToLower(t.name)
+		attributes := t.Attributes()
+		builder.WriteString("<" + name)
+		for _, key := range sortedKeys(attributes) {
+			fmt.Fprintf(builder, ` %s="%v"`, key, toJSON(attributes[key]))
+		}
+		builder.WriteString(">")
+		for _, child := range t.Children() {
+			child.writeXML(builder)
+		}
+		builder.WriteString("</" + name + ">")
+	case KindXmlFragment:
+		for _, child := range t.Children() {
+			child.writeXML(builder)
+		}
+	}
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func copyAttributes(attributes map[string]any) map[string]any {
+	if len(attributes) == 0 {
+		return nil
+	}
+	return maps.Clone(attributes)
+}
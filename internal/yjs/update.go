@@ -0,0 +1,441 @@
+package yjs
+
+import (
+	"encoding/json"
+	"math"
+	"unicode/utf16"
+)
+
+// Content refs as written in the lower five bits of the struct info byte
+const (
+	refGC      = 0
+	refDeleted = 1
+	refJSON    = 2
+	refBinary  = 3
+	refString  = 4
+	refEmbed   = 5
+	refFormat  = 6
+	refType    = 7
+	refAny     = 8
+	refDoc     = 9
+	refSkip    = 10
+)
+
+// Type refs of ContentType
+const (
+	typeArray       = 0
+	typeMap         = 1
+	typeText        = 2
+	typeXmlElement  = 3
+	typeXmlFragment = 4
+	typeXmlHook     = 5
+	typeXmlText     = 6
+)
+
+const (
+	infoOrigin      = 0x80
+	infoRightOrigin = 0x40
+	infoParentSub   = 0x20
+	infoContentRef  = 0x1f
+)
+
+type ID struct {
+	Client uint64
+	Clock  uint64
+}
+
+// content is the content of a single clock tick. Items with content are
+// split per tick while decoding, garbage collected and deleted runs carry no
+// content and stay a single item so their length costs no memory.
+type content struct {
+	ref   byte
+	unit  uint16
+	key   string
+	value any
+	typ   *Type
+}
+
+// item is a struct of the document, length is the number of clock ticks it
+// covers, only runs of garbage collected or deleted content are longer than one
+type item struct {
+	id          ID
+	length      uint64
+	origin      *ID
+	rightOrigin *ID
+	parentKey   string
+	hasKey      bool
+	parentID    *ID
+	parentSub   string
+	hasSub      bool
+	content     content
+	gc          bool
+
+	parent  *Type
+	left    *item
+	right   *item
+	deleted bool
+}
+
+type deleteRange struct {
+	client uint64
+	clock  uint64
+	length uint64
+}
+
+type update struct {
+	items   []*item
+	deletes []deleteRange
+}
+
+func decodeUpdate(buf []byte) (update, error) {
+	d := &decoder{buf: buf}
+	var u update
+
+	groups, err := d.readVarUint()
+	if err != nil {
+		return u, err
+	}
+	for i := uint64(0); i < groups; i++ {
+		structs, err := d.readVarUint()
+		if err != nil {
+			return u, err
+		}
+		client, err := d.readVarUint()
+		if err != nil {
+			return u, err
+		}
+		clock, err := d.readVarUint()
+		if err != nil {
+			return u, err
+		}
+
+		for j := uint64(0); j < structs; j++ {
+			items, length, err := decodeStruct(d, ID{Client: client, Clock: clock})
+			if err != nil {
+				return u, err
+			}
+			u.items = append(u.items, items...)
+			clock += length
+		}
+	}
+
+	u.deletes, err = decodeDeleteSet(d)
+	return u, err
+}
+
+// decodeStruct returns the struct as one item per clock tick together with
+// the length of the struct. Garbage collected and deleted runs are returned
+// as a single item, skipped ranges produce no items.
+func decodeStruct(d *decoder, id ID) ([]*item, uint64, error) {
+	info, err := d.readUint8()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch info & infoContentRef {
+	case refGC:
+		length, err := decodeRunLength(d, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return []*item{{id: id, length: length, gc: true}}, length, nil
+	case refSkip:
+		length, err := d.readVarUint()
+		return nil, length, err
+	}
+
+	base := item{id: id}
+	if info&infoOrigin > 0 {
+		origin, err := decodeID(d)
+		if err != nil {
+			return nil, 0, err
+		}
+		base.origin = &origin
+	}
+	if info&infoRightOrigin > 0 {
+		rightOrigin, err := decodeID(d)
+		if err != nil {
+			return nil, 0, err
+		}
+		base.rightOrigin = &rightOrigin
+	}
+
+	if info&(infoOrigin|infoRightOrigin) == 0 {
+		isKey, err := d.readVarUint()
+		if err != nil {
+			return nil, 0, err
+		}
+		if isKey == 1 {
+			base.parentKey, err = d.readVarString()
+			base.hasKey = true
+		} else {
+			var parentID ID
+			parentID, err = decodeID(d)
+			base.parentID = &parentID
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if info&infoParentSub > 0 {
+			base.parentSub, err = d.readVarString()
+			if err != nil {
+				return nil, 0, err
+			}
+			base.hasSub = true
+		}
+	}
+
+	if info&infoContentRef == refDeleted {
+		base.length, err = decodeRunLength(d, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		base.content = content{ref: refDeleted}
+		return []*item{&base}, base.length, nil
+	}
+
+	contents, err := decodeContent(d, info&infoContentRef)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]*item, 0, len(contents))
+	for i, c := range contents {
+		unit := base
+		unit.id = ID{Client: id.Client, Clock: id.Clock + uint64(i)}
+		unit.length = 1
+		unit.content = c
+		if i > 0 {
+			unit.origin = &ID{Client: id.Client, Clock: unit.id.Clock - 1}
+		}
+		items = append(items, &unit)
+	}
+	return items, uint64(len(contents)), nil
+}
+
+// decodeRunLength reads the length of a garbage collected or deleted run,
+// which has to cover at least one tick without overflowing the clock
+func decodeRunLength(d *decoder, id ID) (uint64, error) {
+	length, err := d.readVarUint()
+	if err != nil {
+		return 0, err
+	}
+	if length == 0 || length > math.MaxUint64-id.Clock {
+		return 0, ErrInvalidUpdate
+	}
+	return length, nil
+}
+
+func decodeID(d *decoder) (ID, error) {
+	client, err := d.readVarUint()
+	if err != nil {
+		return ID{}, err
+	}
+	clock, err := d.readVarUint()
+	return ID{Client: client, Clock: clock}, err
+}
+
+func decodeContent(d *decoder, ref byte) ([]content, error) {
+	switch ref {
+	case refJSON:
+		length, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		contents := []content{}
+		for i := uint64(0); i < length; i++ {
+			raw, err := d.readVarString()
+			if err != nil {
+				return nil, err
+			}
+			var value any
+			if raw != "undefined" {
+				err = json.Unmarshal([]byte(raw), &value)
+				if err != nil {
+					return nil, ErrInvalidUpdate
+				}
+			}
+			contents = append(contents, content{ref: refJSON, value: value})
+		}
+		return contents, nil
+	case refBinary:
+		value, err := d.readVarBytes()
+		if err != nil {
+			return nil, err
+		}
+		return []content{{ref: refBinary, value: value}}, nil
+	case refString:
+		value, err := d.readVarString()
+		if err != nil {
+			return nil, err
+		}
+		units := utf16.Encode([]rune(value))
+		contents := make([]content, 0, len(units))
+		for _, unit := range units {
+			contents = append(contents, content{ref: refString, unit: unit})
+		}
+		return contents, nil
+	case refEmbed:
+		value, err := decodeJSON(d)
+		if err != nil {
+			return nil, err
+		}
+		return []content{{ref: refEmbed, value: value}}, nil
+	case refFormat:
+		key, err := d.readVarString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeJSON(d)
+		if err != nil {
+			return nil, err
+		}
+		return []content{{ref: refFormat, key: key, value: value}}, nil
+	case refType:
+		typ, err := decodeType(d)
+		if err != nil {
+			return nil, err
+		}
+		return []content{{ref: refType, typ: typ}}, nil
+	case refAny:
+		length, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		contents := []content{}
+		for i := uint64(0); i < length; i++ {
+			value, err := d.readAny()
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, content{ref: refAny, value: value})
+		}
+		return contents, nil
+	case refDoc:
+		guid, err := d.readVarString()
+		if err != nil {
+			return nil, err
+		}
+		_, err = d.readAny()
+		if err != nil {
+			return nil, err
+		}
+		return []content{{ref: refDoc, value: guid}}, nil
+	default:
+		return nil, ErrInvalidUpdate
+	}
+}
+
+func decodeJSON(d *decoder) (any, error) {
+	raw, err := d.readVarString()
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	err = json.Unmarshal([]byte(raw), &value)
+	if err != nil {
+		return nil, ErrInvalidUpdate
+	}
+	return value, nil
+}
+
+func decodeType(d *decoder) (*Type, error) {
+	ref, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+
+	typ := &Type{entries: make(map[string]*item)}
+	switch ref {
+	case typeArray:
+		typ.kind = KindArray
+	case typeMap:
+		typ.kind = KindMap
+	case typeText:
+		typ.kind = KindText
+	case typeXmlElement:
+		typ.kind = KindXmlElement
+		typ.name, err = d.readVarString()
+	case typeXmlFragment:
+		typ.kind = KindXmlFragment
+	case typeXmlHook:
+		typ.kind = KindXmlHook
+		typ.name, err = d.readVarString()
+	case typeXmlText:
+		typ.kind = KindXmlText
+	default:
+		return nil, ErrInvalidUpdate
+	}
+	return typ, err
+}
+
+// UpdateStateVector returns the next clock of every client with structs in
+// the update, the state vector a replica has after applying only the update
+func UpdateStateVector(buf []byte) (map[uint64]uint64, error) {
+	u, err := decodeUpdate(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	vector := make(map[uint64]uint64)
+	for _, it := range u.items {
+		vector[it.id.Client] = max(vector[it.id.Client], it.id.Clock+it.length)
+	}
+	return vector, nil
+}
+
+// DecodeStateVector decodes a state vector encoded with encodeStateVector,
+// the format of documents.state_vector
+func DecodeStateVector(buf []byte) (map[uint64]uint64, error) {
+	d := &decoder{buf: buf}
+	vector := make(map[uint64]uint64)
+
+	clients, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < clients; i++ {
+		client, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		clock, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		vector[client] = clock
+	}
+	return vector, nil
+}
+
+func decodeDeleteSet(d *decoder) ([]deleteRange, error) {
+	clients, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+
+	deletes := []deleteRange{}
+	for i := uint64(0); i < clients; i++ {
+		client, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		ranges, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		for j := uint64(0); j < ranges; j++ {
+			clock, err := d.readVarUint()
+			if err != nil {
+				return nil, err
+			}
+			length, err := d.readVarUint()
+			if err != nil {
+				return nil, err
+			}
+			deletes = append(deletes, deleteRange{client: client, clock: clock, length: length})
+		}
+	}
+	return deletes, nil
+}
@@ -40,7 +40,10 @@ curl -X POST http://localhost:8083/connectors -H 'Content-Type: application/json
     "name": "elasticsearch-sink",
     "value.converter": "org.apache.kafka.connect.json.JsonConverter",
     "value.converter.schemas.enable": "false",
-    "behaviour.on.null.vallues": "DELETE"
+    "behaviour.on.null.vallues": "DELETE",
+    "transforms": "unwrap",
+    "transforms.unwrap.type": "org.apache.kafka.connect.transforms.ExtractField$Value",
+    "transforms.unwrap.field": "data"
   }
 }'
 
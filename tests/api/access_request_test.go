@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestAccessRequests(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	editor := testApp.GetTestUser()
+	testApp.AddTestContributor(testDoc.ID, editor.ID)
+	approvedUser := testApp.GetTestUser()
+	deniedUser := testApp.GetTestUser()
+
+	requestPath := "/document/" + testDoc.ID.String() + "/access-request"
+
+	cases := []struct {
+		name             string
+		userID           uuid.UUID
+		path             string
+		body             string
+		outputStatusCode int
+	}{
+		{name: "unknown document", userID: approvedUser.ID, path: "/document/" + uuid.NewString() + "/access-request", body: `{}`, outputStatusCode: 404},
+		{name: "contributor already has access", userID: editor.ID, path: requestPath, body: `{}`, outputStatusCode: 409},
+		{name: "owner role", userID: approvedUser.ID, path: requestPath, body: `{"role": "owner"}`, outputStatusCode: 400},
+		{name: "message too long", userID: approvedUser.ID, path: requestPath, body: `{"message": "` + strings.Repeat("a", routes.MAX_ACCESS_REQUEST_MESSAGE_LENGTH+1) + `"}`, outputStatusCode: 400},
+		{name: "multibyte message within limit", userID: approvedUser.ID, path: requestPath, body: `{"message": "` + strings.Repeat("é", routes.MAX_ACCESS_REQUEST_MESSAGE_LENGTH) + `"}`, outputStatusCode: 202},
+		{name: "request", userID: approvedUser.ID, path: requestPath, body: `{"role": "viewer"}`, outputStatusCode: 202},
+		{name: "request again", userID: approvedUser.ID, path: requestPath, body: `{"role": "commenter", "message": "I need to leave comments"}`, outputStatusCode: 202},
+		{name: "other user requests", userID: deniedUser.ID, path: requestPath, body: `{}`, outputStatusCode: 202},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, testCase.path, strings.NewReader(testCase.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testCase.userID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+		})
+	}
+
+	requests := map[uuid.UUID]routes.AccessRequestResponse{}
+	t.Run("owner lists pending requests", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, requestPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(owner.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+
+		var response []routes.AccessRequestResponse
+		err = json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if len(response) != 2 {
+			t.Fatalf("expected %d access requests got %d", 2, len(response))
+		}
+		for _, request := range response {
+			requests[request.UserID] = request
+		}
+		if requests[approvedUser.ID].Role != routes.ROLE_COMMENTER {
+			t.Errorf("expected updated role %s got %s", routes.ROLE_COMMENTER, requests[approvedUser.ID].Role)
+		}
+	})
+
+	decide := func(userID uuid.UUID, requestID uuid.UUID, action string) int {
+		req, err := http.NewRequest(http.MethodPost, requestPath+"/"+requestID.String()+"/"+action, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	t.Run("editor can not approve", func(t *testing.T) {
+		status := decide(editor.ID, requests[approvedUser.ID].ID, "approve")
+		if status != 403 {
+			t.Errorf("expected %d got %d", 403, status)
+		}
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		status := decide(owner.ID, requests[approvedUser.ID].ID, "approve")
+		if status != 200 {
+			t.Fatalf("expected %d got %d", 200, status)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/document/"+testDoc.ID.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(approvedUser.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		var document routes.DocumentResponse
+		err = json.NewDecoder(rr.Body).Decode(&document)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if role := document.RoleOf(approvedUser.ID); role != routes.ROLE_COMMENTER {
+			t.Errorf("expected role %s got %s", routes.ROLE_COMMENTER, role)
+		}
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		status := decide(owner.ID, requests[deniedUser.ID].ID, "deny")
+		if status != 200 {
+			t.Fatalf("expected %d got %d", 200, status)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "/document/"+testDoc.ID.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(deniedUser.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+		if rr.Code != 404 {
+			t.Errorf("expected %d got %d", 404, rr.Code)
+		}
+	})
+
+	t.Run("denied user can not request again", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, requestPath, strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(deniedUser.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+		if rr.Code != 409 {
+			t.Errorf("expected %d got %d", 409, rr.Code)
+		}
+	})
+
+	t.Run("decided requests can not be decided again", func(t *testing.T) {
+		status := decide(owner.ID, requests[deniedUser.ID].ID, "approve")
+		if status != 404 {
+			t.Errorf("expected %d got %d", 404, status)
+		}
+	})
+}
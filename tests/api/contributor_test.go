@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
 	"github.com/rejdeboer/multiplayer-server/internal/routes"
 	"github.com/rejdeboer/multiplayer-server/tests/helpers"
 )
@@ -40,8 +43,18 @@ func TestCreateContributor(t *testing.T) {
 		testApp.Handler.ServeHTTP(rr, req)
 
 		status := rr.Result().StatusCode
-		if status != 202 {
-			t.Errorf("expected %d got %d", 202, rr.Result().StatusCode)
+		if status != 200 {
+			t.Errorf("expected %d got %d", 200, rr.Result().StatusCode)
+		}
+
+		messages := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, testDocID.String())
+		if len(messages) != 1 {
+			t.Fatalf("expected %d outbox message got %d", 1, len(messages))
+		}
+
+		err = helpers.ValidateEvent(messages[0].Value)
+		if err != nil {
+			t.Errorf("outbox message does not match schema: %v", err)
 		}
 	})
 
@@ -73,3 +86,209 @@ func TestCreateContributor(t *testing.T) {
 		}
 	})
 }
+
+func TestRemoveContributor(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	editor := testApp.GetTestUser()
+	testApp.AddTestContributor(testDoc.ID, editor.ID)
+	outsider := testApp.GetTestUser()
+
+	cases := []struct {
+		name             string
+		userID           uuid.UUID
+		contributorID    uuid.UUID
+		outputStatusCode int
+	}{
+		{name: "editor can not remove others", userID: editor.ID, contributorID: owner.ID, outputStatusCode: 403},
+		{name: "owner can not be removed", userID: owner.ID, contributorID: owner.ID, outputStatusCode: 400},
+		{name: "not a contributor", userID: owner.ID, contributorID: outsider.ID, outputStatusCode: 404},
+		{name: "owner removes editor", userID: owner.ID, contributorID: editor.ID, outputStatusCode: 202},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodDelete,
+				"/document/"+testDoc.ID.String()+"/contributor/"+testCase.contributorID.String(),
+				nil,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testCase.userID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+		})
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/document/"+testDoc.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(editor.ID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	status := rr.Result().StatusCode
+	if status != 404 {
+		t.Errorf("expected removed contributor to lose access; got %d", status)
+	}
+
+	messages := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, testDoc.ID.String())
+	if len(messages) != 1 {
+		t.Fatalf("expected %d outbox message got %d", 1, len(messages))
+	}
+
+	err = helpers.ValidateEvent(messages[0].Value)
+	if err != nil {
+		t.Errorf("outbox message does not match schema: %v", err)
+	}
+}
+
+func TestLeaveDocument(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	viewer := testApp.GetTestUser()
+	testApp.AddTestContributorWithRole(testDoc.ID, viewer.ID, routes.ROLE_VIEWER)
+
+	cases := []struct {
+		name             string
+		userID           uuid.UUID
+		outputStatusCode int
+	}{
+		{name: "owner can not leave", userID: owner.ID, outputStatusCode: 400},
+		{name: "viewer leaves", userID: viewer.ID, outputStatusCode: 202},
+		{name: "viewer already left", userID: viewer.ID, outputStatusCode: 404},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/document/"+testDoc.ID.String()+"/leave", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testCase.userID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+		})
+	}
+}
+
+func TestAddContributorValidation(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	existing := testApp.GetTestUser()
+	testApp.AddTestContributor(testDoc.ID, existing.ID)
+	byEmail := testApp.GetTestUser()
+	byUsername := testApp.GetTestUser()
+
+	cases := []struct {
+		name             string
+		body             string
+		outputStatusCode int
+	}{
+		{name: "unknown user", body: `{"userId": "` + uuid.New().String() + `"}`, outputStatusCode: 404},
+		{name: "unknown email", body: `{"email": "nobody@example.com"}`, outputStatusCode: 404},
+		{name: "no identifier", body: `{"role": "viewer"}`, outputStatusCode: 400},
+		{name: "multiple identifiers", body: `{"email": "` + byEmail.Email + `", "username": "` + byEmail.Username + `"}`, outputStatusCode: 400},
+		{name: "already a contributor", body: `{"userId": "` + existing.ID.String() + `"}`, outputStatusCode: 409},
+		{name: "by email", body: `{"email": "` + byEmail.Email + `", "role": "viewer"}`, outputStatusCode: 200},
+		{name: "by username", body: `{"username": "` + byUsername.Username + `"}`, outputStatusCode: 200},
+		{name: "empty bulk", body: `[]`, outputStatusCode: 400},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPost,
+				"/document/"+testDoc.ID.String()+"/contributor",
+				strings.NewReader(testCase.body),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(owner.ID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+		})
+	}
+}
+
+func TestAddContributorsInBulk(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	existing := testApp.GetTestUser()
+	testApp.AddTestContributorWithRole(testDoc.ID, existing.ID, routes.ROLE_VIEWER)
+	newUser := testApp.GetTestUser()
+
+	bodyBytes, err := json.Marshal([]routes.DocumentContributorCreate{
+		{UserID: existing.ID, Role: routes.ROLE_EDITOR},
+		{Email: newUser.Email, Role: routes.ROLE_COMMENTER},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		"/document/"+testDoc.ID.String()+"/contributor",
+		bytes.NewReader(bodyBytes),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(owner.ID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	status := rr.Result().StatusCode
+	if status != 200 {
+		t.Fatalf("expected %d got %d", 200, status)
+	}
+
+	var response []routes.ContributorAddResult
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	if err != nil {
+		t.Fatalf("error decoding json response: %v", err)
+	}
+
+	if len(response) != 2 {
+		t.Fatalf("expected %d results got %d", 2, len(response))
+	}
+	if response[0].UserID != existing.ID || response[0].Added || response[0].Role != routes.ROLE_VIEWER {
+		t.Errorf("expected existing contributor to be skipped with its viewer role; got %v", response[0])
+	}
+	if response[1].UserID != newUser.ID || !response[1].Added || response[1].Role != routes.ROLE_COMMENTER {
+		t.Errorf("expected new contributor to be added as commenter; got %v", response[1])
+	}
+
+	messages := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, testDoc.ID.String())
+	if len(messages) != 1 {
+		t.Errorf("expected %d outbox message got %d", 1, len(messages))
+	}
+}
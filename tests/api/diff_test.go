@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestDiffVersions(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	editor := testApp.GetTestUser()
+	otherUser := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	testApp.AddTestContributorWithRole(testDoc.ID, editor.ID, routes.ROLE_EDITOR)
+
+	start := time.Now().Add(-time.Hour)
+	testApp.AddTestDocumentUpdateAt(testDoc.ID, owner.ID, helloUpdate, start)
+	testApp.AddTestDocumentUpdateAt(testDoc.ID, editor.ID, worldUpdate, start.Add(2*time.Minute))
+	testApp.AddTestDocumentUpdateAt(testDoc.ID, owner.ID, exclamationUpdate, start.Add(30*time.Minute))
+
+	diffPath := "/document/" + testDoc.ID.String() + "/diff"
+
+	t.Run("attributes insertions to authors", func(t *testing.T) {
+		rr := versionRequest(testApp, http.MethodGet, diffPath+"?from=0&to=2", "", editor.ID)
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+
+		var diff routes.DiffResponse
+		err := json.NewDecoder(rr.Body).Decode(&diff)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+
+		expected := []struct {
+			kind   yjs.ChangeKind
+			text   string
+			author *uuid.UUID
+		}{
+			{kind: yjs.ChangeEqual, text: "hello"},
+			{kind: yjs.ChangeInsert, text: " world", author: &editor.ID},
+			{kind: yjs.ChangeInsert, text: "!", author: &owner.ID},
+		}
+		if len(diff.Changes) != len(expected) {
+			t.Fatalf("expected %d changes got %+v", len(expected), diff.Changes)
+		}
+		for i, change := range diff.Changes {
+			if change.Kind != expected[i].kind || change.Text != expected[i].text {
+				t.Errorf("expected %s %q got %s %q", expected[i].kind, expected[i].text, change.Kind, change.Text)
+			}
+			if (change.Author == nil) != (expected[i].author == nil) ||
+				(change.Author != nil && *change.Author != *expected[i].author) {
+				t.Errorf("expected author %v got %v", expected[i].author, change.Author)
+			}
+		}
+
+		if diff.Insertions != 7 || diff.Deletions != 0 {
+			t.Errorf("expected 7 insertions and 0 deletions got %d and %d", diff.Insertions, diff.Deletions)
+		}
+
+		expectedUnified := "--- " + testDoc.Name + "@0\n+++ " + testDoc.Name + "@2\n@@ -1 +1 @@\n-hello\n+hello world!\n"
+		if diff.Unified != expectedUnified {
+			t.Errorf("expected unified diff %q got %q", expectedUnified, diff.Unified)
+		}
+	})
+
+	t.Run("to defaults to the latest version", func(t *testing.T) {
+		rr := versionRequest(testApp, http.MethodGet, diffPath+"?from=1", "", owner.ID)
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+
+		var diff routes.DiffResponse
+		err := json.NewDecoder(rr.Body).Decode(&diff)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if diff.To != 2 || diff.Insertions != 1 {
+			t.Errorf("expected 1 insertion up to clock 2 got %d up to %d", diff.Insertions, diff.To)
+		}
+	})
+
+	requestCases := []struct {
+		name             string
+		userID           uuid.UUID
+		query            string
+		outputStatusCode int
+	}{
+		{name: "same version", userID: owner.ID, query: "?from=2&to=2", outputStatusCode: 200},
+		{name: "missing from", userID: owner.ID, query: "?to=2", outputStatusCode: 400},
+		{name: "invalid to", userID: owner.ID, query: "?from=0&to=abc", outputStatusCode: 400},
+		{name: "from after to", userID: owner.ID, query: "?from=2&to=1", outputStatusCode: 400},
+		{name: "unknown clock", userID: owner.ID, query: "?from=0&to=3", outputStatusCode: 404},
+		{name: "no access", userID: otherUser.ID, query: "?from=0&to=2", outputStatusCode: 404},
+	}
+
+	for _, testCase := range requestCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rr := versionRequest(testApp, http.MethodGet, diffPath+testCase.query, "", testCase.userID)
+			if rr.Code != testCase.outputStatusCode {
+				t.Fatalf("expected %d got %d", testCase.outputStatusCode, rr.Code)
+			}
+		})
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
 	"github.com/rejdeboer/multiplayer-server/internal/routes"
 	"github.com/rejdeboer/multiplayer-server/tests/helpers"
 )
@@ -51,6 +54,16 @@ func TestCreateDocument(t *testing.T) {
 	if response.Name != documentName {
 		t.Errorf("output name mismatch; expected %v; got %v", documentName, response.Name)
 	}
+
+	messages := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, response.ID.String())
+	if len(messages) != 1 {
+		t.Fatalf("expected %d outbox message got %d", 1, len(messages))
+	}
+
+	err = helpers.ValidateEvent(messages[0].Value)
+	if err != nil {
+		t.Errorf("outbox message does not match schema: %v", err)
+	}
 }
 
 func TestDeleteDocument(t *testing.T) {
@@ -111,40 +124,310 @@ func TestDeleteDocument(t *testing.T) {
 }
 
 func TestListDocuments(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	testUser := testApp.GetTestUser()
+	ownedDoc := testApp.GetTestDocument(testUser.ID)
+
+	otherUser := testApp.GetTestUser()
+	sharedDoc := testApp.GetTestDocument(otherUser.ID)
+	testApp.AddTestContributor(sharedDoc.ID, testUser.ID)
+
+	cases := []struct {
+		name             string
+		query            string
+		expectOwned      bool
+		expectShared     bool
+		outputStatusCode int
+	}{
+		{name: "default", query: "", expectOwned: true, expectShared: true, outputStatusCode: 200},
+		{name: "any", query: "?owner=any", expectOwned: true, expectShared: true, outputStatusCode: 200},
+		{name: "me", query: "?owner=me", expectOwned: true, expectShared: false, outputStatusCode: 200},
+		{name: "others", query: "?owner=others", expectOwned: false, expectShared: true, outputStatusCode: 200},
+		{name: "name prefix", query: "?name=" + url.QueryEscape(ownedDoc.Name), expectOwned: true, expectShared: strings.HasPrefix(strings.ToLower(sharedDoc.Name), strings.ToLower(ownedDoc.Name)), outputStatusCode: 200},
+		{name: "invalid filter", query: "?owner=nobody", outputStatusCode: 400},
+		{name: "invalid sort", query: "?sort=size", outputStatusCode: 400},
+		{name: "invalid limit", query: "?limit=1000", outputStatusCode: 400},
+		{name: "invalid cursor", query: "?cursor=nope", outputStatusCode: 400},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/document"+testCase.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testUser.ID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Fatalf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+			if status != 200 {
+				return
+			}
+
+			var response routes.Page[routes.DocumentListItem]
+			err = json.NewDecoder(rr.Body).Decode(&response)
+			if err != nil {
+				t.Fatalf("error decoding json response: %v", err)
+			}
+
+			var owned, shared *routes.DocumentListItem
+			for i, doc := range response.Items {
+				switch doc.ID {
+				case ownedDoc.ID:
+					owned = &response.Items[i]
+				case sharedDoc.ID:
+					shared = &response.Items[i]
+				}
+			}
+
+			if (owned != nil) != testCase.expectOwned {
+				t.Errorf("owned document present: %v; expected: %v", owned != nil, testCase.expectOwned)
+			}
+			if (shared != nil) != testCase.expectShared {
+				t.Errorf("shared document present: %v; expected: %v", shared != nil, testCase.expectShared)
+			}
+			if owned != nil && (!owned.IsOwner || owned.Role != routes.ROLE_OWNER) {
+				t.Errorf("owned document has wrong role: %v", owned)
+			}
+			if shared != nil && (shared.IsOwner || shared.Role != routes.ROLE_EDITOR) {
+				t.Errorf("shared document has wrong role: %v", shared)
+			}
+		})
+	}
+}
+
+func TestListDocumentsPagination(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	testUser := testApp.GetTestUser()
+
+	created := map[uuid.UUID]bool{}
+	for range 5 {
+		created[testApp.GetTestDocument(testUser.ID).ID] = true
+	}
+
+	for _, sort := range []string{"name", "created", "updated", "lastOpened"} {
+		t.Run(sort, func(t *testing.T) {
+			seen := map[uuid.UUID]bool{}
+			cursor := ""
+			pages := 0
+
+			for {
+				query := url.Values{"sort": {sort}, "limit": {"2"}}
+				if cursor != "" {
+					query.Set("cursor", cursor)
+				}
+
+				req, err := http.NewRequest(http.MethodGet, "/document?"+query.Encode(), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testUser.ID))
+
+				rr := httptest.NewRecorder()
+				testApp.Handler.ServeHTTP(rr, req)
+
+				status := rr.Result().StatusCode
+				if status != 200 {
+					t.Fatalf("expected %d got %d", 200, status)
+				}
+
+				var response routes.Page[routes.DocumentListItem]
+				err = json.NewDecoder(rr.Body).Decode(&response)
+				if err != nil {
+					t.Fatalf("error decoding json response: %v", err)
+				}
+
+				if len(response.Items) > 2 {
+					t.Fatalf("page exceeds limit: %d items", len(response.Items))
+				}
+				for _, doc := range response.Items {
+					if seen[doc.ID] {
+						t.Errorf("document %v returned twice", doc.ID)
+					}
+					seen[doc.ID] = true
+				}
+
+				pages++
+				link := rr.Result().Header.Get("Link")
+				if response.NextCursor == "" {
+					if link != "" {
+						t.Errorf("expected no Link header on last page; got %s", link)
+					}
+					break
+				}
+				if !strings.Contains(link, `rel="next"`) {
+					t.Errorf("expected next Link header; got %s", link)
+				}
+				cursor = response.NextCursor
+			}
+
+			if pages != 3 {
+				t.Errorf("expected %d pages got %d", 3, pages)
+			}
+			for id := range created {
+				if !seen[id] {
+					t.Errorf("document %v missing from pages", id)
+				}
+			}
+		})
+	}
+
+	t.Run("cursor from other sort", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/document?sort=name&limit=1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testUser.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		var response routes.Page[routes.DocumentListItem]
+		err = json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+
+		req, err = http.NewRequest(http.MethodGet, "/document?sort=created&cursor="+response.NextCursor, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testUser.ID))
+
+		rr = httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		status := rr.Result().StatusCode
+		if status != 400 {
+			t.Errorf("expected %d got %d", 400, status)
+		}
+	})
+}
+
+func TestUpdateDocument(t *testing.T) {
 	testApp := helpers.GetTestApp()
 	testUser := testApp.GetTestUser()
 	testDoc := testApp.GetTestDocument(testUser.ID)
-	testDocID := testDoc.ID
 
-	req, err := http.NewRequest(http.MethodGet, "/document", nil)
+	editor := testApp.GetTestUser()
+	testApp.AddTestContributor(testDoc.ID, editor.ID)
+
+	outsider := testApp.GetTestUser()
+
+	cases := []struct {
+		name             string
+		userID           uuid.UUID
+		body             string
+		outputStatusCode int
+	}{
+		{name: "rename", userID: editor.ID, body: `{"name": "renamed document"}`, outputStatusCode: 200},
+		{name: "description and icon", userID: testUser.ID, body: `{"description": "meeting notes", "icon": "📝"}`, outputStatusCode: 200},
+		{name: "empty body", userID: testUser.ID, body: `{}`, outputStatusCode: 400},
+		{name: "empty name", userID: testUser.ID, body: `{"name": "  "}`, outputStatusCode: 400},
+		{name: "icon is not an emoji", userID: testUser.ID, body: `{"icon": "abc"}`, outputStatusCode: 400},
+		{name: "not a contributor", userID: outsider.ID, body: `{"name": "hijacked"}`, outputStatusCode: 404},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(
+				http.MethodPatch,
+				"/document/"+testDoc.ID.String(),
+				strings.NewReader(testCase.body),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testCase.userID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+		})
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/document/"+testDoc.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testUser.ID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	var response routes.DocumentResponse
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	if err != nil {
+		t.Fatalf("error decoding json response: %v", err)
+	}
+
+	if response.Name != "renamed document" {
+		t.Errorf("output name mismatch; expected %v; got %v", "renamed document", response.Name)
+	}
+	if response.Description != "meeting notes" {
+		t.Errorf("output description mismatch; expected %v; got %v", "meeting notes", response.Description)
+	}
+	if response.Icon == nil || *response.Icon != "📝" {
+		t.Errorf("output icon mismatch; expected %v; got %v", "📝", response.Icon)
+	}
+
+	messages := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, testDoc.ID.String())
+	if len(messages) != 1 {
+		t.Fatalf("expected %d outbox message got %d", 1, len(messages))
+	}
+
+	err = helpers.ValidateEvent(messages[0].Value)
+	if err != nil {
+		t.Errorf("outbox message does not match schema: %v", err)
+	}
+}
+
+func TestDocumentUpdateTracksLastEditor(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	testUser := testApp.GetTestUser()
+	editor := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(testUser.ID)
+	testApp.AddTestContributor(testDoc.ID, editor.ID)
+
+	testApp.AddTestDocumentUpdate(testDoc.ID, editor.ID, []byte{1, 2, 3})
+
+	req, err := http.NewRequest(http.MethodGet, "/document/"+testDoc.ID.String(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testUser.ID))
 
 	rr := httptest.NewRecorder()
 	testApp.Handler.ServeHTTP(rr, req)
 
 	status := rr.Result().StatusCode
 	if status != 200 {
-		t.Errorf("expected %d got %d", 200, rr.Result().StatusCode)
+		t.Fatalf("expected %d got %d", 200, status)
 	}
 
-	var response []routes.DocumentListItem
+	var response routes.DocumentResponse
 	err = json.NewDecoder(rr.Body).Decode(&response)
 	if err != nil {
-		t.Errorf("error decoding json response: %v", err)
+		t.Fatalf("error decoding json response: %v", err)
 	}
 
-	testDocumentFound := false
-	for _, doc := range response {
-		if doc.ID == testDocID {
-			testDocumentFound = true
-		}
+	if response.LastEditedBy == nil || *response.LastEditedBy != editor.ID {
+		t.Errorf("expected last editor %v; got %v", editor.ID, response.LastEditedBy)
 	}
-
-	if !testDocumentFound {
-		t.Errorf("test document %v not present in response: %v", testDocID, response)
+	if !response.UpdatedAt.After(testDoc.UpdatedAt) {
+		t.Errorf("expected updatedAt after %v; got %v", testDoc.UpdatedAt, response.UpdatedAt)
+	}
+	if !response.CreatedAt.Equal(testDoc.CreatedAt) {
+		t.Errorf("expected createdAt %v; got %v", testDoc.CreatedAt, response.CreatedAt)
 	}
 }
 
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestEmailInvitation(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	existingUser := testApp.GetTestUser()
+
+	invitationPath := "/document/" + testDoc.ID.String() + "/invitation"
+	invitedEmail := "invited-colleague@example.com"
+
+	cases := []struct {
+		name             string
+		body             string
+		outputStatusCode int
+	}{
+		{name: "invalid email", body: `{"email": "not an email"}`, outputStatusCode: 400},
+		{name: "owner role", body: `{"email": "` + invitedEmail + `", "role": "owner"}`, outputStatusCode: 400},
+		{name: "existing user", body: `{"email": "` + existingUser.Email + `"}`, outputStatusCode: 409},
+		{name: "invite", body: `{"email": "` + invitedEmail + `", "role": "viewer"}`, outputStatusCode: 200},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, invitationPath, strings.NewReader(testCase.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(owner.ID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+		})
+	}
+
+	t.Run("invitation mail is sent", func(t *testing.T) {
+		messages := testApp.Mailer.Messages(invitedEmail)
+		if len(messages) != 1 {
+			t.Fatalf("expected %d mails got %d", 1, len(messages))
+		}
+		if !strings.Contains(messages[0].Body, testDoc.Name) {
+			t.Errorf("expected mail to mention %s; got %s", testDoc.Name, messages[0].Body)
+		}
+	})
+
+	t.Run("invitation is listed", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, invitationPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(owner.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		var invitations []routes.EmailInvitationResponse
+		err = json.NewDecoder(rr.Body).Decode(&invitations)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if len(invitations) != 1 || invitations[0].Email != invitedEmail {
+			t.Errorf("expected invitation for %s; got %v", invitedEmail, invitations)
+		}
+	})
+
+	t.Run("registering without a matching token claims nothing", func(t *testing.T) {
+		cases := []struct {
+			name  string
+			email string
+			token string
+		}{
+			{name: "no token", email: "unverified-colleague@example.com"},
+			{name: "token of another address", email: "other-colleague@example.com", token: invitationToken(t, testApp, invitedEmail)},
+		}
+
+		for _, testCase := range cases {
+			t.Run(testCase.name, func(t *testing.T) {
+				rr := invitationRequest(testApp, invitationPath, `{"email": "`+testCase.email+`"}`, owner.ID)
+				if rr.Code != 200 {
+					t.Fatalf("expected %d got %d", 200, rr.Code)
+				}
+
+				user := registerInvitedUser(t, testApp, testCase.email, testCase.token)
+				rr = documentRequest(testApp, testDoc.ID, user.ID)
+				if rr.Code != 404 {
+					t.Errorf("expected %d got %d", 404, rr.Code)
+				}
+			})
+		}
+	})
+
+	t.Run("registering claims the invitation", func(t *testing.T) {
+		email := strings.ToUpper(invitedEmail[:1]) + invitedEmail[1:]
+		user := registerInvitedUser(t, testApp, email, invitationToken(t, testApp, invitedEmail))
+
+		rr := documentRequest(testApp, testDoc.ID, user.ID)
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+
+		var document routes.DocumentResponse
+		err := json.NewDecoder(rr.Body).Decode(&document)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if role := document.RoleOf(user.ID); role != routes.ROLE_VIEWER {
+			t.Errorf("expected role %s got %s", routes.ROLE_VIEWER, role)
+		}
+	})
+
+	t.Run("existing user registered in other case", func(t *testing.T) {
+		rr := invitationRequest(testApp, invitationPath, `{"email": "`+invitedEmail+`"}`, owner.ID)
+		if rr.Code != 409 {
+			t.Errorf("expected %d got %d", 409, rr.Code)
+		}
+	})
+}
+
+func invitationRequest(testApp *helpers.TestApp, path string, body string, userID uuid.UUID) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func documentRequest(testApp *helpers.TestApp, docID uuid.UUID, userID uuid.UUID) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/document/"+docID.String(), nil)
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// invitationToken returns the token of the signup link in the last
+// invitation mail sent to email
+func invitationToken(t *testing.T, testApp *helpers.TestApp, email string) string {
+	messages := testApp.Mailer.Messages(email)
+	if len(messages) == 0 {
+		t.Fatalf("expected an invitation mail for %s", email)
+	}
+
+	for _, line := range strings.Split(messages[len(messages)-1].Body, "\n") {
+		link, err := url.Parse(line)
+		if err == nil && link.Query().Has("token") {
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("expected a signup link in %s", messages[len(messages)-1].Body)
+	return ""
+}
+
+func registerInvitedUser(t *testing.T, testApp *helpers.TestApp, email string, token string) routes.UserResponse {
+	bodyBytes, err := json.Marshal(routes.UserCreate{
+		Email:           email,
+		Username:        strings.ReplaceAll(strings.Split(strings.ToLower(email), "@")[0], "-", ""),
+		Password:        "Very$ecret1",
+		InvitationToken: token,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/user", bytes.NewReader(bodyBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	if rr.Code != 200 {
+		t.Fatalf("expected %d got %d", 200, rr.Code)
+	}
+
+	var user routes.UserResponse
+	err = json.NewDecoder(rr.Body).Decode(&user)
+	if err != nil {
+		t.Fatalf("error decoding json response: %v", err)
+	}
+	return user
+}
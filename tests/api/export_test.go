@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestExportDocument(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	viewer := testApp.GetTestUser()
+	testApp.AddTestContributorWithRole(testDoc.ID, viewer.ID, routes.ROLE_VIEWER)
+	otherUser := testApp.GetTestUser()
+	testApp.AddTestDocumentUpdate(testDoc.ID, owner.ID, helloUpdate)
+
+	exportPath := "/document/" + testDoc.ID.String() + "/export"
+
+	cases := []struct {
+		name              string
+		userID            uuid.UUID
+		query             string
+		outputStatusCode  int
+		outputContentType string
+		outputBody        string
+	}{
+		{name: "default markdown", userID: viewer.ID, query: "", outputStatusCode: 200, outputContentType: "text/markdown; charset=utf-8", outputBody: "hello\n"},
+		{name: "markdown", userID: owner.ID, query: "?format=md", outputStatusCode: 200, outputContentType: "text/markdown; charset=utf-8", outputBody: "hello\n"},
+		{name: "plain text", userID: viewer.ID, query: "?format=txt", outputStatusCode: 200, outputContentType: "text/plain; charset=utf-8", outputBody: "hello\n"},
+		{name: "html", userID: viewer.ID, query: "?format=html", outputStatusCode: 200, outputContentType: "text/html; charset=utf-8", outputBody: "<p>hello</p>"},
+		{name: "invalid format", userID: viewer.ID, query: "?format=pdf", outputStatusCode: 400},
+		{name: "no access", userID: otherUser.ID, query: "?format=md", outputStatusCode: 404},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rr := exportRequest(testApp, exportPath+testCase.query, testCase.userID)
+			if rr.Code != testCase.outputStatusCode {
+				t.Fatalf("expected %d got %d", testCase.outputStatusCode, rr.Code)
+			}
+			if testCase.outputStatusCode != 200 {
+				return
+			}
+
+			contentType := rr.Header().Get("Content-Type")
+			if contentType != testCase.outputContentType {
+				t.Errorf("expected %s got %s", testCase.outputContentType, contentType)
+			}
+			disposition := rr.Header().Get("Content-Disposition")
+			if !strings.HasPrefix(disposition, "attachment;") {
+				t.Errorf("expected an attachment got %s", disposition)
+			}
+			if !strings.Contains(rr.Body.String(), testCase.outputBody) {
+				t.Errorf("expected %q in %q", testCase.outputBody, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		rr := exportRequest(testApp, exportPath+"?format=json", viewer.ID)
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+
+		var response routes.DocumentExport
+		err := json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if response.Name != testDoc.Name {
+			t.Errorf("expected %s got %s", testDoc.Name, response.Name)
+		}
+		if _, ok := response.Content["content"]; !ok {
+			t.Errorf("expected the content root in %v", response.Content)
+		}
+	})
+}
+
+func exportRequest(testApp *helpers.TestApp, path string, userID uuid.UUID) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	return rr
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestImportDocument(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	testUser := testApp.GetTestUser()
+
+	cases := []struct {
+		name             string
+		fileName         string
+		documentName     string
+		content          string
+		outputStatusCode int
+		outputName       string
+		outputMarkdown   string
+	}{
+		{
+			name:             "markdown",
+			fileName:         "notes.md",
+			content:          "# Notes\n\nSome **bold** text\n\n- one\n- two\n",
+			outputStatusCode: 200,
+			outputName:       "notes",
+			outputMarkdown:   "# Notes\n\nSome **bold** text\n\n- one\n- two\n",
+		},
+		{
+			name:             "plain text with name",
+			fileName:         "notes.txt",
+			documentName:     "Meeting",
+			content:          "first line\nsecond *line*\n",
+			outputStatusCode: 200,
+			outputName:       "Meeting",
+			outputMarkdown:   "first line\\\nsecond \\*line\\*\n",
+		},
+		{
+			name:             "empty file",
+			fileName:         "empty.md",
+			content:          "",
+			outputStatusCode: 200,
+			outputName:       "empty",
+			outputMarkdown:   "",
+		},
+		{
+			name:             "invalid file type",
+			fileName:         "notes.pdf",
+			content:          "%PDF",
+			outputStatusCode: 400,
+		},
+		{
+			name:             "invalid utf-8",
+			fileName:         "notes.txt",
+			content:          "\xff\xfe",
+			outputStatusCode: 400,
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rr := importRequest(t, testApp, testUser.ID, testCase.fileName, testCase.documentName, testCase.content)
+			if rr.Code != testCase.outputStatusCode {
+				t.Fatalf("expected %d got %d", testCase.outputStatusCode, rr.Code)
+			}
+			if testCase.outputStatusCode != 200 {
+				return
+			}
+
+			var response routes.DocumentResponse
+			err := json.NewDecoder(rr.Body).Decode(&response)
+			if err != nil {
+				t.Fatalf("error decoding json response: %v", err)
+			}
+			if response.Name != testCase.outputName {
+				t.Errorf("expected %s got %s", testCase.outputName, response.Name)
+			}
+			if response.OwnerID != testUser.ID {
+				t.Errorf("expected owner %s got %s", testUser.ID, response.OwnerID)
+			}
+
+			export := exportRequest(testApp, "/document/"+response.ID.String()+"/export?format=md", testUser.ID)
+			if export.Code != 200 {
+				t.Fatalf("expected %d got %d", 200, export.Code)
+			}
+			if export.Body.String() != testCase.outputMarkdown {
+				t.Errorf("expected %q got %q", testCase.outputMarkdown, export.Body.String())
+			}
+		})
+	}
+}
+
+func importRequest(t *testing.T, testApp *helpers.TestApp, userID uuid.UUID, fileName string, name string, content string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if name != "" {
+		err := writer.WriteField("name", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dataPart, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dataPart.Write([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, "/document/import", body)
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	return rr
+}
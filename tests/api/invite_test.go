@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestCreateInvite(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	editor := testApp.GetTestUser()
+	testApp.AddTestContributor(testDoc.ID, editor.ID)
+
+	invitePath := "/document/" + testDoc.ID.String() + "/invite"
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	cases := []struct {
+		name             string
+		userID           uuid.UUID
+		body             string
+		outputStatusCode int
+	}{
+		{name: "default role", userID: owner.ID, body: `{}`, outputStatusCode: 200},
+		{name: "viewer with max uses", userID: owner.ID, body: `{"role": "viewer", "maxUses": 2}`, outputStatusCode: 200},
+		{name: "editor can not create", userID: editor.ID, body: `{}`, outputStatusCode: 403},
+		{name: "owner role", userID: owner.ID, body: `{"role": "owner"}`, outputStatusCode: 400},
+		{name: "expired", userID: owner.ID, body: `{"expiresAt": "` + past + `"}`, outputStatusCode: 400},
+		{name: "zero max uses", userID: owner.ID, body: `{"maxUses": 0}`, outputStatusCode: 400},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, invitePath, strings.NewReader(testCase.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testCase.userID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+		})
+	}
+
+	t.Run("list invites", func(t *testing.T) {
+		invites := listInvites(t, testApp, owner.ID, testDoc.ID)
+		if len(invites) != 2 {
+			t.Fatalf("expected %d invites got %d", 2, len(invites))
+		}
+	})
+}
+
+func TestAcceptInvite(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+
+	invite := createInvite(t, testApp, owner.ID, testDoc.ID, `{"role": "commenter", "maxUses": 1}`)
+
+	firstUser := testApp.GetTestUser()
+	t.Run("accept", func(t *testing.T) {
+		rr := acceptInvite(t, testApp, firstUser.ID, invite.Token)
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+
+		var response routes.DocumentResponse
+		err := json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if role := response.RoleOf(firstUser.ID); role != routes.ROLE_COMMENTER {
+			t.Errorf("expected role %s got %s", routes.ROLE_COMMENTER, role)
+		}
+	})
+
+	t.Run("accepting twice does not use the invite", func(t *testing.T) {
+		rr := acceptInvite(t, testApp, firstUser.ID, invite.Token)
+		if rr.Code != 200 {
+			t.Errorf("expected %d got %d", 200, rr.Code)
+		}
+	})
+
+	t.Run("invite is used up", func(t *testing.T) {
+		rr := acceptInvite(t, testApp, testApp.GetTestUser().ID, invite.Token)
+		if rr.Code != 410 {
+			t.Errorf("expected %d got %d", 410, rr.Code)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		rr := acceptInvite(t, testApp, testApp.GetTestUser().ID, "unknown")
+		if rr.Code != 404 {
+			t.Errorf("expected %d got %d", 404, rr.Code)
+		}
+	})
+
+	t.Run("revoked invite", func(t *testing.T) {
+		revoked := createInvite(t, testApp, owner.ID, testDoc.ID, `{}`)
+
+		req, err := http.NewRequest(http.MethodDelete, "/document/"+testDoc.ID.String()+"/invite/"+revoked.ID.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(owner.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+		if rr.Code != 202 {
+			t.Fatalf("expected %d got %d", 202, rr.Code)
+		}
+
+		rr = acceptInvite(t, testApp, testApp.GetTestUser().ID, revoked.Token)
+		if rr.Code != 404 {
+			t.Errorf("expected %d got %d", 404, rr.Code)
+		}
+
+		for _, listed := range listInvites(t, testApp, owner.ID, testDoc.ID) {
+			if listed.ID == revoked.ID {
+				t.Errorf("expected revoked invite to not be listed")
+			}
+		}
+	})
+}
+
+func createInvite(t *testing.T, testApp *helpers.TestApp, userID uuid.UUID, docID uuid.UUID, body string) routes.InviteResponse {
+	req, err := http.NewRequest(http.MethodPost, "/document/"+docID.String()+"/invite", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	if rr.Code != 200 {
+		t.Fatalf("expected %d got %d", 200, rr.Code)
+	}
+
+	var invite routes.InviteResponse
+	err = json.NewDecoder(rr.Body).Decode(&invite)
+	if err != nil {
+		t.Fatalf("error decoding json response: %v", err)
+	}
+	return invite
+}
+
+func listInvites(t *testing.T, testApp *helpers.TestApp, userID uuid.UUID, docID uuid.UUID) []routes.InviteResponse {
+	req, err := http.NewRequest(http.MethodGet, "/document/"+docID.String()+"/invite", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	if rr.Code != 200 {
+		t.Fatalf("expected %d got %d", 200, rr.Code)
+	}
+
+	var invites []routes.InviteResponse
+	err = json.NewDecoder(rr.Body).Decode(&invites)
+	if err != nil {
+		t.Fatalf("error decoding json response: %v", err)
+	}
+	return invites
+}
+
+func acceptInvite(t *testing.T, testApp *helpers.TestApp, userID uuid.UUID, token string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(http.MethodPost, "/invite/"+token+"/accept", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	return rr
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rejdeboer/multiplayer-server/internal/mailer"
+)
+
+func TestSmtpMailerEncodesSubject(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go serveSmtp(listener, received)
+
+	smtpMailer := newTestSmtpMailer(t, listener.Addr(), 5*time.Second)
+	err = smtpMailer.Send(context.Background(), mailer.Message{
+		To:      "invited@example.com",
+		Subject: "alice invited you to Notes\r\nBcc: attacker@example.com",
+		Body:    "hello",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := <-received
+	for _, line := range strings.Split(data, "\r\n") {
+		if strings.HasPrefix(line, "Bcc:") {
+			t.Errorf("expected the subject to stay in a single header, got %q", data)
+		}
+	}
+	if !strings.Contains(data, "Subject: =?utf-8?q?") {
+		t.Errorf("expected a q-encoded subject, got %q", data)
+	}
+}
+
+func TestSmtpMailerTimesOut(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// Accept connections without ever greeting the client
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	smtpMailer := newTestSmtpMailer(t, listener.Addr(), 200*time.Millisecond)
+
+	start := time.Now()
+	err = smtpMailer.Send(context.Background(), mailer.Message{To: "invited@example.com", Subject: "hi", Body: "hello"})
+	if err == nil {
+		t.Fatal("expected send to fail against an unresponsive relay")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected send to give up after the timeout, took %v", elapsed)
+	}
+}
+
+func newTestSmtpMailer(t *testing.T, addr net.Addr, timeout time.Duration) *mailer.SmtpMailer {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mailer.NewSmtpMailer(host, uint16(portNumber), "", "", "noreply@multiplayer.local", timeout)
+}
+
+// serveSmtp accepts a single connection and answers just enough of the
+// protocol to deliver one message, the message data is sent to received
+func serveSmtp(listener net.Listener, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		switch command := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case command == "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			received <- data.String()
+			reply("250 ok")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
@@ -15,6 +15,11 @@ func TestMain(m *testing.M) {
 
 	settings := configuration.ReadConfiguration("../../configuration")
 	settings.Azure.BlobConnectionString = strings.ReplaceAll(settings.Azure.BlobConnectionString, "azurite:10000", cluster.GetAzuriteHostPort())
+	settings.Storage.S3Endpoint = cluster.GetMinioHostPort()
+	settings.Storage.S3Region = "us-east-1"
+	settings.Storage.S3AccessKey = helpers.MINIO_ACCESS_KEY
+	settings.Storage.S3SecretKey = helpers.MINIO_SECRET_KEY
+	settings.Storage.S3UseSsl = false
 	settings.Application.ElasticsearchEndpoint = cluster.GetElasticsearchEndpoint()
 	settings.Database.Host = "localhost"
 	settings.Database.Port = cluster.GetDBPort()
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/eventbus"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestOutboxRelay(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	testUser := testApp.GetTestUser()
+
+	bodyBytes, err := json.Marshal(routes.DocumentCreate{
+		Name: "relayed document",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/document", bytes.NewReader(bodyBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testUser.ID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	var response routes.DocumentResponse
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	if err != nil {
+		t.Fatalf("error decoding json response: %v", err)
+	}
+
+	err = testApp.Relay.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("error flushing outbox: %v", err)
+	}
+
+	published := testApp.Publisher.Messages(events.DOCUMENTS_TOPIC, response.ID.String())
+	if len(published) != 1 {
+		t.Fatalf("expected %d published message got %d", 1, len(published))
+	}
+
+	err = helpers.ValidateEvent(published[0].Value)
+	if err != nil {
+		t.Errorf("published message does not match schema: %v", err)
+	}
+
+	messages := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, response.ID.String())
+	if !messages[0].DeliveredAt.Valid {
+		t.Errorf("outbox message should be marked delivered")
+	}
+}
+
+// rejectingPublisher fails every publish that contains the rejected key, like
+// a broker rejects a message that is too large
+type rejectingPublisher struct {
+	*eventbus.MemoryPublisher
+	rejected string
+}
+
+func (p *rejectingPublisher) Publish(ctx context.Context, messages ...eventbus.Message) error {
+	for _, message := range messages {
+		if string(message.Key) == p.rejected {
+			return errors.New("message too large")
+		}
+	}
+	return p.MemoryPublisher.Publish(ctx, messages...)
+}
+
+func TestOutboxRelayRejectedMessage(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	ctx := context.Background()
+
+	err := testApp.Relay.Flush(ctx)
+	if err != nil {
+		t.Fatalf("error flushing outbox: %v", err)
+	}
+
+	keys := []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+	q := db.New(testApp.Relay.Pool)
+	for _, key := range keys {
+		err := q.CreateOutboxMessage(ctx, db.CreateOutboxMessageParams{
+			Topic: events.DOCUMENTS_TOPIC,
+			Key:   []byte(key),
+			Value: []byte("{}"),
+		})
+		if err != nil {
+			t.Fatalf("error creating outbox message: %v", err)
+		}
+	}
+
+	publisher := &rejectingPublisher{MemoryPublisher: eventbus.NewMemoryPublisher(), rejected: keys[1]}
+	relay := *testApp.Relay
+	relay.Publisher = publisher
+	relay.MaxAttempts = 2
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		err := relay.Flush(ctx)
+		if err == nil {
+			t.Fatalf("expected attempt %d to fail", attempt)
+		}
+	}
+	err = relay.Flush(ctx)
+	if err != nil {
+		t.Fatalf("expected the failed message to be skipped got %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		key       string
+		published int
+		delivered bool
+		failed    bool
+	}{
+		{name: "message before the rejected one", key: keys[0], published: 1, delivered: true},
+		{name: "rejected message", key: keys[1], failed: true},
+		{name: "message after the rejected one", key: keys[2], published: 1, delivered: true},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if published := publisher.Messages(events.DOCUMENTS_TOPIC, testCase.key); len(published) != testCase.published {
+				t.Errorf("expected %d published messages got %d", testCase.published, len(published))
+			}
+			message := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, testCase.key)[0]
+			if message.DeliveredAt.Valid != testCase.delivered {
+				t.Errorf("expected delivered to be %v got %v", testCase.delivered, message.DeliveredAt.Valid)
+			}
+			if message.FailedAt.Valid != testCase.failed {
+				t.Errorf("expected failed to be %v got %v", testCase.failed, message.FailedAt.Valid)
+			}
+		})
+	}
+}
+
+func TestOutboxRelayValidate(t *testing.T) {
+	valid := outbox.Relay{
+		BatchSize:      100,
+		PollInterval:   time.Second,
+		MaxBackoff:     time.Minute,
+		PublishTimeout: 10 * time.Second,
+		MaxAttempts:    3,
+	}
+
+	cases := []struct {
+		name   string
+		modify func(r *outbox.Relay)
+		valid  bool
+	}{
+		{name: "valid", modify: func(r *outbox.Relay) {}, valid: true},
+		{name: "zero batch size", modify: func(r *outbox.Relay) { r.BatchSize = 0 }},
+		{name: "zero poll interval", modify: func(r *outbox.Relay) { r.PollInterval = 0 }},
+		{name: "backoff shorter than poll interval", modify: func(r *outbox.Relay) { r.MaxBackoff = time.Millisecond }},
+		{name: "zero publish timeout", modify: func(r *outbox.Relay) { r.PublishTimeout = 0 }},
+		{name: "zero max attempts", modify: func(r *outbox.Relay) { r.MaxAttempts = 0 }},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			relay := valid
+			testCase.modify(&relay)
+			err := relay.Validate()
+			if (err == nil) != testCase.valid {
+				t.Errorf("expected valid to be %v got error %v", testCase.valid, err)
+			}
+		})
+	}
+}
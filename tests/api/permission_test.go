@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestDocumentRolePermissions(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+
+	editor := testApp.GetTestUser()
+	testApp.AddTestContributorWithRole(testDoc.ID, editor.ID, routes.ROLE_EDITOR)
+	commenter := testApp.GetTestUser()
+	testApp.AddTestContributorWithRole(testDoc.ID, commenter.ID, routes.ROLE_COMMENTER)
+	viewer := testApp.GetTestUser()
+	testApp.AddTestContributorWithRole(testDoc.ID, viewer.ID, routes.ROLE_VIEWER)
+	outsider := testApp.GetTestUser()
+
+	documentPath := "/document/" + testDoc.ID.String()
+
+	cases := []struct {
+		name             string
+		userID           uuid.UUID
+		method           string
+		path             string
+		body             string
+		outputStatusCode int
+	}{
+		{name: "viewer can read", userID: viewer.ID, method: http.MethodGet, path: documentPath, outputStatusCode: 200},
+		{name: "outsider can not read", userID: outsider.ID, method: http.MethodGet, path: documentPath, outputStatusCode: 404},
+		{name: "viewer can not update", userID: viewer.ID, method: http.MethodPatch, path: documentPath, body: `{"name": "viewer"}`, outputStatusCode: 403},
+		{name: "commenter can not update", userID: commenter.ID, method: http.MethodPatch, path: documentPath, body: `{"name": "commenter"}`, outputStatusCode: 403},
+		{name: "editor can update", userID: editor.ID, method: http.MethodPatch, path: documentPath, body: `{"name": "editor"}`, outputStatusCode: 200},
+		{name: "viewer can not share", userID: viewer.ID, method: http.MethodPost, path: documentPath + "/contributor", body: `{"userId": "` + outsider.ID.String() + `"}`, outputStatusCode: 403},
+		{name: "owner role can not be granted", userID: editor.ID, method: http.MethodPost, path: documentPath + "/contributor", body: `{"userId": "` + outsider.ID.String() + `", "role": "owner"}`, outputStatusCode: 400},
+		{name: "unknown role", userID: editor.ID, method: http.MethodPost, path: documentPath + "/contributor", body: `{"userId": "` + outsider.ID.String() + `", "role": "admin"}`, outputStatusCode: 400},
+		{name: "editor can not delete", userID: editor.ID, method: http.MethodDelete, path: documentPath, outputStatusCode: 403},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testCase.userID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+		})
+	}
+
+	t.Run("contributors include roles", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, documentPath, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(viewer.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		var response routes.DocumentResponse
+		err = json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+
+		expected := map[uuid.UUID]string{
+			owner.ID:     routes.ROLE_OWNER,
+			editor.ID:    routes.ROLE_EDITOR,
+			commenter.ID: routes.ROLE_COMMENTER,
+			viewer.ID:    routes.ROLE_VIEWER,
+		}
+		if len(response.Contributors) != len(expected) {
+			t.Fatalf("expected %d contributors got %d", len(expected), len(response.Contributors))
+		}
+		for _, contributor := range response.Contributors {
+			if expected[contributor.UserID] != contributor.Role {
+				t.Errorf("expected role %s for %v; got %s", expected[contributor.UserID], contributor.UserID, contributor.Role)
+			}
+		}
+	})
+}
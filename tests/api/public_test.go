@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+// helloUpdate inserts "hello" into the root text named "content", encoded
+// the same way Y.encodeStateAsUpdate does for a single client
+var helloUpdate = []byte("\x01\x01\x01\x00\x04\x01\x07content\x05hello\x00")
+
+func TestPublishDocument(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	editor := testApp.GetTestUser()
+	testApp.AddTestContributor(testDoc.ID, editor.ID)
+	testApp.AddTestDocumentUpdate(testDoc.ID, owner.ID, helloUpdate)
+
+	publishPath := "/document/" + testDoc.ID.String() + "/publish"
+
+	cases := []struct {
+		name             string
+		userID           uuid.UUID
+		method           string
+		outputStatusCode int
+	}{
+		{name: "editor can not publish", userID: editor.ID, method: http.MethodPost, outputStatusCode: 403},
+		{name: "not published", userID: owner.ID, method: http.MethodDelete, outputStatusCode: 404},
+		{name: "publish", userID: owner.ID, method: http.MethodPost, outputStatusCode: 200},
+		{name: "editor can not unpublish", userID: editor.ID, method: http.MethodDelete, outputStatusCode: 403},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rr := publishRequest(testApp, testCase.method, publishPath, testCase.userID)
+			if rr.Code != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, rr.Code)
+			}
+		})
+	}
+
+	rr := publishRequest(testApp, http.MethodPost, publishPath, owner.ID)
+	var publication routes.PublicationResponse
+	err := json.NewDecoder(rr.Body).Decode(&publication)
+	if err != nil {
+		t.Fatalf("error decoding json response: %v", err)
+	}
+
+	t.Run("serves html without authentication", func(t *testing.T) {
+		rr := getPublic(testApp, "/public/"+publication.Slug, "")
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), "<p>hello</p>") {
+			t.Errorf("expected rendered content in %s", rr.Body.String())
+		}
+		if vary := rr.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("expected Vary %q got %q", "Accept", vary)
+		}
+	})
+
+	t.Run("serves json", func(t *testing.T) {
+		rr := getPublic(testApp, "/public/"+publication.Slug, "application/json")
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+
+		var response routes.PublicDocumentResponse
+		err := json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if response.ID != testDoc.ID {
+			t.Errorf("expected document %s got %s", testDoc.ID, response.ID)
+		}
+		delta, ok := response.Content["content"].([]any)
+		if !ok || len(delta) != 1 {
+			t.Fatalf("expected a single insert got %v", response.Content["content"])
+		}
+		if insert := delta[0].(map[string]any)["insert"]; insert != "hello" {
+			t.Errorf("expected %s got %v", "hello", insert)
+		}
+	})
+
+	t.Run("serves updates stored after the last request", func(t *testing.T) {
+		testApp.AddTestDocumentUpdate(testDoc.ID, owner.ID, worldUpdate)
+
+		rr := getPublic(testApp, "/public/"+publication.Slug, "")
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), "<p>hello world</p>") {
+			t.Errorf("expected updated content in %s", rr.Body.String())
+		}
+	})
+
+	t.Run("publishing again keeps the slug", func(t *testing.T) {
+		rr := publishRequest(testApp, http.MethodPost, publishPath, owner.ID)
+		var response routes.PublicationResponse
+		err := json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if response.Slug != publication.Slug {
+			t.Errorf("expected %s got %s", publication.Slug, response.Slug)
+		}
+	})
+
+	t.Run("unpublished document is not served", func(t *testing.T) {
+		rr := publishRequest(testApp, http.MethodDelete, publishPath, owner.ID)
+		if rr.Code != 202 {
+			t.Fatalf("expected %d got %d", 202, rr.Code)
+		}
+
+		rr = getPublic(testApp, "/public/"+publication.Slug, "")
+		if rr.Code != 404 {
+			t.Errorf("expected %d got %d", 404, rr.Code)
+		}
+	})
+
+	t.Run("unknown slug", func(t *testing.T) {
+		rr := getPublic(testApp, "/public/does-not-exist", "")
+		if rr.Code != 404 {
+			t.Errorf("expected %d got %d", 404, rr.Code)
+		}
+	})
+}
+
+func publishRequest(testApp *helpers.TestApp, method string, path string, userID uuid.UUID) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func getPublic(testApp *helpers.TestApp, path string, accept string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		req.Header.Add("Accept", accept)
+	}
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	return rr
+}
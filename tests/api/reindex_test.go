@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/indexing"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestReindexUsers(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	testUser := testApp.GetTestUser()
+
+	reindexer := testApp.GetReindexer(filepath.Join(t.TempDir(), "checkpoint.json"))
+	err := reindexer.Run(context.Background(), indexing.USERS_INDEX)
+	if err != nil {
+		t.Fatalf("error reindexing users: %v", err)
+	}
+
+	assertUserSearchable(t, testApp, testUser)
+}
+
+// TestReindexCatchesUpWithChanges resumes a reindex whose rows were all
+// copied, a user created in the meantime has to be copied before the swap
+func TestReindexCatchesUpWithChanges(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	reindexer := testApp.GetReindexer(checkpointPath)
+
+	index := indexing.USERS_INDEX + "_catch_up"
+	err := indexing.CreateIndex(context.Background(), reindexer.SearchClient, indexing.USERS_INDEX, index)
+	if err != nil {
+		t.Fatalf("error creating index: %v", err)
+	}
+	checkpoint, err := json.Marshal(indexing.Checkpoint{
+		Alias:     indexing.USERS_INDEX,
+		Index:     index,
+		LastID:    uuid.Max,
+		StartedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(checkpointPath, checkpoint, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testUser := testApp.GetTestUser()
+
+	err = reindexer.Run(context.Background(), indexing.USERS_INDEX)
+	if err != nil {
+		t.Fatalf("error reindexing users: %v", err)
+	}
+
+	assertUserSearchable(t, testApp, testUser)
+}
+
+func assertUserSearchable(t *testing.T, testApp *helpers.TestApp, testUser helpers.TestUser) {
+	req, err := http.NewRequest(http.MethodGet, "/user/search?query="+testUser.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	status := rr.Result().StatusCode
+	if status != 200 {
+		t.Errorf("expected %d got %d", 200, status)
+	}
+
+	var response []routes.UserListItem
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	if err != nil {
+		t.Errorf("error decoding json response: %v", err)
+	}
+
+	testUserFound := false
+	for _, user := range response {
+		if user.ID == testUser.ID {
+			testUserFound = true
+		}
+	}
+
+	if !testUserFound {
+		t.Errorf("reindexed user %v not present in response: %v", testUser, response)
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rejdeboer/multiplayer-server/internal/storage"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestLocalStorageSignedURL(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	ctx := context.Background()
+
+	err := testApp.Storage.EnsureContainer(ctx, "private")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = testApp.Storage.Put(ctx, "private", "notes/hello.txt", []byte("hello"), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name             string
+		expiry           time.Duration
+		signed           bool
+		outputStatusCode int
+	}{
+		{name: "unsigned", signed: false, outputStatusCode: 403},
+		{name: "signed", signed: true, expiry: time.Minute, outputStatusCode: 200},
+		{name: "expired", signed: true, expiry: -time.Minute, outputStatusCode: 403},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rawUrl := testApp.Storage.URL("private", "notes/hello.txt")
+			if testCase.signed {
+				rawUrl, err = testApp.Storage.SignedURL(ctx, "private", "notes/hello.txt", testCase.expiry)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			objectUrl, err := url.Parse(rawUrl)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, objectUrl.RequestURI(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+
+			if status == 200 && rr.Body.String() != "hello" {
+				t.Errorf("expected body %q got %q", "hello", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestLocalStorageMountPath(t *testing.T) {
+	cases := []struct {
+		name      string
+		baseURL   string
+		mountPath string
+		valid     bool
+	}{
+		{name: "path", baseURL: "http://localhost:8000/files", mountPath: "/files", valid: true},
+		{name: "trailing slash", baseURL: "http://localhost:8000/files/", mountPath: "/files", valid: true},
+		{name: "no path", baseURL: "http://localhost:8000"},
+		{name: "root path", baseURL: "http://localhost:8000/"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			store, err := storage.NewLocalStore(t.TempDir(), testCase.baseURL, "secret", nil)
+			if (err == nil) != testCase.valid {
+				t.Fatalf("expected valid to be %v got error %v", testCase.valid, err)
+			}
+			if err == nil && store.MountPath() != testCase.mountPath {
+				t.Errorf("expected mount path %s got %s", testCase.mountPath, store.MountPath())
+			}
+		})
+	}
+}
+
+func TestObjectStores(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	ctx := context.Background()
+
+	for backend, store := range testApp.ObjectStores {
+		t.Run(backend, func(t *testing.T) {
+			container := "contract"
+
+			err := store.EnsureContainer(ctx, container)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Creating an existing container is not an error
+			err = store.EnsureContainer(ctx, container)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = store.Put(ctx, container, "notes/hello.txt", []byte("hello"), "text/plain")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.Put(ctx, container, "other/bye.txt", []byte("bye"), "text/plain")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, err := store.Get(ctx, container, "notes/hello.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := io.ReadAll(body)
+			body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != "hello" {
+				t.Errorf("expected content %q got %q", "hello", string(content))
+			}
+
+			objects, err := store.List(ctx, container, "notes/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(objects) != 1 || objects[0].Key != "notes/hello.txt" || objects[0].Size != 5 {
+				t.Errorf("expected only notes/hello.txt with size 5, got %+v", objects)
+			}
+
+			signedUrl, err := store.SignedURL(ctx, container, "notes/hello.txt", time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			status, signedContent := fetchObject(t, testApp, backend, signedUrl)
+			if status != 200 || signedContent != "hello" {
+				t.Errorf("expected signed url to return 200 %q, got %d %q", "hello", status, signedContent)
+			}
+
+			_, err = store.Get(ctx, container, "notes/missing.txt")
+			if !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("expected ErrNotFound for missing object, got %v", err)
+			}
+
+			err = store.Delete(ctx, container, "notes/hello.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = store.Get(ctx, container, "notes/hello.txt")
+			if !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("expected ErrNotFound after delete, got %v", err)
+			}
+			err = store.Delete(ctx, container, "notes/hello.txt")
+			if !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("expected ErrNotFound deleting a missing object, got %v", err)
+			}
+		})
+	}
+}
+
+// fetchObject requests an object url, local objects are served by the application itself
+func fetchObject(t *testing.T, testApp *helpers.TestApp, backend string, rawUrl string) (int, string) {
+	if backend == storage.LOCAL {
+		objectUrl, err := url.Parse(rawUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, objectUrl.RequestURI(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+		return rr.Result().StatusCode, rr.Body.String()
+	}
+
+	res, err := http.Get(rawUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(content)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/audit"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestTransferOwnership(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+
+	recipient := testApp.GetTestUser()
+	testApp.AddTestContributorWithRole(testDoc.ID, recipient.ID, routes.ROLE_VIEWER)
+	editor := testApp.GetTestUser()
+	testApp.AddTestContributor(testDoc.ID, editor.ID)
+	outsider := testApp.GetTestUser()
+
+	transferPath := "/document/" + testDoc.ID.String() + "/transfer"
+
+	cases := []struct {
+		name             string
+		userID           uuid.UUID
+		method           string
+		path             string
+		body             string
+		outputStatusCode int
+	}{
+		{name: "editor can not transfer", userID: editor.ID, method: http.MethodPost, path: transferPath, body: `{"userId": "` + recipient.ID.String() + `"}`, outputStatusCode: 403},
+		{name: "missing recipient", userID: owner.ID, method: http.MethodPost, path: transferPath, body: `{}`, outputStatusCode: 400},
+		{name: "recipient is not a contributor", userID: owner.ID, method: http.MethodPost, path: transferPath, body: `{"userId": "` + outsider.ID.String() + `"}`, outputStatusCode: 400},
+		{name: "recipient is the owner", userID: owner.ID, method: http.MethodPost, path: transferPath, body: `{"userId": "` + owner.ID.String() + `"}`, outputStatusCode: 400},
+		{name: "accept without transfer", userID: recipient.ID, method: http.MethodPost, path: transferPath + "/accept", outputStatusCode: 404},
+		{name: "request transfer", userID: owner.ID, method: http.MethodPost, path: transferPath, body: `{"userId": "` + recipient.ID.String() + `"}`, outputStatusCode: 202},
+		{name: "other contributor can not accept", userID: editor.ID, method: http.MethodPost, path: transferPath + "/accept", outputStatusCode: 404},
+		{name: "other contributor can not cancel", userID: editor.ID, method: http.MethodDelete, path: transferPath, outputStatusCode: 404},
+		{name: "recipient declines", userID: recipient.ID, method: http.MethodDelete, path: transferPath, outputStatusCode: 202},
+		{name: "accept declined transfer", userID: recipient.ID, method: http.MethodPost, path: transferPath + "/accept", outputStatusCode: 404},
+		{name: "request transfer again", userID: owner.ID, method: http.MethodPost, path: transferPath, body: `{"userId": "` + recipient.ID.String() + `"}`, outputStatusCode: 202},
+		{name: "owner is unchanged before acceptance", userID: editor.ID, method: http.MethodDelete, path: "/document/" + testDoc.ID.String() + "/contributor/" + owner.ID.String(), outputStatusCode: 403},
+		{name: "recipient accepts", userID: recipient.ID, method: http.MethodPost, path: transferPath + "/accept", outputStatusCode: 200},
+		{name: "previous owner can not transfer", userID: owner.ID, method: http.MethodPost, path: transferPath, body: `{"userId": "` + editor.ID.String() + `"}`, outputStatusCode: 403},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(testCase.userID))
+
+			rr := httptest.NewRecorder()
+			testApp.Handler.ServeHTTP(rr, req)
+
+			status := rr.Result().StatusCode
+			if status != testCase.outputStatusCode {
+				t.Errorf("expected %d got %d", testCase.outputStatusCode, status)
+			}
+		})
+	}
+
+	t.Run("roles are swapped", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/document/"+testDoc.ID.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(owner.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		var response routes.DocumentResponse
+		err = json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+
+		if response.OwnerID != recipient.ID {
+			t.Errorf("expected owner %v got %v", recipient.ID, response.OwnerID)
+		}
+		if role := response.RoleOf(recipient.ID); role != routes.ROLE_OWNER {
+			t.Errorf("expected role %s for recipient; got %s", routes.ROLE_OWNER, role)
+		}
+		if role := response.RoleOf(owner.ID); role != routes.ROLE_EDITOR {
+			t.Errorf("expected role %s for previous owner; got %s", routes.ROLE_EDITOR, role)
+		}
+	})
+
+	t.Run("transfer is audited", func(t *testing.T) {
+		entries := testApp.GetAuditLog(testDoc.ID)
+		expected := []string{
+			audit.OWNERSHIP_TRANSFER_REQUESTED,
+			audit.OWNERSHIP_TRANSFER_CANCELLED,
+			audit.OWNERSHIP_TRANSFER_REQUESTED,
+			audit.OWNERSHIP_TRANSFER_ACCEPTED,
+		}
+		if len(entries) != len(expected) {
+			t.Fatalf("expected %d audit log entries got %d", len(expected), len(entries))
+		}
+		for i, entry := range entries {
+			if entry.Action != expected[i] {
+				t.Errorf("expected action %s got %s", expected[i], entry.Action)
+			}
+		}
+	})
+
+	t.Run("transfer is published", func(t *testing.T) {
+		messages := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, testDoc.ID.String())
+		if len(messages) == 0 {
+			t.Fatal("expected outbox messages")
+		}
+		err := helpers.ValidateEvent(messages[len(messages)-1].Value)
+		if err != nil {
+			t.Errorf("outbox message does not match schema: %v", err)
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/internal/trash"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+func TestTrashAndRestoreDocument(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	testUser := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(testUser.ID)
+	token := testApp.GetSignedJwt(testUser.ID)
+
+	req, err := http.NewRequest(http.MethodDelete, "/document/"+testDoc.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	status := rr.Result().StatusCode
+	if status != 202 {
+		t.Fatalf("expected %d got %d", 202, status)
+	}
+
+	t.Run("trashed document is hidden", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/document/"+testDoc.ID.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+token)
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		status := rr.Result().StatusCode
+		if status != 404 {
+			t.Errorf("expected %d got %d", 404, status)
+		}
+	})
+
+	t.Run("trashed document is listed in trash", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/document/trash", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+token)
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		status := rr.Result().StatusCode
+		if status != 200 {
+			t.Fatalf("expected %d got %d", 200, status)
+		}
+
+		var response routes.Page[routes.TrashItem]
+		err = json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+
+		if len(response.Items) != 1 || response.Items[0].ID != testDoc.ID {
+			t.Fatalf("expected trash to contain %v; got %v", testDoc.ID, response.Items)
+		}
+		if !response.Items[0].PurgeAt.After(response.Items[0].DeletedAt) {
+			t.Errorf("expected purgeAt after deletedAt; got %v", response.Items[0])
+		}
+	})
+
+	t.Run("deleting twice", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, "/document/"+testDoc.ID.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+token)
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		status := rr.Result().StatusCode
+		if status != 404 {
+			t.Errorf("expected %d got %d", 404, status)
+		}
+	})
+
+	t.Run("other user can not restore", func(t *testing.T) {
+		otherUser := testApp.GetTestUser()
+		req, err := http.NewRequest(http.MethodPost, "/document/"+testDoc.ID.String()+"/restore", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(otherUser.ID))
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		status := rr.Result().StatusCode
+		if status != 404 {
+			t.Errorf("expected %d got %d", 404, status)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "/document/"+testDoc.ID.String()+"/restore", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Add("Authorization", "Bearer "+token)
+
+		rr := httptest.NewRecorder()
+		testApp.Handler.ServeHTTP(rr, req)
+
+		status := rr.Result().StatusCode
+		if status != 200 {
+			t.Fatalf("expected %d got %d", 200, status)
+		}
+
+		var response routes.DocumentResponse
+		err = json.NewDecoder(rr.Body).Decode(&response)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+
+		if response.ID != testDoc.ID {
+			t.Errorf("documents do not match; expected: %v; was: %v", testDoc.ID, response.ID)
+		}
+
+		messages := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, testDoc.ID.String())
+		if len(messages) != 2 {
+			t.Fatalf("expected %d outbox messages got %d", 2, len(messages))
+		}
+		for _, message := range messages {
+			err = helpers.ValidateEvent(message.Value)
+			if err != nil {
+				t.Errorf("outbox message does not match schema: %v", err)
+			}
+		}
+	})
+}
+
+func TestPurgeTrash(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	testUser := testApp.GetTestUser()
+	trashedDoc := testApp.GetTestDocument(testUser.ID)
+	keptDoc := testApp.GetTestDocument(testUser.ID)
+	token := testApp.GetSignedJwt(testUser.ID)
+
+	req, err := http.NewRequest(http.MethodDelete, "/document/"+trashedDoc.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	status := rr.Result().StatusCode
+	if status != 202 {
+		t.Fatalf("expected %d got %d", 202, status)
+	}
+
+	purged, err := testApp.Purger.Purge(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("error purging trash: %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("expected %d purged documents got %d", 0, purged)
+	}
+
+	_, err = testApp.Purger.Purge(context.Background(), time.Now().Add(testApp.Purger.Retention+time.Hour))
+	if err != nil {
+		t.Fatalf("error purging trash: %v", err)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, "/document/"+trashedDoc.ID.String()+"/restore", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	rr = httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	status = rr.Result().StatusCode
+	if status != 404 {
+		t.Errorf("expected purged document to be gone; got %d", status)
+	}
+
+	req, err = http.NewRequest(http.MethodGet, "/document/"+keptDoc.ID.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	rr = httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	status = rr.Result().StatusCode
+	if status != 200 {
+		t.Errorf("expected document outside trash to be kept; got %d", status)
+	}
+
+	messages := testApp.GetOutboxMessages(events.DOCUMENTS_TOPIC, trashedDoc.ID.String())
+	if len(messages) != 2 {
+		t.Fatalf("expected %d outbox messages got %d", 2, len(messages))
+	}
+
+	err = helpers.ValidateEvent(messages[1].Value)
+	if err != nil {
+		t.Errorf("outbox message does not match schema: %v", err)
+	}
+}
+
+func TestTrashPurgerValidate(t *testing.T) {
+	valid := trash.Purger{
+		Retention: 24 * time.Hour,
+		Interval:  time.Minute,
+		BatchSize: 100,
+	}
+
+	cases := []struct {
+		name   string
+		modify func(p *trash.Purger)
+		valid  bool
+	}{
+		{name: "valid", modify: func(p *trash.Purger) {}, valid: true},
+		{name: "zero batch size", modify: func(p *trash.Purger) { p.BatchSize = 0 }},
+		{name: "zero interval", modify: func(p *trash.Purger) { p.Interval = 0 }},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			purger := valid
+			testCase.modify(&purger)
+			err := purger.Validate()
+			if (err == nil) != testCase.valid {
+				t.Errorf("expected valid to be %v got error %v", testCase.valid, err)
+			}
+		})
+	}
+}
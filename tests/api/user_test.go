@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"mime/multipart"
@@ -11,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/events"
 	"github.com/rejdeboer/multiplayer-server/internal/routes"
 	"github.com/rejdeboer/multiplayer-server/pkg/httperrors"
 	"github.com/rejdeboer/multiplayer-server/tests/helpers"
@@ -152,6 +154,62 @@ func TestCreateUser(t *testing.T) {
 	}
 }
 
+func TestCreateUserWritesOutboxMessage(t *testing.T) {
+	testApp := helpers.GetTestApp()
+
+	bodyBytes, err := json.Marshal(routes.UserCreate{
+		Email:    "outbox@example.com",
+		Username: "outboxuser",
+		Password: "Very$ecret1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/user", bytes.NewReader(bodyBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+
+	status := rr.Result().StatusCode
+	if status != 200 {
+		t.Fatalf("expected %d got %d", 200, status)
+	}
+
+	var response routes.UserResponse
+	err = json.NewDecoder(rr.Body).Decode(&response)
+	if err != nil {
+		t.Fatalf("error decoding json response: %v", err)
+	}
+
+	messages := testApp.GetOutboxMessages(events.USERS_TOPIC, response.ID.String())
+	if len(messages) != 1 {
+		t.Fatalf("expected %d outbox message got %d", 1, len(messages))
+	}
+
+	if messages[0].DeliveredAt.Valid {
+		t.Errorf("outbox message should not be delivered yet")
+	}
+
+	err = helpers.ValidateEvent(messages[0].Value)
+	if err != nil {
+		t.Errorf("outbox message does not match schema: %v", err)
+	}
+
+	var envelope events.Envelope
+	err = json.Unmarshal(messages[0].Value, &envelope)
+	if err != nil {
+		t.Fatalf("error decoding envelope: %v", err)
+	}
+
+	if envelope.Type != "user.created" {
+		t.Errorf("expected event type %s got %s", "user.created", envelope.Type)
+	}
+}
+
 func TestUpdateUserImage(t *testing.T) {
 	testApp := helpers.GetTestApp()
 	testUser := testApp.GetTestUser()
@@ -188,4 +246,10 @@ func TestUpdateUserImage(t *testing.T) {
 	if status != 200 {
 		t.Errorf("expected %d got %d", 200, rr.Result().StatusCode)
 	}
+
+	object, err := testApp.Storage.Get(context.Background(), routes.USER_IMAGES_CONTAINER, testUser.ID.String()+".png")
+	if err != nil {
+		t.Fatalf("error fetching stored user image: %v", err)
+	}
+	object.Close()
 }
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rejdeboer/multiplayer-server/internal/audit"
+	"github.com/rejdeboer/multiplayer-server/internal/routes"
+	"github.com/rejdeboer/multiplayer-server/tests/helpers"
+)
+
+// worldUpdate and exclamationUpdate append " world" and "!" to helloUpdate
+var (
+	worldUpdate       = []byte("\x01\x01\x01\x05\x84\x01\x04\x06 world\x00")
+	exclamationUpdate = []byte("\x01\x01\x01\x0b\x84\x01\x0a\x01!\x00")
+)
+
+func TestDocumentVersions(t *testing.T) {
+	testApp := helpers.GetTestApp()
+	owner := testApp.GetTestUser()
+	editor := testApp.GetTestUser()
+	viewer := testApp.GetTestUser()
+	otherUser := testApp.GetTestUser()
+	testDoc := testApp.GetTestDocument(owner.ID)
+	testApp.AddTestContributorWithRole(testDoc.ID, editor.ID, routes.ROLE_EDITOR)
+	testApp.AddTestContributorWithRole(testDoc.ID, viewer.ID, routes.ROLE_VIEWER)
+
+	start := time.Now().Add(-time.Hour)
+	testApp.AddTestDocumentUpdateAt(testDoc.ID, owner.ID, helloUpdate, start)
+	testApp.AddTestDocumentUpdateAt(testDoc.ID, editor.ID, worldUpdate, start.Add(2*time.Minute))
+	testApp.AddTestDocumentUpdateAt(testDoc.ID, owner.ID, exclamationUpdate, start.Add(30*time.Minute))
+
+	basePath := "/document/" + testDoc.ID.String()
+
+	t.Run("groups updates by time", func(t *testing.T) {
+		versions := listVersionsRequest(t, testApp, basePath, viewer.ID)
+		if len(versions) != 2 {
+			t.Fatalf("expected %d versions got %d", 2, len(versions))
+		}
+		if versions[0].FromClock != 2 || versions[0].Clock != 2 {
+			t.Errorf("expected newest version to span 2-2 got %d-%d", versions[0].FromClock, versions[0].Clock)
+		}
+		if versions[1].FromClock != 0 || versions[1].Clock != 1 || versions[1].Updates != 2 {
+			t.Errorf("expected oldest version to span 0-1 got %d-%d", versions[1].FromClock, versions[1].Clock)
+		}
+		if len(versions[1].Authors) != 2 || versions[1].Authors[0] != owner.ID || versions[1].Authors[1] != editor.ID {
+			t.Errorf("expected authors %s and %s got %v", owner.ID, editor.ID, versions[1].Authors)
+		}
+	})
+
+	snapshotCases := []struct {
+		name             string
+		userID           uuid.UUID
+		body             string
+		outputStatusCode int
+	}{
+		{name: "viewer can not snapshot", userID: viewer.ID, body: `{"name": "Draft"}`, outputStatusCode: 403},
+		{name: "empty name", userID: editor.ID, body: `{"name": " "}`, outputStatusCode: 400},
+		{name: "clock ahead of document", userID: editor.ID, body: `{"name": "Future", "clock": 3}`, outputStatusCode: 400},
+		{name: "no access", userID: otherUser.ID, body: `{"name": "Draft"}`, outputStatusCode: 404},
+		{name: "snapshot at clock", userID: editor.ID, body: `{"name": "First draft", "clock": 0}`, outputStatusCode: 201},
+	}
+
+	for _, testCase := range snapshotCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rr := versionRequest(testApp, http.MethodPost, basePath+"/snapshots", testCase.body, testCase.userID)
+			if rr.Code != testCase.outputStatusCode {
+				t.Fatalf("expected %d got %d", testCase.outputStatusCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("snapshot ends a version", func(t *testing.T) {
+		versions := listVersionsRequest(t, testApp, basePath, owner.ID)
+		if len(versions) != 3 {
+			t.Fatalf("expected %d versions got %d", 3, len(versions))
+		}
+		oldest := versions[2]
+		if oldest.Clock != 0 || len(oldest.Snapshots) != 1 || oldest.Snapshots[0].Name != "First draft" {
+			t.Errorf("expected the snapshot to end the version at clock 0 got %+v", oldest)
+		}
+		if versions[1].FromClock != 1 || versions[1].Clock != 1 {
+			t.Errorf("expected a version spanning 1-1 got %d-%d", versions[1].FromClock, versions[1].Clock)
+		}
+	})
+
+	t.Run("snapshot is audited", func(t *testing.T) {
+		details := auditDetails(t, testApp, testDoc.ID, audit.SNAPSHOT_CREATED)
+		if details["snapshotId"] == nil || details["name"] != "First draft" {
+			t.Errorf("expected the snapshot id and name got %v", details)
+		}
+	})
+
+	contentCases := []struct {
+		name             string
+		userID           uuid.UUID
+		clock            string
+		outputStatusCode int
+		outputText       string
+	}{
+		{name: "first update", userID: viewer.ID, clock: "0", outputStatusCode: 200, outputText: `"hello"`},
+		{name: "second update", userID: viewer.ID, clock: "1", outputStatusCode: 200, outputText: `"hello world"`},
+		{name: "latest update", userID: owner.ID, clock: "2", outputStatusCode: 200, outputText: `"hello world!"`},
+		{name: "unknown clock", userID: owner.ID, clock: "3", outputStatusCode: 404},
+		{name: "invalid clock", userID: owner.ID, clock: "abc", outputStatusCode: 400},
+		{name: "no access", userID: otherUser.ID, clock: "0", outputStatusCode: 404},
+	}
+
+	for _, testCase := range contentCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rr := versionRequest(testApp, http.MethodGet, basePath+"/versions/"+testCase.clock, "", testCase.userID)
+			if rr.Code != testCase.outputStatusCode {
+				t.Fatalf("expected %d got %d", testCase.outputStatusCode, rr.Code)
+			}
+			if testCase.outputStatusCode != 200 {
+				return
+			}
+			if !strings.Contains(rr.Body.String(), testCase.outputText) {
+				t.Errorf("expected %s in %s", testCase.outputText, rr.Body.String())
+			}
+		})
+	}
+
+	restoreCases := []struct {
+		name             string
+		userID           uuid.UUID
+		clock            string
+		outputStatusCode int
+	}{
+		{name: "viewer can not restore", userID: viewer.ID, clock: "0", outputStatusCode: 403},
+		{name: "restore unknown clock", userID: editor.ID, clock: "3", outputStatusCode: 404},
+		{name: "restore invalid clock", userID: editor.ID, clock: "abc", outputStatusCode: 400},
+		{name: "restore without access", userID: otherUser.ID, clock: "0", outputStatusCode: 404},
+	}
+
+	for _, testCase := range restoreCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rr := versionRequest(testApp, http.MethodPost, basePath+"/versions/"+testCase.clock+"/restore", "", testCase.userID)
+			if rr.Code != testCase.outputStatusCode {
+				t.Fatalf("expected %d got %d", testCase.outputStatusCode, rr.Code)
+			}
+		})
+	}
+
+	t.Run("restore appends an update", func(t *testing.T) {
+		rr := versionRequest(testApp, http.MethodPost, basePath+"/versions/0/restore", "", editor.ID)
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+
+		var restored routes.VersionRestoreResponse
+		err := json.NewDecoder(rr.Body).Decode(&restored)
+		if err != nil {
+			t.Fatalf("error decoding json response: %v", err)
+		}
+		if restored.RestoredClock != 0 || restored.Clock != 3 {
+			t.Fatalf("expected clock 0 restored as clock 3 got %+v", restored)
+		}
+
+		rr = versionRequest(testApp, http.MethodGet, basePath+"/versions/3", "", viewer.ID)
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), `"hello"`) {
+			t.Errorf("expected %s in %s", `"hello"`, rr.Body.String())
+		}
+
+		rr = versionRequest(testApp, http.MethodGet, basePath+"/versions/2", "", viewer.ID)
+		if !strings.Contains(rr.Body.String(), `"hello world!"`) {
+			t.Errorf("expected history to be kept got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("restore is audited", func(t *testing.T) {
+		details := auditDetails(t, testApp, testDoc.ID, audit.VERSION_RESTORED)
+		if details["restoredClock"] != float64(0) || details["clock"] != float64(3) {
+			t.Errorf("expected clock 0 restored as clock 3 got %v", details)
+		}
+	})
+
+	t.Run("restore without changes", func(t *testing.T) {
+		rr := versionRequest(testApp, http.MethodPost, basePath+"/versions/3/restore", "", owner.ID)
+		if rr.Code != 200 {
+			t.Fatalf("expected %d got %d", 200, rr.Code)
+		}
+		versions := listVersionsRequest(t, testApp, basePath, owner.ID)
+		if versions[0].Clock != 3 {
+			t.Errorf("expected no new update got latest clock %d", versions[0].Clock)
+		}
+	})
+}
+
+// auditDetails returns the details of the last audit log entry with action
+func auditDetails(t *testing.T, testApp *helpers.TestApp, documentID uuid.UUID, action string) map[string]any {
+	entries := testApp.GetAuditLog(documentID)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Action != action {
+			continue
+		}
+		var details map[string]any
+		err := json.Unmarshal(entries[i].Details, &details)
+		if err != nil {
+			t.Fatalf("error decoding audit details: %v", err)
+		}
+		return details
+	}
+	t.Fatalf("expected an audit log entry with action %s", action)
+	return nil
+}
+
+func listVersionsRequest(t *testing.T, testApp *helpers.TestApp, basePath string, userID uuid.UUID) []routes.VersionResponse {
+	rr := versionRequest(testApp, http.MethodGet, basePath+"/versions", "", userID)
+	if rr.Code != 200 {
+		t.Fatalf("expected %d got %d", 200, rr.Code)
+	}
+
+	var versions []routes.VersionResponse
+	err := json.NewDecoder(rr.Body).Decode(&versions)
+	if err != nil {
+		t.Fatalf("error decoding json response: %v", err)
+	}
+	return versions
+}
+
+func versionRequest(testApp *helpers.TestApp, method string, path string, body string, userID uuid.UUID) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Add("Authorization", "Bearer "+testApp.GetSignedJwt(userID))
+
+	rr := httptest.NewRecorder()
+	testApp.Handler.ServeHTTP(rr, req)
+	return rr
+}
@@ -0,0 +1,304 @@
+package crdt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rejdeboer/multiplayer-server/internal/render"
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+)
+
+// The fixtures are the updates written by websocket/tests/api/fixtures.rs,
+// every directory holds the updates of one document in the order they
+// were persisted and the content toJSON returns for it in Yjs
+const FIXTURES_PATH = "../resources/yjs"
+
+func TestFixtures(t *testing.T) {
+	for _, name := range fixtureNames(t) {
+		t.Run(name, func(t *testing.T) {
+			updates, expected := loadFixture(t, name)
+
+			doc, err := yjs.MergeUpdates(updates)
+			if err != nil {
+				t.Fatalf("error merging updates: %v", err)
+			}
+			assertContent(t, doc, expected)
+		})
+	}
+}
+
+func TestFixturesInReverseOrder(t *testing.T) {
+	for _, name := range fixtureNames(t) {
+		t.Run(name, func(t *testing.T) {
+			updates, expected := loadFixture(t, name)
+			slices.Reverse(updates)
+
+			doc, err := yjs.MergeUpdates(updates)
+			if err != nil {
+				t.Fatalf("error merging updates: %v", err)
+			}
+			assertContent(t, doc, expected)
+		})
+	}
+}
+
+func TestTruncatedUpdates(t *testing.T) {
+	for _, name := range fixtureNames(t) {
+		t.Run(name, func(t *testing.T) {
+			updates, _ := loadFixture(t, name)
+			update := updates[0]
+
+			for i := range len(update) - 1 {
+				err := yjs.NewDoc().ApplyUpdate(update[:i])
+				if !errors.Is(err, yjs.ErrUnexpectedEOF) && !errors.Is(err, yjs.ErrInvalidUpdate) {
+					t.Fatalf("expected an error when truncated to %d bytes got %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+// Garbage collected and deleted runs cost the same regardless of their
+// length, so a few bytes can not make the decoder allocate per clock tick
+func TestLongRuns(t *testing.T) {
+	const long = 1 << 62
+
+	t.Run("garbage collected", func(t *testing.T) {
+		doc := yjs.NewDoc()
+		err := doc.ApplyUpdate(gcUpdate(5, 0, long))
+		if err != nil {
+			t.Fatalf("error applying update: %v", err)
+		}
+
+		expected := map[uint64]uint64{5: long}
+		if !reflect.DeepEqual(doc.StateVector(), expected) {
+			t.Errorf("expected %v got %v", expected, doc.StateVector())
+		}
+	})
+
+	t.Run("empty run", func(t *testing.T) {
+		err := yjs.NewDoc().ApplyUpdate(gcUpdate(5, 0, 0))
+		if !errors.Is(err, yjs.ErrInvalidUpdate) {
+			t.Errorf("expected ErrInvalidUpdate got %v", err)
+		}
+	})
+
+	t.Run("overlapping pending runs", func(t *testing.T) {
+		doc := merge(t, [][]byte{gcUpdate(7, 5, 10), gcUpdate(7, 0, 10)})
+
+		expected := map[uint64]uint64{7: 15}
+		if !reflect.DeepEqual(doc.StateVector(), expected) {
+			t.Errorf("expected %v got %v", expected, doc.StateVector())
+		}
+	})
+
+	// Text inserted inside a deleted run splits it, in any order of updates
+	deleted := deletedUpdate(1, "content", long)
+	x := stringUpdate(2, yjs.ID{Client: 1, Clock: 1 << 40}, "x")
+	y := stringUpdate(3, yjs.ID{Client: 1, Clock: 1<<40 + 5}, "y")
+	for name, updates := range map[string][][]byte{
+		"deleted":                  {deleted, x, y},
+		"deleted in reverse order": {y, x, deleted},
+	} {
+		t.Run(name, func(t *testing.T) {
+			doc := merge(t, updates)
+			if text := doc.Get("content").String(); text != "xy" {
+				t.Errorf("expected %q got %q", "xy", text)
+			}
+		})
+	}
+}
+
+// gcUpdate encodes a single garbage collected run of client
+func gcUpdate(client uint64, clock uint64, length uint64) []byte {
+	update := []byte{1, 1}
+	update = binary.AppendUvarint(update, client)
+	update = binary.AppendUvarint(update, clock)
+	update = append(update, 0)
+	update = binary.AppendUvarint(update, length)
+	return append(update, 0)
+}
+
+// deletedUpdate encodes a deleted run of client at the start of a root type
+func deletedUpdate(client uint64, root string, length uint64) []byte {
+	update := []byte{1, 1}
+	update = binary.AppendUvarint(update, client)
+	update = append(update, 0, 1, 1, byte(len(root)))
+	update = append(update, root...)
+	update = binary.AppendUvarint(update, length)
+	return append(update, 0)
+}
+
+// stringUpdate encodes text of client inserted between origin and the
+// next tick of its client, the way a client types inside existing text
+func stringUpdate(client uint64, origin yjs.ID, text string) []byte {
+	update := []byte{1, 1}
+	update = binary.AppendUvarint(update, client)
+	update = append(update, 0, 0xc4)
+	update = binary.AppendUvarint(update, origin.Client)
+	update = binary.AppendUvarint(update, origin.Clock)
+	update = binary.AppendUvarint(update, origin.Client)
+	update = binary.AppendUvarint(update, origin.Clock+1)
+	update = append(update, byte(len(text)))
+	update = append(update, text...)
+	return append(update, 0)
+}
+
+func TestStateVector(t *testing.T) {
+	updates, _ := loadFixture(t, "concurrent")
+	doc, err := yjs.MergeUpdates(updates)
+	if err != nil {
+		t.Fatalf("error merging updates: %v", err)
+	}
+
+	expected := map[uint64]uint64{1: 2, 2: 1}
+	if !reflect.DeepEqual(doc.StateVector(), expected) {
+		t.Errorf("expected %v got %v", expected, doc.StateVector())
+	}
+
+	decoded, err := yjs.DecodeStateVector([]byte{0x02, 0x02, 0x01, 0x01, 0x02})
+	if err != nil {
+		t.Fatalf("error decoding state vector: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("expected %v got %v", expected, decoded)
+	}
+}
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name    string
+		fixture string
+		render  func(io.Writer, *yjs.Doc) error
+		output  string
+	}{
+		{name: "text html", fixture: "text", render: render.HTML, output: "<p>Hello <strong>world</strong></p>"},
+		{name: "xml html", fixture: "xml", render: render.HTML, output: "<h2>Title</h2><p>Hi <strong>there</strong></p>"},
+		{name: "text markdown", fixture: "text", render: render.Markdown, output: "Hello **world**\n"},
+		{name: "xml markdown", fixture: "xml", render: render.Markdown, output: "## Title\n\nHi **there**\n"},
+		{name: "text plain", fixture: "text", render: render.Text, output: "Hello world\n"},
+		{name: "xml plain", fixture: "xml", render: render.Text, output: "Title\n\nHi there\n"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			updates, _ := loadFixture(t, testCase.fixture)
+			doc, err := yjs.MergeUpdates(updates)
+			if err != nil {
+				t.Fatalf("error merging updates: %v", err)
+			}
+
+			output := renderString(t, testCase.render, doc)
+			if output != testCase.output {
+				t.Errorf("expected %q got %q", testCase.output, output)
+			}
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestRenderWriteError(t *testing.T) {
+	updates, _ := loadFixture(t, "xml")
+	doc, err := yjs.MergeUpdates(updates)
+	if err != nil {
+		t.Fatalf("error merging updates: %v", err)
+	}
+
+	for name, write := range map[string]func(io.Writer, *yjs.Doc) error{
+		"html":     render.HTML,
+		"markdown": render.Markdown,
+		"plain":    render.Text,
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := write(failingWriter{}, doc)
+			if !errors.Is(err, io.ErrClosedPipe) {
+				t.Errorf("expected %v got %v", io.ErrClosedPipe, err)
+			}
+		})
+	}
+}
+
+// renderString renders the document in memory
+func renderString(t *testing.T, write func(io.Writer, *yjs.Doc) error, doc *yjs.Doc) string {
+	var builder strings.Builder
+	err := write(&builder, doc)
+	if err != nil {
+		t.Fatalf("error rendering document: %v", err)
+	}
+	return builder.String()
+}
+
+func fixtureNames(t *testing.T) []string {
+	entries, err := os.ReadDir(FIXTURES_PATH)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func loadFixture(t *testing.T, name string) ([][]byte, map[string]any) {
+	paths, err := filepath.Glob(filepath.Join(FIXTURES_PATH, name, "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(paths)
+
+	updates := [][]byte{}
+	for _, path := range paths {
+		update, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		updates = append(updates, update)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(FIXTURES_PATH, name, "expected.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected map[string]any
+	err = json.Unmarshal(raw, &expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return updates, expected
+}
+
+// assertContent compares through json so numbers decoded from updates and
+// from the expected file have the same type
+func assertContent(t *testing.T, doc *yjs.Doc, expected map[string]any) {
+	raw, err := json.Marshal(doc.ToJSON())
+	if err != nil {
+		t.Fatalf("error marshalling content: %v", err)
+	}
+	var content map[string]any
+	err = json.Unmarshal(raw, &content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(content, expected) {
+		t.Errorf("expected %v got %v", expected, content)
+	}
+}
@@ -0,0 +1,143 @@
+package crdt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rejdeboer/multiplayer-server/internal/markdown"
+	"github.com/rejdeboer/multiplayer-server/internal/render"
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+)
+
+func TestDiffImportedMarkdown(t *testing.T) {
+	first := yjs.EncodeXmlFragment(1, "default", markdown.Parse("First paragraph\n\nSecond\n"))
+	second := yjs.EncodeXmlFragment(2, "default", markdown.Parse("Appended\n"))
+	third := yjs.EncodeRestore(merge(t, [][]byte{first, second}), merge(t, [][]byte{first}), 3)
+	updates := [][]byte{first, second, third}
+	authors := []string{"alice", "bob", "carol"}
+
+	inserted := diff(t, updates, authors, 0, 1)
+	expected := []yjs.TextChange{
+		{Kind: yjs.ChangeEqual, Text: "First paragraph\nSecond\n"},
+		{Kind: yjs.ChangeInsert, Text: "Appended\n", Author: "bob"},
+	}
+	assertChanges(t, inserted, expected)
+
+	deleted := diff(t, updates, authors, 1, 2)
+	expected = []yjs.TextChange{
+		{Kind: yjs.ChangeEqual, Text: "First paragraph\nSecond\n"},
+		{Kind: yjs.ChangeDelete, Text: "Appended\n", Author: "carol"},
+	}
+	assertChanges(t, deleted, expected)
+
+	unchanged := diff(t, updates, authors, 0, 2)
+	expected = []yjs.TextChange{
+		{Kind: yjs.ChangeEqual, Text: "First paragraph\nSecond\n"},
+	}
+	assertChanges(t, unchanged, expected)
+}
+
+// An update that is attributed again, like one that was stored twice, keeps
+// the author it was first attributed to
+func TestDiffRepeatedUpdate(t *testing.T) {
+	first := yjs.EncodeXmlFragment(1, "default", markdown.Parse("First paragraph\n\nSecond\n"))
+	second := yjs.EncodeXmlFragment(2, "default", markdown.Parse("Appended\n"))
+
+	attributed := yjs.NewAuthors()
+	for i, update := range [][]byte{second, first, second, first} {
+		err := attributed.Add(update, []string{"bob", "alice", "carol", "dave"}[i])
+		if err != nil {
+			t.Fatalf("error attributing update: %v", err)
+		}
+	}
+
+	changes := yjs.DiffText(yjs.NewDoc(), merge(t, [][]byte{first, second}), attributed)
+	expected := []yjs.TextChange{
+		{Kind: yjs.ChangeInsert, Text: "First paragraph\nSecond\n", Author: "alice"},
+		{Kind: yjs.ChangeInsert, Text: "Appended\n", Author: "bob"},
+	}
+	assertChanges(t, changes, expected)
+}
+
+// Rewriting every line of a long document used to keep the furthest reaching
+// paths of every edit distance, several gigabytes for ten thousand lines
+func TestUnifiedLargeRewrite(t *testing.T) {
+	var from, to strings.Builder
+	for i := 0; i < 10_000; i++ {
+		fmt.Fprintf(&from, "old line %d\n", i)
+		fmt.Fprintf(&to, "new line %d\n", i)
+	}
+
+	start := time.Now()
+	unified := render.Unified("a", "b", from.String(), to.String())
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the diff to be fast, took %v", elapsed)
+	}
+	if !strings.HasPrefix(unified, "--- a\n+++ b\n@@ -1,10000 +1,10000 @@\n-old line 0\n") {
+		t.Errorf("expected a single hunk replacing every line got %q", unified[:min(len(unified), 100)])
+	}
+	if lines := strings.Count(unified, "\n"); lines != 20_003 {
+		t.Errorf("expected %d lines got %d", 20_003, lines)
+	}
+}
+
+// TestDiffFixtures checks that leaving out the insertions gives the text of
+// the earlier state and leaving out the deletions the text of the later one
+func TestDiffFixtures(t *testing.T) {
+	for _, name := range fixtureNames(t) {
+		updates, _ := loadFixture(t, name)
+		authors := make([]string, len(updates))
+		for clock := -1; clock < len(updates)-1; clock++ {
+			t.Run(name, func(t *testing.T) {
+				changes := diff(t, updates, authors, clock, len(updates)-1)
+				before := diff(t, updates, authors, clock, clock)
+				after := diff(t, updates, authors, len(updates)-1, len(updates)-1)
+
+				if text := changesText(changes, yjs.ChangeInsert); text != changesText(before, "") {
+					t.Errorf("expected %q before the changes got %q", changesText(before, ""), text)
+				}
+				if text := changesText(changes, yjs.ChangeDelete); text != changesText(after, "") {
+					t.Errorf("expected %q after the changes got %q", changesText(after, ""), text)
+				}
+			})
+		}
+	}
+}
+
+// diff compares the document after the update at clock from with the
+// document after the update at clock to, clock -1 is the empty document
+func diff(t *testing.T, updates [][]byte, authors []string, from int, to int) []yjs.TextChange {
+	attributed := yjs.NewAuthors()
+	for clock := from + 1; clock <= to; clock++ {
+		err := attributed.Add(updates[clock], authors[clock])
+		if err != nil {
+			t.Fatalf("error attributing update: %v", err)
+		}
+	}
+	return yjs.DiffText(merge(t, updates[:from+1]), merge(t, updates[:to+1]), attributed)
+}
+
+// changesText concatenates the text of all changes except those of the
+// skipped kind
+func changesText(changes []yjs.TextChange, skip yjs.ChangeKind) string {
+	text := ""
+	for _, change := range changes {
+		if change.Kind != skip {
+			text += change.Text
+		}
+	}
+	return text
+}
+
+func assertChanges(t *testing.T, changes []yjs.TextChange, expected []yjs.TextChange) {
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes got %d: %+v", len(expected), len(changes), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("expected change %d to be %+v got %+v", i, expected[i], changes[i])
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package crdt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rejdeboer/multiplayer-server/internal/markdown"
+	"github.com/rejdeboer/multiplayer-server/internal/render"
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+)
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+	}{
+		{name: "heading and paragraph", source: "# Title\n\nHello **bold** and *italic* 👋\n"},
+		{name: "lists", source: "- one\n- two\n\n3. three\n4. four\n"},
+		{name: "code", source: "```go\nfmt.Println(\"hi\")\n```\n"},
+		{name: "quote and rule", source: "> quoted `code`\n\n---\n"},
+		{name: "link and image", source: "[site](https://example.com)\n\n![alt](https://example.com/a.png)\n"},
+		{name: "table", source: "| a | b |\n| --- | --- |\n| 1 | 2 |\n"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			update := yjs.EncodeXmlFragment(42, "default", markdown.Parse(testCase.source))
+			doc, err := yjs.MergeUpdates([][]byte{update})
+			if err != nil {
+				t.Fatalf("error merging update: %v", err)
+			}
+
+			output := renderString(t, render.Markdown, doc)
+			if output != testCase.source {
+				t.Errorf("expected %q got %q", testCase.source, output)
+			}
+		})
+	}
+}
+
+func TestTextImport(t *testing.T) {
+	update := yjs.EncodeXmlFragment(7, "default", markdown.ParseText("first\nline *not bold*\n\nsecond\n"))
+	doc, err := yjs.MergeUpdates([][]byte{update})
+	if err != nil {
+		t.Fatalf("error merging update: %v", err)
+	}
+
+	expected := "first\nline *not bold*\n\nsecond\n"
+	if output := renderString(t, render.Text, doc); output != expected {
+		t.Errorf("expected %q got %q", expected, output)
+	}
+	if clock := doc.StateVector()[7]; clock == 0 {
+		t.Errorf("expected a clock for the importing client")
+	}
+}
+
+// Unclosed delimiters, code spans and links and long paragraphs used to make
+// inline parsing quadratic, several of these took tens of seconds when it was
+func TestMarkdownLargeInput(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+	}{
+		{name: "unclosed underscores", source: strings.Repeat("_a ", 100_000)},
+		{name: "unclosed asterisks", source: strings.Repeat("*a ", 100_000)},
+		{name: "long paragraph", source: strings.Repeat("word\n", 100_000)},
+		{name: "unclosed code spans", source: strings.Repeat("`a ``b ", 50_000)},
+		{name: "unclosed links", source: strings.Repeat("[a](", 100_000)},
+		{name: "unclosed brackets", source: strings.Repeat("[", 200_000)},
+		{name: "unclosed autolinks", source: strings.Repeat("<", 200_000) + ">"},
+		{name: "adjacent bold", source: strings.Repeat("**a**", 100_000)},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			start := time.Now()
+			nodes := markdown.Parse(testCase.source)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("expected parsing to be linear, took %v", elapsed)
+			}
+			if len(nodes) == 0 {
+				t.Errorf("expected content to be parsed")
+			}
+		})
+	}
+}
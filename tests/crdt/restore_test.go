@@ -0,0 +1,120 @@
+package crdt
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/rejdeboer/multiplayer-server/internal/markdown"
+	"github.com/rejdeboer/multiplayer-server/internal/render"
+	"github.com/rejdeboer/multiplayer-server/internal/yjs"
+)
+
+const RESTORE_CLIENT = 1000
+
+func TestRestoreFixtures(t *testing.T) {
+	for _, name := range fixtureNames(t) {
+		updates, _ := loadFixture(t, name)
+		for clock := -1; clock < len(updates)-1; clock++ {
+			t.Run(name, func(t *testing.T) {
+				restored := restore(t, updates, updates[:clock+1], RESTORE_CLIENT)
+				assertSameContent(t, restored, merge(t, updates[:clock+1]))
+			})
+		}
+	}
+}
+
+// TestRestoreRemovedContent restores a document that was cleared, so the
+// removed content including nested xml elements has to be inserted again
+func TestRestoreRemovedContent(t *testing.T) {
+	for _, name := range fixtureNames(t) {
+		t.Run(name, func(t *testing.T) {
+			updates, _ := loadFixture(t, name)
+
+			cleared := restore(t, updates, nil, RESTORE_CLIENT)
+			clearUpdate := yjs.EncodeRestore(merge(t, updates), yjs.NewDoc(), RESTORE_CLIENT)
+			history := append(append([][]byte{}, updates...), clearUpdate)
+			assertSameContent(t, cleared, yjs.NewDoc())
+
+			restored := restore(t, history, updates, RESTORE_CLIENT+1)
+			assertSameContent(t, restored, merge(t, updates))
+		})
+	}
+}
+
+func TestRestoreImportedMarkdown(t *testing.T) {
+	first := yjs.EncodeXmlFragment(1, "default", markdown.Parse("# Title\n\nSome **bold** text\n\n- one\n- two\n"))
+	second := yjs.EncodeXmlFragment(2, "default", markdown.Parse("Appended `code`\n"))
+	updates := [][]byte{first, second}
+
+	restored := restore(t, updates, updates[:1], RESTORE_CLIENT)
+	expected := "# Title\n\nSome **bold** text\n\n- one\n- two\n"
+	if output := renderString(t, render.Markdown, restored); output != expected {
+		t.Errorf("expected %q got %q", expected, output)
+	}
+}
+
+func TestRestoreWithoutChanges(t *testing.T) {
+	updates, _ := loadFixture(t, "text")
+	doc := merge(t, updates)
+	if update := yjs.EncodeRestore(doc, merge(t, updates), RESTORE_CLIENT); update != nil {
+		t.Errorf("expected no update got %v", update)
+	}
+}
+
+// restore encodes the restore of the target updates and applies it after
+// all updates, the way the server persists it
+func restore(t *testing.T, updates [][]byte, target [][]byte, client uint64) *yjs.Doc {
+	update := yjs.EncodeRestore(merge(t, updates), merge(t, target), client)
+	if update == nil {
+		return merge(t, updates)
+	}
+
+	return merge(t, append(append([][]byte{}, updates...), update))
+}
+
+func merge(t *testing.T, updates [][]byte) *yjs.Doc {
+	doc, err := yjs.MergeUpdates(updates)
+	if err != nil {
+		t.Fatalf("error merging updates: %v", err)
+	}
+	return doc
+}
+
+// assertSameContent compares the rendered json, roots that are empty are
+// left out because a restored document keeps the roots it had
+func assertSameContent(t *testing.T, doc *yjs.Doc, expected *yjs.Doc) {
+	content := nonEmptyRoots(t, doc)
+	expectedContent := nonEmptyRoots(t, expected)
+	if !reflect.DeepEqual(content, expectedContent) {
+		t.Errorf("expected %v got %v", expectedContent, content)
+	}
+}
+
+func nonEmptyRoots(t *testing.T, doc *yjs.Doc) map[string]any {
+	raw, err := json.Marshal(render.JSON(doc))
+	if err != nil {
+		t.Fatalf("error marshalling content: %v", err)
+	}
+	var content map[string]any
+	err = json.Unmarshal(raw, &content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range content {
+		switch v := value.(type) {
+		case []any:
+			if len(v) == 0 {
+				delete(content, name)
+			}
+		case map[string]any:
+			if len(v) == 0 {
+				delete(content, name)
+			}
+		case nil:
+			delete(content, name)
+		}
+	}
+	return content
+}
@@ -4,18 +4,25 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 	"github.com/brianvoe/gofakeit"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/refresh"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rejdeboer/multiplayer-server/internal/application"
 	"github.com/rejdeboer/multiplayer-server/internal/configuration"
 	"github.com/rejdeboer/multiplayer-server/internal/db"
+	"github.com/rejdeboer/multiplayer-server/internal/eventbus"
+	"github.com/rejdeboer/multiplayer-server/internal/indexing"
+	"github.com/rejdeboer/multiplayer-server/internal/mailer"
+	"github.com/rejdeboer/multiplayer-server/internal/outbox"
 	"github.com/rejdeboer/multiplayer-server/internal/routes"
-	"github.com/segmentio/kafka-go"
+	"github.com/rejdeboer/multiplayer-server/internal/storage"
+	"github.com/rejdeboer/multiplayer-server/internal/trash"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -24,6 +31,12 @@ var app *TestApp
 type TestApp struct {
 	Handler      http.Handler
 	SigningKey   string
+	Publisher    *eventbus.MemoryPublisher
+	Relay        *outbox.Relay
+	Storage      *storage.LocalStore
+	ObjectStores map[string]storage.ObjectStore
+	Purger       *trash.Purger
+	Mailer       *mailer.MemoryMailer
 	dbpool       *pgxpool.Pool
 	searchClient *elasticsearch.TypedClient
 }
@@ -37,31 +50,77 @@ type TestUser struct {
 
 // Should be run in the main test function
 func InitApplication(settings configuration.Settings) {
-	producer := &kafka.Writer{
-		Addr:     kafka.TCP(settings.Application.KafkaEndpoint),
-		Balancer: &kafka.LeastBytes{},
-	}
-
 	dbpool := application.GetDbConnectionPool(settings.Database)
 
 	searchClient := application.GetSearchClient(settings.Application.ElasticsearchEndpoint)
 
-	blobClient := application.GetBlobClient(settings.Azure)
-	_, err := blobClient.CreateContainer(context.Background(), routes.USER_IMAGES_CONTAINER, &container.CreateOptions{})
+	storagePath, err := os.MkdirTemp("", "multiplayer-storage-*")
+	if err != nil {
+		log.Fatalf("error creating storage directory: %v", err)
+	}
+
+	store, err := storage.NewLocalStore(
+		storagePath,
+		settings.Storage.PublicUrl,
+		settings.Application.SigningKey,
+		settings.Storage.PublicContainers,
+	)
+	if err != nil {
+		log.Fatalf("error creating object store: %v", err)
+	}
+
+	err = store.EnsureContainer(context.Background(), routes.USER_IMAGES_CONTAINER)
 	if err != nil {
 		log.Fatalf("error creating user images container: %v", err)
 	}
 
+	s3Store, err := storage.NewS3Store(
+		settings.Storage.S3Endpoint,
+		settings.Storage.S3Region,
+		settings.Storage.S3AccessKey,
+		settings.Storage.S3SecretKey,
+		settings.Storage.S3UseSsl,
+	)
+	if err != nil {
+		log.Fatalf("error creating s3 object store: %v", err)
+	}
+
+	memoryMailer := mailer.NewMemoryMailer()
+
 	handler := routes.CreateHandler(settings, &routes.Env{
-		Pool:         dbpool,
-		Producer:     producer,
-		Blob:         application.GetBlobClient(settings.Azure),
-		SearchClient: searchClient,
+		Pool:           dbpool,
+		Storage:        store,
+		SearchClient:   searchClient,
+		TrashRetention: settings.Trash.Retention(),
+		Mailer:         memoryMailer,
+		SignupUrl:      settings.Mailer.SignupUrl,
 	})
 
+	publisher := eventbus.NewMemoryPublisher()
+
 	app = &TestApp{
-		Handler:      handler,
-		SigningKey:   settings.Application.SigningKey,
+		Handler:    handler,
+		SigningKey: settings.Application.SigningKey,
+		Publisher:  publisher,
+		Storage:    store,
+		ObjectStores: map[string]storage.ObjectStore{
+			storage.LOCAL: store,
+			storage.AZURE: storage.NewAzureStore(application.GetBlobClient(settings.Azure)),
+			storage.S3:    s3Store,
+		},
+		Relay: &outbox.Relay{
+			Pool:           dbpool,
+			Publisher:      publisher,
+			BatchSize:      100,
+			PublishTimeout: 10 * time.Second,
+			MaxAttempts:    3,
+		},
+		Purger: &trash.Purger{
+			Pool:      dbpool,
+			Retention: settings.Trash.Retention(),
+			BatchSize: 100,
+		},
+		Mailer:       memoryMailer,
 		dbpool:       dbpool,
 		searchClient: searchClient,
 	}
@@ -131,15 +190,101 @@ func (app *TestApp) GetTestDocument(ownerID uuid.UUID) routes.DocumentResponse {
 	err = q.CreateDocumentContributor(context.Background(), db.CreateDocumentContributorParams{
 		DocumentID: document.ID,
 		UserID:     ownerID,
+		Role:       routes.ROLE_OWNER,
 	})
 	if err != nil {
 		log.Fatalf("error storing owner as contributor in db: %s", err)
 	}
 
 	return routes.DocumentResponse{
-		ID:   document.ID,
-		Name: document.Name,
+		ID:        document.ID,
+		Name:      document.Name,
+		OwnerID:   document.OwnerID,
+		CreatedAt: document.CreatedAt.Time,
+		UpdatedAt: document.UpdatedAt.Time,
+	}
+}
+
+func (app *TestApp) AddTestContributor(documentID uuid.UUID, userID uuid.UUID) {
+	app.AddTestContributorWithRole(documentID, userID, routes.ROLE_EDITOR)
+}
+
+func (app *TestApp) AddTestContributorWithRole(documentID uuid.UUID, userID uuid.UUID, role string) {
+	q := db.New(app.dbpool)
+
+	err := q.CreateDocumentContributor(context.Background(), db.CreateDocumentContributorParams{
+		DocumentID: documentID,
+		UserID:     userID,
+		Role:       role,
+	})
+	if err != nil {
+		log.Fatalf("error storing test contributor in db: %s", err)
+	}
+}
+
+// AddTestDocumentUpdate appends an update the way the websocket server does
+func (app *TestApp) AddTestDocumentUpdate(documentID uuid.UUID, authorID uuid.UUID, value []byte) {
+	app.AddTestDocumentUpdateAt(documentID, authorID, value, time.Now())
+}
+
+// AddTestDocumentUpdateAt appends an update as if it was persisted at createdAt
+func (app *TestApp) AddTestDocumentUpdateAt(documentID uuid.UUID, authorID uuid.UUID, value []byte, createdAt time.Time) {
+	_, err := app.dbpool.Exec(
+		context.Background(),
+		`INSERT INTO document_updates (document_id, clock, value, author_id, created_at)
+		SELECT $1, COALESCE(MAX(clock), -1) + 1, $2, $3, $4 FROM document_updates WHERE document_id = $1`,
+		documentID,
+		value,
+		authorID,
+		createdAt,
+	)
+	if err != nil {
+		log.Fatalf("error storing test document update in db: %s", err)
+	}
+}
+
+func (app *TestApp) GetReindexer(checkpointPath string) indexing.Reindexer {
+	return indexing.Reindexer{
+		Pool:           app.dbpool,
+		SearchClient:   app.searchClient,
+		BatchSize:      2,
+		CheckpointPath: checkpointPath,
+	}
+}
+
+func (app *TestApp) GetOutboxMessages(topic string, key string) []db.Outbox {
+	rows, err := app.dbpool.Query(
+		context.Background(),
+		"SELECT id, topic, key, value, attempts, last_error, created_at, delivered_at, failed_at FROM outbox WHERE topic = $1 AND key = $2 ORDER BY id",
+		topic,
+		[]byte(key),
+	)
+	if err != nil {
+		log.Fatalf("error fetching outbox messages: %s", err)
+	}
+
+	messages, err := pgx.CollectRows(rows, pgx.RowToStructByPos[db.Outbox])
+	if err != nil {
+		log.Fatalf("error scanning outbox messages: %s", err)
+	}
+	return messages
+}
+
+func (app *TestApp) GetAuditLog(documentID uuid.UUID) []db.AuditLog {
+	rows, err := app.dbpool.Query(
+		context.Background(),
+		"SELECT id, document_id, actor_id, action, details, created_at FROM audit_log WHERE document_id = $1 ORDER BY id",
+		documentID,
+	)
+	if err != nil {
+		log.Fatalf("error fetching audit log: %s", err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[db.AuditLog])
+	if err != nil {
+		log.Fatalf("error scanning audit log: %s", err)
 	}
+	return entries
 }
 
 func (app *TestApp) InsertElasticsearch(index string, doc interface{}) {
@@ -7,12 +7,11 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
-	"github.com/elastic/go-elasticsearch/v8/typedapi/indices/create"
-	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -20,12 +19,19 @@ import (
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/rejdeboer/multiplayer-server/internal/application"
+	"github.com/rejdeboer/multiplayer-server/internal/indexing"
+)
+
+const (
+	MINIO_ACCESS_KEY = "minioadmin"
+	MINIO_SECRET_KEY = "minioadmin"
 )
 
 type Cluster struct {
 	pool                   *dockertest.Pool
 	postgresContainer      *dockertest.Resource
 	azuriteContainer       *dockertest.Resource
+	minioContainer         *dockertest.Resource
 	elasticsearchContainer *dockertest.Resource
 }
 
@@ -43,6 +49,10 @@ func (cluster *Cluster) GetAzuriteHostPort() string {
 	return cluster.azuriteContainer.GetHostPort("10000/tcp")
 }
 
+func (cluster *Cluster) GetMinioHostPort() string {
+	return cluster.minioContainer.GetHostPort("9000/tcp")
+}
+
 func (cluster *Cluster) GetElasticsearchEndpoint() string {
 	return "http://" + cluster.elasticsearchContainer.GetHostPort("9200/tcp")
 }
@@ -56,6 +66,10 @@ func (cluster *Cluster) Purge() {
 		fmt.Printf("could not purge azurite: %s", err)
 	}
 
+	if err := cluster.pool.Purge(cluster.minioContainer); err != nil {
+		fmt.Printf("could not purge minio: %s", err)
+	}
+
 	if err := cluster.pool.Purge(cluster.elasticsearchContainer); err != nil {
 		fmt.Printf("could not purge elasticsearch: %s", err)
 	}
@@ -66,12 +80,14 @@ func SpawnCluster() *Cluster {
 	postgresContainer := createPostgresContainer(pool)
 	elasticsearchContainer := createElasticsearchContainer(pool)
 	azuriteContainer := createAzuriteContainer(pool)
+	minioContainer := createMinioContainer(pool)
 
 	cluster := Cluster{
 		pool:                   pool,
 		postgresContainer:      postgresContainer,
 		elasticsearchContainer: elasticsearchContainer,
 		azuriteContainer:       azuriteContainer,
+		minioContainer:         minioContainer,
 	}
 
 	db, err := sql.Open("pgx", cluster.GetDBUrl())
@@ -95,6 +111,10 @@ func SpawnCluster() *Cluster {
 		log.Fatalf("azurite container not intialized: %s", err)
 	}
 
+	if err := pool.Retry(waitMinioContainerToBeReady(cluster.GetMinioHostPort())); err != nil {
+		log.Fatalf("minio container not intialized: %s", err)
+	}
+
 	return &cluster
 }
 
@@ -151,6 +171,27 @@ func createAzuriteContainer(dockerPool *dockertest.Pool) *dockertest.Resource {
 	return container
 }
 
+func createMinioContainer(dockerPool *dockertest.Pool) *dockertest.Resource {
+	container, err := dockerPool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "minio/minio",
+		Tag:        "latest",
+		Cmd:        []string{"server", "/data"},
+		Env: []string{
+			"MINIO_ROOT_USER=" + MINIO_ACCESS_KEY,
+			"MINIO_ROOT_PASSWORD=" + MINIO_SECRET_KEY,
+		},
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		log.Fatalf("could not start minio: %s", err)
+	}
+
+	container.Expire(120)
+	return container
+}
+
 func createElasticsearchContainer(dockerPool *dockertest.Pool) *dockertest.Resource {
 	container, err := dockerPool.RunWithOptions(&dockertest.RunOptions{
 		Repository: "docker.elastic.co/elasticsearch/elasticsearch",
@@ -180,8 +221,25 @@ func waitPostgresContainerToBeReady(db *sql.DB) func() error {
 
 func waitAzuriteContainerToBeReady(address string) func() error {
 	return func() error {
-		_, err := net.Dial("tcp", address)
-		return err
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+func waitMinioContainerToBeReady(address string) func() error {
+	return func() error {
+		res, err := http.Get("http://" + address + "/minio/health/live")
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return errors.New("minio is not ready yet")
+		}
+		return nil
 	}
 }
 
@@ -215,17 +273,7 @@ func startMigration(db *sql.DB) {
 }
 
 func createElasticsearchIndices(searchClient *elasticsearch.TypedClient) {
-	_, err := searchClient.Indices.Create("users").
-		Request(&create.Request{
-			Mappings: &types.TypeMapping{
-				Properties: map[string]types.Property{
-					"id":       types.NewTextProperty(),
-					"username": types.NewTextProperty(),
-					"email":    types.NewTextProperty(),
-				},
-			},
-		}).
-		Do(nil)
+	err := indexing.CreateIndex(context.Background(), searchClient, indexing.USERS_INDEX, indexing.USERS_INDEX)
 	if err != nil {
 		log.Fatal("error creating users index in elasticsearch")
 	}
@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rejdeboer/multiplayer-server/internal/events"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ValidateEvent checks a published message against the envelope schema and the schema of its data
+func ValidateEvent(value []byte) error {
+	envelopeSchema, err := events.EnvelopeSchema()
+	if err != nil {
+		return err
+	}
+
+	err = validateSchema(envelopeSchema, value)
+	if err != nil {
+		return fmt.Errorf("invalid envelope: %w", err)
+	}
+
+	var envelope events.Envelope
+	err = json.Unmarshal(value, &envelope)
+	if err != nil {
+		return err
+	}
+
+	dataSchema, err := events.DataSchema(envelope.Type, envelope.SchemaVersion)
+	if err != nil {
+		return fmt.Errorf("no schema for %s v%d: %w", envelope.Type, envelope.SchemaVersion, err)
+	}
+
+	err = validateSchema(dataSchema, envelope.Data)
+	if err != nil {
+		return fmt.Errorf("invalid %s data: %w", envelope.Type, err)
+	}
+
+	return nil
+}
+
+func validateSchema(schema []byte, document []byte) error {
+	result, err := gojsonschema.Validate(
+		gojsonschema.NewBytesLoader(schema),
+		gojsonschema.NewBytesLoader(document),
+	)
+	if err != nil {
+		return err
+	}
+
+	if !result.Valid() {
+		violations := []string{}
+		for _, violation := range result.Errors() {
+			violations = append(violations, violation.String())
+		}
+		return fmt.Errorf("%s", strings.Join(violations, "; "))
+	}
+
+	return nil
+}
@@ -6,3 +6,30 @@ pub struct Document {
     pub owner_id: Uuid,
     pub state_vector: Option<Vec<u8>>,
 }
+
+// The role of a user on a document, see document_contributors.role
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Role {
+    Owner,
+    Editor,
+    Commenter,
+    Viewer,
+}
+
+impl Role {
+    pub fn parse(role: &str) -> Option<Self> {
+        match role {
+            "owner" => Some(Self::Owner),
+            "editor" => Some(Self::Editor),
+            "commenter" => Some(Self::Commenter),
+            "viewer" => Some(Self::Viewer),
+            _ => None,
+        }
+    }
+
+    // Commenters and viewers receive updates and share their awareness, but
+    // only owners and editors change the content
+    pub fn can_edit(&self) -> bool {
+        matches!(self, Self::Owner | Self::Editor)
+    }
+}
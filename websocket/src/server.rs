@@ -7,12 +7,16 @@ use axum::{
 };
 use axum_extra::TypedHeader;
 use serde::Deserialize;
-use sqlx::{postgres::PgPoolOptions, PgPool};
+use sqlx::{
+    postgres::{PgListener, PgPoolOptions},
+    PgPool,
+};
 use std::{
     collections::HashMap,
     net::SocketAddr,
     str::FromStr,
     sync::{Arc, Mutex},
+    time::Duration,
 };
 use tokio::{
     net::TcpListener,
@@ -24,11 +28,15 @@ use uuid::Uuid;
 use crate::{
     auth::{auth_middleware, User},
     configuration::{DatabaseSettings, Settings},
-    document::Document,
+    document::{Document, Role},
     error::ApiError,
     websocket::{handle_socket, Message, Syncer},
 };
 
+// The api appends updates itself when a version is restored, it notifies
+// this channel with "<document_id>:<clock>" once the update is committed
+pub const STORED_UPDATES_CHANNEL: &str = "document_updates";
+
 pub struct Application {
     listener: TcpListener,
     router: Router,
@@ -61,6 +69,8 @@ impl Application {
             doc_handles: Mutex::new(HashMap::new()),
         });
 
+        tokio::spawn(listen_for_stored_updates(application_state.clone()));
+
         let router = Router::new()
             .route("/:document_id", get(ws_handler))
             .route_layer(middleware::from_fn_with_state(
@@ -100,6 +110,60 @@ pub fn get_connection_pool(settings: &DatabaseSettings) -> PgPool {
     PgPoolOptions::new().connect_lazy_with(settings.with_db())
 }
 
+// listen_for_stored_updates hands updates stored by the api to the syncer of
+// the document, so clients that are connected receive them right away
+async fn listen_for_stored_updates(state: Arc<ApplicationState>) {
+    let mut listener = match PgListener::connect_with(&state.pool).await {
+        Ok(listener) => listener,
+        Err(error) => {
+            tracing::error!(?error, "error connecting listener for stored updates");
+            return;
+        }
+    };
+    if let Err(error) = listener.listen(STORED_UPDATES_CHANNEL).await {
+        tracing::error!(?error, "error listening for stored updates");
+        return;
+    }
+
+    loop {
+        let notification = match listener.recv().await {
+            Ok(notification) => notification,
+            Err(error) => {
+                tracing::error!(?error, "error receiving stored update notification");
+                tokio::time::sleep(Duration::from_secs(1)).await;
+                continue;
+            }
+        };
+
+        let Some((document_id, clock)) = parse_stored_update(notification.payload()) else {
+            tracing::warn!(
+                payload = notification.payload(),
+                "invalid stored update notification"
+            );
+            continue;
+        };
+
+        // Documents without connected clients have no syncer, they will
+        // receive the update when they sync
+        let doc_handle = state
+            .doc_handles
+            .lock()
+            .expect("received handles lock")
+            .get(&document_id)
+            .cloned();
+        if let Some(tx) = doc_handle {
+            if tx.send(Message::StoredUpdate(clock)).await.is_err() {
+                tracing::debug!(%document_id, "syncer stopped before receiving stored update");
+            }
+        }
+    }
+}
+
+fn parse_stored_update(payload: &str) -> Option<(Uuid, i32)> {
+    let (document_id, clock) = payload.split_once(':')?;
+    Some((Uuid::from_str(document_id).ok()?, clock.parse().ok()?))
+}
+
 // TODO: Add connection context for tracing
 async fn ws_handler(
     ws: WebSocketUpgrade,
@@ -123,7 +187,7 @@ async fn ws_handler(
         r#"
         SELECT id, owner_id, name, state_vector
         FROM documents
-        WHERE id = $1 
+        WHERE id = $1 AND deleted_at IS NULL
         "#,
         document_id
     )
@@ -134,18 +198,39 @@ async fn ws_handler(
         ApiError::DocumentNotFoundError(document_id)
     })?;
 
-    if document.owner_id != user.id {
+    let role = if document.owner_id == user.id {
+        Some(Role::Owner)
+    } else {
+        sqlx::query!(
+            r#"
+            SELECT role
+            FROM document_contributors
+            WHERE document_id = $1 AND user_id = $2
+            "#,
+            document_id,
+            user.id
+        )
+        .fetch_optional(&state.pool)
+        .await
+        .map_err(|error| {
+            tracing::error!(?error, "error fetching contributor");
+            ApiError::DocumentNotFoundError(document_id)
+        })?
+        .and_then(|contributor| Role::parse(&contributor.role))
+    };
+
+    let Some(role) = role else {
         tracing::error!(
             ?user,
             document = %document_id,
             "user does not have access to document"
         );
         return Err(ApiError::DocumentNotFoundError(document_id));
-    }
+    };
 
     let doc_handle = get_or_create_doc_handle(state, document);
 
-    Ok(ws.on_upgrade(move |socket| handle_socket(socket, user, doc_handle)))
+    Ok(ws.on_upgrade(move |socket| handle_socket(socket, user, role, doc_handle)))
 }
 
 fn get_or_create_doc_handle(state: Arc<ApplicationState>, document: Document) -> Sender<Message> {
@@ -5,7 +5,7 @@ use axum::extract::ws::WebSocket;
 use tokio::sync::mpsc::{channel, Sender};
 use uuid::Uuid;
 
-use crate::auth::User;
+use crate::{auth::User, document::Role};
 
 use self::client::Client;
 pub use syncer::Syncer;
@@ -18,19 +18,21 @@ pub const MESSAGE_SYNC_STEP_2: u8 = 2;
 pub const MESSAGE_AWARENESS_UPDATE: u8 = 3;
 pub const MESSAGE_GET_AWARENESS: u8 = 3;
 
-pub async fn handle_socket(socket: WebSocket, user: User, doc_handle: Sender<Message>) {
+pub async fn handle_socket(socket: WebSocket, user: User, role: Role, doc_handle: Sender<Message>) {
     let (client_tx, client_rx) = channel(128);
-    let client = Client::new(user, client_tx, doc_handle);
+    let client = Client::new(user, role, client_tx, doc_handle);
 
     client.run(socket, client_rx).await;
 }
 
 #[derive(Debug, Clone)]
 pub enum Message {
-    Connect(Uuid, Sender<Vec<u8>>),
+    Connect(Uuid, Sender<Vec<u8>>, Role),
     Disconnect(Uuid),
-    Update(Uuid, Vec<u8>),
+    Update(Uuid, Uuid, Vec<u8>),
     GetDiff(Uuid, Vec<u8>),
     UpdateAwareness(Uuid, Vec<u8>),
     GetAwareness(Uuid),
+    // An update the api appended to document_updates, identified by its clock
+    StoredUpdate(i32),
 }
@@ -6,7 +6,7 @@ use tokio::sync::mpsc::{error::SendError, Receiver, Sender};
 use tracing::instrument;
 use uuid::Uuid;
 
-use crate::auth::User;
+use crate::{auth::User, document::Role};
 
 use super::Message;
 
@@ -16,19 +16,26 @@ pub struct Client {
     client_tx: Sender<Vec<u8>>,
     syncer_tx: Sender<Message>,
     user: User,
+    role: Role,
 }
 
 impl Client {
-    pub fn new(user: User, client_tx: Sender<Vec<u8>>, syncer_tx: Sender<Message>) -> Self {
+    pub fn new(
+        user: User,
+        role: Role,
+        client_tx: Sender<Vec<u8>>,
+        syncer_tx: Sender<Message>,
+    ) -> Self {
         Self {
             id: Uuid::new_v4(),
             client_tx,
             syncer_tx,
             user,
+            role,
         }
     }
 
-    #[instrument(name="websocket client", skip_all, fields(user = ?self.user))]
+    #[instrument(name="websocket client", skip_all, fields(user = ?self.user, role = ?self.role))]
     pub async fn run(self, socket: WebSocket, client_rx: Receiver<Vec<u8>>) {
         let (ws_tx, mut ws_rx) = socket.split();
 
@@ -37,7 +44,7 @@ impl Client {
         });
 
         self.syncer_tx
-            .send(Message::Connect(self.id, self.client_tx.clone()))
+            .send(Message::Connect(self.id, self.client_tx.clone(), self.role))
             .await
             .expect("client connects to syncer");
         tracing::info!("new client connected");
@@ -90,7 +97,9 @@ impl Client {
         match message_type {
             super::MESSAGE_UPDATE | super::MESSAGE_SYNC_STEP_2 => {
                 tracing::info!("sending sync step 2 update");
-                self.syncer_tx.send(Message::Update(self.id, bytes)).await?;
+                self.syncer_tx
+                    .send(Message::Update(self.id, self.user.id, bytes))
+                    .await?;
             }
             super::MESSAGE_SYNC_STEP_1 => {
                 self.syncer_tx
@@ -1,6 +1,10 @@
 use futures::future::join_all;
 use rand::seq::IteratorRandom;
-use std::{collections::HashMap, ops::ControlFlow, sync::Arc};
+use std::{
+    collections::{HashMap, HashSet},
+    ops::ControlFlow,
+    sync::Arc,
+};
 use tokio::sync::mpsc::{Receiver, Sender};
 use yrs::{
     updates::{decoder::Decode, encoder::Encode},
@@ -17,6 +21,8 @@ use super::Message;
 
 pub struct Syncer {
     clients: HashMap<Uuid, Sender<Vec<u8>>>,
+    // Clients whose role does not allow them to change the content
+    read_only: HashSet<Uuid>,
     document_id: Uuid,
     state_vector: StateVector,
     rx: Receiver<Message>,
@@ -38,6 +44,7 @@ impl Syncer {
 
         Self {
             clients: HashMap::new(),
+            read_only: HashSet::new(),
             rx,
             state,
             document_id,
@@ -63,11 +70,15 @@ impl Syncer {
 
     async fn process_message(&mut self, message: Message) -> ControlFlow<(), ()> {
         match message {
-            Message::Connect(id, tx) => {
+            Message::Connect(id, tx, role) => {
                 self.clients.insert(id, tx);
+                if !role.can_edit() {
+                    self.read_only.insert(id);
+                }
             }
             Message::Disconnect(id) => {
                 self.clients.remove(&id);
+                self.read_only.remove(&id);
                 if self.clients.is_empty() {
                     let mut doc_handles = self
                         .state
@@ -78,13 +89,27 @@ impl Syncer {
                     return ControlFlow::Break(());
                 };
             }
-            Message::Update(id, mut update) => {
+            Message::Update(id, author_id, mut update) => {
+                if self.read_only.contains(&id) {
+                    tracing::warn!(%id, %author_id, "dropping update from read-only client");
+                    return ControlFlow::Continue(());
+                }
+
+                // The last byte is the message type
+                let state_vector = match Update::decode_v1(&update[..update.len() - 1]) {
+                    Ok(decoded) => decoded.state_vector(),
+                    Err(error) => {
+                        tracing::error!(%id, %author_id, ?error, "dropping update that could not be decoded");
+                        return ControlFlow::Continue(());
+                    }
+                };
+
                 self.forward_update(id, update.clone()).await;
 
                 // Remove message type
                 update.pop();
 
-                self.store_update(update).await;
+                self.store_update(author_id, update, state_vector).await;
             }
             Message::GetDiff(id, mut state_vector) => {
                 tracing::info!(%id, "received GetDiff message");
@@ -111,6 +136,28 @@ impl Syncer {
                     .await
                     .expect("SyncStep1 message sent to client");
             }
+            Message::StoredUpdate(clock) => {
+                tracing::info!(clock, "received update stored by the api");
+                let Some(mut update) =
+                    get_document_update(self.document_id, clock, self.state.pool.clone()).await
+                else {
+                    tracing::warn!(clock, "stored update not found");
+                    return ControlFlow::Continue(());
+                };
+
+                let state_vector = match Update::decode_v1(&update) {
+                    Ok(decoded) => decoded.state_vector(),
+                    Err(error) => {
+                        tracing::error!(clock, ?error, "stored update could not be decoded");
+                        return ControlFlow::Continue(());
+                    }
+                };
+                self.state_vector.merge(state_vector);
+
+                // The api is not one of the clients, so every client receives it
+                update.push(super::MESSAGE_UPDATE);
+                self.forward_update(Uuid::nil(), update).await;
+            }
             Message::UpdateAwareness(id, update) => {
                 self.forward_update(id, update).await;
             }
@@ -137,19 +184,37 @@ impl Syncer {
         ControlFlow::Continue(())
     }
 
-    async fn store_update(&mut self, update: Vec<u8>) {
+    async fn store_update(
+        &mut self,
+        author_id: Uuid,
+        update: Vec<u8>,
+        mut state_vector: StateVector,
+    ) {
         let pool = self.state.pool.clone();
         let document_id = self.document_id;
 
-        let mut state_vector = Update::decode_v1(&update)
-            .expect("update decoded")
-            .state_vector();
-
         let mut txn = pool
             .begin()
             .await
             .expect("receive pool connection for update transaction");
 
+        // The row lock serialises writers, the api takes it too when it
+        // appends a restore or import, so clocks never collide and the
+        // stored state vector is not overwritten with a stale one
+        let stored_state_vector = sqlx::query!(
+            r#"
+            SELECT state_vector
+            FROM documents
+            WHERE id = $1
+            FOR UPDATE;
+            "#,
+            document_id
+        )
+        .fetch_one(&mut *txn)
+        .await
+        .expect("lock document")
+        .state_vector;
+
         let current_clock = sqlx::query!(
             r#"
             SELECT COALESCE(MAX(clock), -1) as value 
@@ -164,16 +229,28 @@ impl Syncer {
         .value
         .unwrap();
 
+        if let Some(stored) = stored_state_vector {
+            let stored = match StateVector::decode_v1(&stored) {
+                Ok(stored) => stored,
+                Err(error) => {
+                    tracing::error!(%document_id, ?error, "stored state vector could not be decoded");
+                    txn.rollback().await.expect("transaction rolled back");
+                    return;
+                }
+            };
+            state_vector.merge(stored);
+        }
         state_vector.merge(self.state_vector.clone());
 
         let store_update = sqlx::query!(
             r#"
-            INSERT INTO document_updates (document_id, clock, value)
-            VALUES($1, $2, $3);
+            INSERT INTO document_updates (document_id, clock, value, author_id)
+            VALUES($1, $2, $3, $4);
             "#,
             document_id,
             current_clock + 1,
-            update
+            update,
+            author_id
         )
         .execute(&mut *txn)
         .await;
@@ -250,3 +327,19 @@ async fn get_document_updates(document_id: Uuid, pool: PgPool) -> Vec<Vec<u8>> {
     .map(|update| update.value)
     .collect::<_>()
 }
+
+async fn get_document_update(document_id: Uuid, clock: i32, pool: PgPool) -> Option<Vec<u8>> {
+    sqlx::query!(
+        r#"
+        SELECT value
+        FROM document_updates
+        WHERE document_id = $1 AND clock = $2;
+        "#,
+        document_id,
+        clock
+    )
+    .fetch_optional(&pool)
+    .await
+    .expect("retrieve document update")
+    .map(|update| update.value)
+}
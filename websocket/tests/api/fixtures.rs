@@ -0,0 +1,259 @@
+use std::{collections::HashMap, fs, path::PathBuf};
+
+use yrs::{
+    types::{text::YChange, ToJson},
+    updates::decoder::Decode,
+    Any, Array, Doc, GetString, Map, MapPrelim, OffsetKind, Options, ReadTxn, StateVector, Text,
+    TextPrelim, Transact, Update, XmlElementPrelim, XmlFragment, XmlTextPrelim,
+};
+
+// The Go decoder in internal/yjs is tested against these updates. Every
+// fixture is a directory with the updates of one document in the order the
+// server persists them. Run with UPDATE_YJS_FIXTURES=1 to write them again,
+// expected.json next to the updates is maintained by hand.
+struct Fixture {
+    name: &'static str,
+    doc: Doc,
+    updates: Vec<Vec<u8>>,
+    content: fn(&Doc) -> Any,
+}
+
+#[test]
+fn fixtures_decode_to_the_same_content() {
+    let update_fixtures = std::env::var("UPDATE_YJS_FIXTURES").is_ok();
+
+    for fixture in fixtures() {
+        let dir = fixtures_dir().join(fixture.name);
+        if update_fixtures {
+            write_fixture(&dir, &fixture.updates);
+        }
+
+        let decoded = Doc::with_options(options(100));
+        for update in read_fixture(&dir) {
+            decoded
+                .transact_mut()
+                .apply_update(Update::decode_v1(&update).unwrap());
+        }
+
+        assert_eq!(
+            (fixture.content)(&fixture.doc),
+            (fixture.content)(&decoded),
+            "fixture {} decodes to different content",
+            fixture.name
+        );
+    }
+}
+
+fn fixtures() -> Vec<Fixture> {
+    vec![text(), unicode(), map(), array(), xml(), concurrent()]
+}
+
+fn text() -> Fixture {
+    let doc = Doc::with_options(options(1));
+    let text = doc.get_or_insert_text("text");
+
+    let updates = vec![
+        record(&doc, |doc| {
+            text.insert(&mut doc.transact_mut(), 0, "Hello world");
+        }),
+        record(&doc, |doc| {
+            let bold = HashMap::from([("bold".into(), Any::Bool(true))]);
+            text.format(&mut doc.transact_mut(), 6, 5, bold);
+        }),
+    ];
+
+    Fixture {
+        name: "text",
+        doc,
+        updates,
+        content: |doc| {
+            let text = doc.get_or_insert_text("text");
+            Any::from(format!(
+                "{:?}",
+                text.diff(&doc.transact(), YChange::identity)
+            ))
+        },
+    }
+}
+
+fn unicode() -> Fixture {
+    let doc = Doc::with_options(options(1));
+    let text = doc.get_or_insert_text("text");
+
+    let updates = vec![
+        record(&doc, |doc| {
+            text.insert(&mut doc.transact_mut(), 0, "👋 héllo");
+        }),
+        record(&doc, |doc| {
+            text.remove_range(&mut doc.transact_mut(), 0, 2);
+        }),
+    ];
+
+    Fixture {
+        name: "unicode",
+        doc,
+        updates,
+        content: |doc| Any::from(doc.get_or_insert_text("text").get_string(&doc.transact())),
+    }
+}
+
+fn map() -> Fixture {
+    let doc = Doc::with_options(options(1));
+    let map = doc.get_or_insert_map("map");
+
+    let updates = vec![
+        record(&doc, |doc| {
+            let mut txn = doc.transact_mut();
+            map.insert(&mut txn, "title", "Doc");
+            map.insert(&mut txn, "count", Any::Number(3.0));
+            map.insert(
+                &mut txn,
+                "nested",
+                MapPrelim::<bool>::from(HashMap::from([("a".into(), true)])),
+            );
+        }),
+        record(&doc, |doc| {
+            map.insert(&mut doc.transact_mut(), "title", "Doc 2");
+        }),
+    ];
+
+    Fixture {
+        name: "map",
+        doc,
+        updates,
+        content: |doc| doc.get_or_insert_map("map").to_json(&doc.transact()),
+    }
+}
+
+fn array() -> Fixture {
+    let doc = Doc::with_options(options(1));
+    let list = doc.get_or_insert_array("list");
+
+    let updates = vec![
+        record(&doc, |doc| {
+            let mut txn = doc.transact_mut();
+            let three = HashMap::from([("three".to_string(), Any::Number(3.0))]);
+            list.insert_range(
+                &mut txn,
+                0,
+                vec![Any::Number(1.0), Any::from("two"), Any::from(three)],
+            );
+            list.push_back(&mut txn, TextPrelim::new("nested"));
+        }),
+        record(&doc, |doc| {
+            list.remove(&mut doc.transact_mut(), 1);
+        }),
+    ];
+
+    Fixture {
+        name: "array",
+        doc,
+        updates,
+        content: |doc| doc.get_or_insert_array("list").to_json(&doc.transact()),
+    }
+}
+
+fn xml() -> Fixture {
+    let doc = Doc::with_options(options(1));
+    let fragment = doc.get_or_insert_xml_fragment("prosemirror");
+
+    let updates = vec![record(&doc, |doc| {
+        let mut txn = doc.transact_mut();
+        let heading = fragment.push_back(&mut txn, XmlElementPrelim::empty("heading"));
+        heading.insert_attribute(&mut txn, "level", "2");
+        heading.push_back(&mut txn, XmlTextPrelim::new("Title"));
+
+        let paragraph = fragment.push_back(&mut txn, XmlElementPrelim::empty("paragraph"));
+        let text = paragraph.push_back(&mut txn, XmlTextPrelim::new("Hi "));
+        let bold = HashMap::from([("bold".into(), Any::Bool(true))]);
+        text.insert_with_attributes(&mut txn, 3, "there", bold);
+    })];
+
+    Fixture {
+        name: "xml",
+        doc,
+        updates,
+        content: |doc| {
+            Any::from(
+                doc.get_or_insert_xml_fragment("prosemirror")
+                    .get_string(&doc.transact()),
+            )
+        },
+    }
+}
+
+fn concurrent() -> Fixture {
+    let doc_a = Doc::with_options(options(1));
+    let text_a = doc_a.get_or_insert_text("text");
+    let doc_b = Doc::with_options(options(2));
+    let text_b = doc_b.get_or_insert_text("text");
+
+    let update_a = record(&doc_a, |doc| {
+        text_a.insert(&mut doc.transact_mut(), 0, "A");
+    });
+    let update_b = record(&doc_b, |doc| {
+        text_b.insert(&mut doc.transact_mut(), 0, "B");
+    });
+    doc_a
+        .transact_mut()
+        .apply_update(Update::decode_v1(&update_b).unwrap());
+    let update_c = record(&doc_a, |doc| {
+        let mut txn = doc.transact_mut();
+        text_a.remove_range(&mut txn, 0, 1);
+        text_a.push(&mut txn, "C");
+    });
+
+    Fixture {
+        name: "concurrent",
+        doc: doc_a,
+        updates: vec![update_a, update_b, update_c],
+        content: |doc| Any::from(doc.get_or_insert_text("text").get_string(&doc.transact())),
+    }
+}
+
+fn options(client_id: u64) -> Options {
+    Options {
+        client_id,
+        offset_kind: OffsetKind::Utf16,
+        ..Options::default()
+    }
+}
+
+// record returns the update of the changes made by f, the same diff a
+// client sends to the server after a transaction
+fn record(doc: &Doc, f: impl FnOnce(&Doc)) -> Vec<u8> {
+    let before: StateVector = doc.transact().state_vector();
+    f(doc);
+    doc.transact().encode_diff_v1(&before)
+}
+
+fn fixtures_dir() -> PathBuf {
+    PathBuf::from(env!("CARGO_MANIFEST_DIR")).join("../tests/resources/yjs")
+}
+
+fn write_fixture(dir: &PathBuf, updates: &[Vec<u8>]) {
+    fs::create_dir_all(dir).unwrap();
+    for path in read_fixture_paths(dir) {
+        fs::remove_file(path).unwrap();
+    }
+    for (i, update) in updates.iter().enumerate() {
+        fs::write(dir.join(format!("{i:02}.bin")), update).unwrap();
+    }
+}
+
+fn read_fixture(dir: &PathBuf) -> Vec<Vec<u8>> {
+    read_fixture_paths(dir)
+        .into_iter()
+        .map(|path| fs::read(path).unwrap())
+        .collect()
+}
+
+fn read_fixture_paths(dir: &PathBuf) -> Vec<PathBuf> {
+    let mut paths: Vec<PathBuf> = fs::read_dir(dir)
+        .unwrap()
+        .map(|entry| entry.unwrap().path())
+        .filter(|path| path.extension().is_some_and(|ext| ext == "bin"))
+        .collect();
+    paths.sort();
+    paths
+}
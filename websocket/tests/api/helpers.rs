@@ -39,6 +39,33 @@ impl TestApp {
         socket
     }
 
+    pub async fn create_contributor_client(
+        &self,
+        role: &str,
+    ) -> WebSocketStream<MaybeTlsStream<tokio::net::TcpStream>> {
+        let (document_id, _) = self.test_document().await;
+        let user_id = add_test_user(&self.db_pool).await;
+        sqlx::query!(
+            "INSERT INTO document_contributors (document_id, user_id, role)
+            VALUES ($1, $2, $3)",
+            document_id,
+            user_id,
+            role,
+        )
+        .execute(&self.db_pool)
+        .await
+        .expect("test contributor added");
+
+        let token = self.signed_jwt(user_id);
+        let request = self.create_connection_request(token, document_id);
+
+        let (socket, _response) = tokio_tungstenite::connect_async(request)
+            .await
+            .expect("websocket connected");
+
+        socket
+    }
+
     pub async fn test_document(&self) -> (Uuid, Uuid) {
         let row = sqlx::query!("SELECT id, owner_id FROM documents LIMIT 1")
             .fetch_one(&self.db_pool)
@@ -1,4 +1,5 @@
 mod awareness;
+mod fixtures;
 mod helpers;
 mod ping_pong;
 mod sync;
@@ -1,3 +1,5 @@
+use std::time::Duration;
+
 use futures::{SinkExt, StreamExt};
 use tokio_tungstenite::tungstenite;
 use yrs::{
@@ -90,3 +92,228 @@ async fn get_diff_after_update() {
         text_b.get_string(&doc_b.transact())
     );
 }
+
+#[tokio::test]
+async fn client_receives_update_stored_by_api() {
+    let app = spawn_app().await;
+    let (document_id, owner_id) = app.test_document().await;
+    let mut client = app.create_owner_client().await;
+
+    // Wait for the sync to finish so the client is known to the syncer
+    let mut sv = Doc::new().transact().state_vector().encode_v1();
+    sv.push(websocket::websocket::MESSAGE_SYNC_STEP_1);
+    client.send(tungstenite::Message::Binary(sv)).await.unwrap();
+    match client.next().await.unwrap().unwrap() {
+        tungstenite::Message::Binary(mut received) => assert_eq!(
+            received.pop(),
+            Some(websocket::websocket::MESSAGE_SYNC_STEP_2)
+        ),
+        other => panic!("expected a binary message but got {other:?}"),
+    };
+
+    let doc = Doc::new();
+    let text = doc.get_or_insert_text("test");
+    {
+        let mut txn = doc.transact_mut();
+        text.push(&mut txn, "restored");
+    }
+    let update = doc.transact().encode_diff_v1(&StateVector::default());
+
+    sqlx::query!(
+        "INSERT INTO document_updates (document_id, clock, value, author_id)
+        VALUES ($1, 0, $2, $3)",
+        document_id,
+        update,
+        owner_id,
+    )
+    .execute(&app.db_pool)
+    .await
+    .expect("update stored");
+    sqlx::query!(
+        "SELECT pg_notify('document_updates', $1)",
+        format!("{document_id}:0"),
+    )
+    .execute(&app.db_pool)
+    .await
+    .expect("update notified");
+
+    match client.next().await.unwrap().unwrap() {
+        tungstenite::Message::Binary(mut received) => match received.pop() {
+            Some(websocket::websocket::MESSAGE_UPDATE) => assert_eq!(update, received),
+            other => panic!("expected an update message but got {other:?}"),
+        },
+        other => panic!("expected a binary message but got {other:?}"),
+    };
+}
+
+#[tokio::test]
+async fn update_waits_for_concurrent_writer() {
+    let app = spawn_app().await;
+    let (document_id, owner_id) = app.test_document().await;
+    let mut client = app.create_owner_client().await;
+
+    let doc_api = Doc::new();
+    {
+        let text = doc_api.get_or_insert_text("test");
+        let mut txn = doc_api.transact_mut();
+        text.push(&mut txn, "restored");
+    }
+    let api_update = doc_api.transact().encode_diff_v1(&StateVector::default());
+
+    let doc_client = Doc::new();
+    {
+        let text = doc_client.get_or_insert_text("test");
+        let mut txn = doc_client.transact_mut();
+        text.push(&mut txn, "typed");
+    }
+    let mut client_update = doc_client
+        .transact()
+        .encode_diff_v1(&StateVector::default());
+    client_update.push(websocket::websocket::MESSAGE_UPDATE);
+
+    // Hold the document row lock like the api does while it appends a restore
+    let mut txn = app.db_pool.begin().await.expect("transaction started");
+    sqlx::query!(
+        "SELECT id FROM documents WHERE id = $1 FOR UPDATE",
+        document_id,
+    )
+    .fetch_one(&mut *txn)
+    .await
+    .expect("document locked");
+
+    client
+        .send(tungstenite::Message::Binary(client_update))
+        .await
+        .unwrap();
+    tokio::time::sleep(Duration::from_millis(200)).await;
+
+    sqlx::query!(
+        "INSERT INTO document_updates (document_id, clock, value, author_id)
+        VALUES ($1, 0, $2, $3)",
+        document_id,
+        api_update,
+        owner_id,
+    )
+    .execute(&mut *txn)
+    .await
+    .expect("update stored");
+    txn.commit().await.expect("transaction committed");
+
+    let mut clocks = Vec::new();
+    for _ in 0..50 {
+        clocks = sqlx::query!(
+            "SELECT clock FROM document_updates WHERE document_id = $1 ORDER BY clock",
+            document_id,
+        )
+        .fetch_all(&app.db_pool)
+        .await
+        .expect("fetched clocks")
+        .into_iter()
+        .map(|row| row.clock)
+        .collect::<Vec<i32>>();
+        if clocks.len() == 2 {
+            break;
+        }
+        tokio::time::sleep(Duration::from_millis(100)).await;
+    }
+    assert_eq!(vec![0, 1], clocks);
+}
+
+#[tokio::test]
+async fn only_editors_can_update() {
+    let app = spawn_app().await;
+    let (document_id, _) = app.test_document().await;
+    let mut owner = app.create_owner_client().await;
+
+    for role in ["viewer", "commenter", "editor"] {
+        let mut client = app.create_contributor_client(role).await;
+
+        let doc = Doc::new();
+        {
+            let text = doc.get_or_insert_text("test");
+            let mut txn = doc.transact_mut();
+            text.push(&mut txn, role);
+        }
+        let diff = doc.transact().encode_diff_v1(&StateVector::default());
+        let mut update = diff.clone();
+        update.push(websocket::websocket::MESSAGE_UPDATE);
+        client
+            .send(tungstenite::Message::Binary(update))
+            .await
+            .unwrap();
+
+        let received = tokio::time::timeout(Duration::from_millis(500), owner.next()).await;
+        match (role, received) {
+            ("editor", Ok(Some(Ok(tungstenite::Message::Binary(mut received))))) => {
+                assert_eq!(received.pop(), Some(websocket::websocket::MESSAGE_UPDATE));
+                assert_eq!(diff, received);
+            }
+            ("editor", other) => panic!("expected the editor update but got {other:?}"),
+            (_, Err(_)) => (),
+            (_, Ok(other)) => panic!("expected the {role} update to be dropped but got {other:?}"),
+        }
+    }
+
+    // The update is stored after it is forwarded
+    let mut stored = None;
+    for _ in 0..50 {
+        stored = sqlx::query!(
+            "SELECT COUNT(*) AS count FROM document_updates WHERE document_id = $1",
+            document_id,
+        )
+        .fetch_one(&app.db_pool)
+        .await
+        .expect("counted updates")
+        .count;
+        if stored != Some(0) {
+            break;
+        }
+        tokio::time::sleep(Duration::from_millis(100)).await;
+    }
+    assert_eq!(Some(1), stored);
+}
+
+#[tokio::test]
+async fn undecodable_update_is_dropped() {
+    let app = spawn_app().await;
+    let mut client_a = app.create_owner_client().await;
+    let mut client_b = app.create_owner_client().await;
+
+    client_a
+        .send(tungstenite::Message::Binary(vec![
+            0xff,
+            0xff,
+            websocket::websocket::MESSAGE_UPDATE,
+        ]))
+        .await
+        .unwrap();
+
+    let received = tokio::time::timeout(Duration::from_millis(500), client_b.next()).await;
+    assert!(
+        received.is_err(),
+        "expected the update to be dropped but got {received:?}"
+    );
+
+    // The syncer keeps running and forwards the next update
+    let doc = Doc::new();
+    {
+        let text = doc.get_or_insert_text("test");
+        let mut txn = doc.transact_mut();
+        text.push(&mut txn, "test");
+    }
+    let diff = doc.transact().encode_diff_v1(&StateVector::default());
+    let mut update = diff.clone();
+    update.push(websocket::websocket::MESSAGE_UPDATE);
+    client_a
+        .send(tungstenite::Message::Binary(update))
+        .await
+        .unwrap();
+
+    match client_b.next().await.unwrap().unwrap() {
+        tungstenite::Message::Binary(mut received) => match received.pop() {
+            Some(websocket::websocket::MESSAGE_UPDATE) => assert_eq!(diff, received),
+            other => panic!("expected an update message but got {other:?}"),
+        },
+        other => panic!("expected a binary message but got {other:?}"),
+    };
+}
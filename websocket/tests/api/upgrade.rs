@@ -50,3 +50,12 @@ async fn user_has_no_access() {
         other => panic!("expected an http error message but got {other:?}"),
     }
 }
+
+#[tokio::test]
+async fn contributors_can_connect() {
+    let app = spawn_app().await;
+
+    for role in ["editor", "commenter", "viewer"] {
+        app.create_contributor_client(role).await;
+    }
+}